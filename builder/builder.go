@@ -0,0 +1,230 @@
+// Package builder creates class files from Go, e.g. for test fixtures:
+//
+//	c := builder.NewClass("com/x/Foo")
+//	c.Method(classfile.ACC_PUBLIC|classfile.ACC_STATIC, "main", "([Ljava/lang/String;)V").Code().
+//		Field(bytecode.GETSTATIC, "java/lang/System", "out", "Ljava/io/PrintStream;").
+//		Ldc("hello").
+//		Invoke(bytecode.INVOKEVIRTUAL, "java/io/PrintStream", "println", "(Ljava/lang/String;)V").
+//		Op(bytecode.RETURN)
+//	b, err := c.Bytes()
+//
+// Constant pool entries are shared, branch offsets are resolved from labels
+// and max_stack and max_locals are computed. Errors are kept until Bytes or
+// Build reports the first of them, so the calls can be chained.
+package builder
+
+import (
+	"bytes"
+	"fmt"
+
+	"gjvm/classfile"
+	"gjvm/descriptor"
+)
+
+// DefaultMajorVersion is Java 5, the newest class file version a JVM verifies
+// without a StackMapTable attribute, which the builder does not compute.
+const DefaultMajorVersion = 49
+
+type ClassBuilder struct {
+	cp           *ConstantPool
+	name         string
+	majorVersion uint16
+	minorVersion uint16
+	flags        classfile.AccessFlags
+	this, super  uint16
+	interfaces   []uint16
+	fields       []classfile.FieldInfo
+	methods      []*MethodBuilder
+	attributes   []classfile.AttributeInfo
+	err          error
+}
+
+// NewClass starts a public class extending java/lang/Object. name is in
+// internal form, e.g. "com/x/Foo".
+func NewClass(name string) *ClassBuilder {
+	self := &ClassBuilder{
+		name:         name,
+		majorVersion: DefaultMajorVersion,
+		flags:        classfile.ACC_PUBLIC | classfile.ACC_SUPER,
+	}
+	self.cp = newConstantPool(self.fail)
+	self.this = self.cp.Class(name)
+	self.super = self.cp.Class("java/lang/Object")
+	return self
+}
+
+func (self *ClassBuilder) fail(format string, a ...any) {
+	if self.err == nil {
+		self.err = fmt.Errorf("builder: %s: %s", self.name, fmt.Sprintf(format, a...))
+	}
+}
+
+// ConstantPool returns the pool the class is built with, for constants that
+// instructions added with Insn refer to.
+func (self *ClassBuilder) ConstantPool() *ConstantPool {
+	return self.cp
+}
+
+func (self *ClassBuilder) Version(major, minor uint16) *ClassBuilder {
+	self.majorVersion, self.minorVersion = major, minor
+	return self
+}
+
+func (self *ClassBuilder) Flags(flags classfile.AccessFlags) *ClassBuilder {
+	self.flags = flags
+	return self
+}
+
+func (self *ClassBuilder) Extends(name string) *ClassBuilder {
+	self.super = self.cp.Class(name)
+	return self
+}
+
+func (self *ClassBuilder) Implements(names ...string) *ClassBuilder {
+	for _, name := range names {
+		self.interfaces = append(self.interfaces, self.cp.Class(name))
+	}
+	return self
+}
+
+func (self *ClassBuilder) SourceFile(name string) *ClassBuilder {
+	return self.Attribute(classfile.SourceFileAttribute{SourceFileIndex: self.cp.Utf8(name), SourceFile: name})
+}
+
+// Attribute adds a class attribute. Its indices must come from ConstantPool.
+func (self *ClassBuilder) Attribute(attr classfile.AttributeInfo) *ClassBuilder {
+	self.internAttributeName(attr)
+	self.attributes = append(self.attributes, attr)
+	return self
+}
+
+// internAttributeName makes sure the pool holds the name the writer looks up.
+func (self *ClassBuilder) internAttributeName(attr classfile.AttributeInfo) {
+	name := classfile.AttributeName(attr)
+	if name == "" {
+		self.fail("unknown attribute type %T", attr)
+		return
+	}
+	self.cp.Utf8(name)
+}
+
+func (self *ClassBuilder) Field(flags classfile.AccessFlags, name, desc string) *ClassBuilder {
+	if _, err := descriptor.ParseFieldDescriptor(desc); err != nil {
+		self.fail("field %s: %v", name, err)
+	}
+	self.fields = append(self.fields, classfile.FieldInfo{
+		AccessFlags:     flags,
+		NameIndex:       self.cp.Utf8(name),
+		DescriptorIndex: self.cp.Utf8(desc),
+		Attributes:      []classfile.AttributeInfo{},
+	})
+	return self
+}
+
+// Method adds a method. Abstract and native methods need nothing more; the
+// others get their body from Code.
+func (self *ClassBuilder) Method(flags classfile.AccessFlags, name, desc string) *MethodBuilder {
+	md, err := descriptor.ParseMethodDescriptor(desc)
+	if err != nil {
+		self.fail("method %s: %v", name, err)
+	}
+	m := &MethodBuilder{
+		class:      self,
+		flags:      flags,
+		name:       name,
+		desc:       desc,
+		descriptor: md,
+		nameIndex:  self.cp.Utf8(name),
+		descIndex:  self.cp.Utf8(desc),
+	}
+	self.methods = append(self.methods, m)
+	return m
+}
+
+// Bytes assembles the class file.
+func (self *ClassBuilder) Bytes() ([]byte, error) {
+	cf := classfile.ClassFile{
+		MinorVersion: self.minorVersion,
+		MajorVersion: self.majorVersion,
+		AccessFlags:  self.flags,
+		ThisClass:    self.this,
+		SuperClass:   self.super,
+		Interfaces:   self.interfaces,
+		Fields:       self.fields,
+		Attributes:   self.attributes,
+	}
+	for _, m := range self.methods {
+		cf.Methods = append(cf.Methods, m.methodInfo())
+	}
+	// methodInfo can add entries, so the pool is taken last
+	cf.ConstantPool = self.cp.entries
+	if self.err != nil {
+		return nil, self.err
+	}
+	return cf.Bytes()
+}
+
+// Build assembles the class file and parses it back, so that the names and
+// decoded attributes are filled in as for a class read from disk.
+func (self *ClassBuilder) Build() (*classfile.ClassFile, error) {
+	b, err := self.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return classfile.NewClassFileParser(bytes.NewReader(b)).Parse()
+}
+
+type MethodBuilder struct {
+	class      *ClassBuilder
+	flags      classfile.AccessFlags
+	name       string
+	desc       string
+	descriptor descriptor.MethodDescriptor
+	nameIndex  uint16
+	descIndex  uint16
+	code       *CodeBuilder
+	attributes []classfile.AttributeInfo
+}
+
+// Code returns the builder for the method's Code attribute, creating it on
+// the first call.
+func (self *MethodBuilder) Code() *CodeBuilder {
+	if self.code == nil {
+		self.class.cp.Utf8("Code")
+		self.code = newCodeBuilder(self)
+	}
+	return self.code
+}
+
+// Throws adds the classes to the method's Exceptions attribute.
+func (self *MethodBuilder) Throws(classes ...string) *MethodBuilder {
+	attr := classfile.ExceptionsAttribute{}
+	for _, class := range classes {
+		attr.ExceptionIndexTable = append(attr.ExceptionIndexTable, self.class.cp.Class(class))
+		attr.Exceptions = append(attr.Exceptions, class)
+	}
+	return self.Attribute(attr)
+}
+
+// Attribute adds a method attribute other than Code.
+func (self *MethodBuilder) Attribute(attr classfile.AttributeInfo) *MethodBuilder {
+	self.class.internAttributeName(attr)
+	self.attributes = append(self.attributes, attr)
+	return self
+}
+
+func (self *MethodBuilder) methodInfo() classfile.MethodInfo {
+	attrs := self.attributes
+	if self.code != nil {
+		code := self.code.attribute()
+		attrs = append([]classfile.AttributeInfo{code}, attrs...)
+	}
+	return classfile.MethodInfo{
+		AccessFlags:     self.flags,
+		Name:            self.name,
+		NameIndex:       self.nameIndex,
+		DescriptorIndex: self.descIndex,
+		Descriptor:      self.desc,
+		Attributes:      attrs,
+	}
+}
@@ -0,0 +1,231 @@
+package builder
+
+import (
+	"strings"
+	"testing"
+
+	"gjvm/bytecode"
+	"gjvm/classfile"
+)
+
+const publicStatic = classfile.ACC_PUBLIC | classfile.ACC_STATIC
+
+func code(t *testing.T, cf *classfile.ClassFile, name string) (classfile.CodeAttribute, []bytecode.Instruction) {
+	t.Helper()
+	for _, m := range cf.Methods {
+		if m.Name == name {
+			attr, ok := classfile.FindAttribute[classfile.CodeAttribute](m.Attributes)
+			if !ok {
+				t.Fatalf("%s has no Code", name)
+			}
+			insns, err := bytecode.DecodeAll(attr.Code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return attr, insns
+		}
+	}
+	t.Fatalf("no method %s", name)
+	return classfile.CodeAttribute{}, nil
+}
+
+func listing(insns []bytecode.Instruction) string {
+	lines := []string{}
+	for _, insn := range insns {
+		lines = append(lines, insn.String())
+	}
+	return strings.Join(lines, "; ")
+}
+
+func TestBuildHello(t *testing.T) {
+	c := NewClass("com/x/Hello").SourceFile("Hello.java")
+	c.Method(publicStatic, "main", "([Ljava/lang/String;)V").Code().
+		Field(bytecode.GETSTATIC, "java/lang/System", "out", "Ljava/io/PrintStream;").
+		Ldc("hello").
+		Invoke(bytecode.INVOKEVIRTUAL, "java/io/PrintStream", "println", "(Ljava/lang/String;)V").
+		Field(bytecode.GETSTATIC, "java/lang/System", "out", "Ljava/io/PrintStream;").
+		Ldc("hello").
+		Invoke(bytecode.INVOKEVIRTUAL, "java/io/PrintStream", "println", "(Ljava/lang/String;)V").
+		Op(bytecode.RETURN)
+	cf, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := cf.SourceFile(); !ok || name != "Hello.java" {
+		t.Errorf("SourceFile: got %q", name)
+	}
+	attr, insns := code(t, cf, "main")
+	if attr.MaxStack != 2 || attr.MaxLocals != 1 {
+		t.Errorf("max_stack %d, max_locals %d, want 2 and 1", attr.MaxStack, attr.MaxLocals)
+	}
+	if insns[0].Index != insns[3].Index || insns[1].Index != insns[4].Index || insns[2].Index != insns[5].Index {
+		t.Errorf("constants are not shared: %s", listing(insns))
+	}
+	if got, _ := cf.ConstantPool[insns[1].Index].(*classfile.ConstantStringInfo).Resolve(cf.ConstantPool); got != "hello" {
+		t.Errorf("ldc: got %q", got)
+	}
+}
+
+func TestBuildLoop(t *testing.T) {
+	c := NewClass("com/x/Sum")
+	m := c.Method(publicStatic, "sum", "(J)J").Code()
+	loop, end := m.NewLabel(), m.NewLabel()
+	m.Ldc(int64(0)).Local(bytecode.LSTORE, 2).
+		Mark(loop).
+		Local(bytecode.LLOAD, 0).Op(bytecode.LCONST_0).Op(bytecode.LCMP).Jump(bytecode.IFLE, end).
+		Local(bytecode.LLOAD, 2).Local(bytecode.LLOAD, 0).Op(bytecode.LADD).Local(bytecode.LSTORE, 2).
+		Local(bytecode.LLOAD, 0).Op(bytecode.LCONST_1).Op(bytecode.LSUB).Local(bytecode.LSTORE, 0).
+		Jump(bytecode.GOTO, loop).
+		Mark(end).
+		Local(bytecode.LLOAD, 2).Op(bytecode.LRETURN)
+	cf, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	attr, insns := code(t, cf, "sum")
+	if attr.MaxStack != 4 || attr.MaxLocals != 4 {
+		t.Errorf("max_stack %d, max_locals %d, want 4 and 4", attr.MaxStack, attr.MaxLocals)
+	}
+	want := "ldc2_w #8; lstore_2; lload_0; lconst_0; lcmp; ifle 21; lload_2; lload_0; ladd; lstore_2; lload_0; lconst_1; lsub; lstore_0; goto 4; lload_2; lreturn"
+	if got := listing(insns); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestBuildOperandForms(t *testing.T) {
+	c := NewClass("com/x/Forms")
+	m := c.Method(publicStatic, "f", "()V").Code()
+	m.Push(-1).Push(100).Push(1000).Push(100000).Op(bytecode.POP2).Op(bytecode.POP2).
+		Local(bytecode.ILOAD, 300).Op(bytecode.POP).
+		Iinc(1, 1).Iinc(1, 1000).
+		Invoke(bytecode.INVOKEINTERFACE, "java/util/List", "add", "(ILjava/lang/Object;)V").
+		Op(bytecode.RETURN)
+	cf, err := c.Build()
+	// the interface call pops three values that were never pushed
+	if err == nil || !strings.Contains(err.Error(), "stack underflow at pc 24") {
+		t.Fatalf("got %v, want a stack underflow", err)
+	}
+
+	c = NewClass("com/x/Forms")
+	m = c.Method(publicStatic, "f", "()V").Code()
+	m.Push(-1).Push(100).Push(1000).Push(100000).Op(bytecode.POP2).Op(bytecode.POP2).
+		Local(bytecode.ILOAD, 300).Op(bytecode.POP).
+		Iinc(1, 1).Iinc(1, 1000).
+		Op(bytecode.RETURN)
+	if cf, err = c.Build(); err != nil {
+		t.Fatal(err)
+	}
+	attr, insns := code(t, cf, "f")
+	want := "iconst_m1; bipush 100; sipush 1000; ldc #8; pop2; pop2; wide iload 300; pop; iinc 1, 1; wide iinc 1, 1000; return"
+	if got := listing(insns); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if attr.MaxStack != 4 || attr.MaxLocals != 301 {
+		t.Errorf("max_stack %d, max_locals %d, want 4 and 301", attr.MaxStack, attr.MaxLocals)
+	}
+}
+
+func TestBuildSwitchAndHandler(t *testing.T) {
+	c := NewClass("com/x/Switch")
+	m := c.Method(publicStatic, "f", "(I)I").Code()
+	one, two, other, start, end, catch := m.NewLabel(), m.NewLabel(), m.NewLabel(), m.NewLabel(), m.NewLabel(), m.NewLabel()
+	m.Mark(start).
+		Local(bytecode.ILOAD, 0).
+		LookupSwitch(other, map[int32]*Label{20: two, 10: one}).
+		Mark(one).Push(1).Op(bytecode.IRETURN).
+		Mark(two).Push(2).Op(bytecode.IRETURN).
+		Mark(other).Local(bytecode.ILOAD, 0).Push(0).Op(bytecode.IDIV).Op(bytecode.IRETURN).
+		Mark(end).
+		Mark(catch).Op(bytecode.POP).Push(-1).Op(bytecode.IRETURN).
+		Catch(start, end, catch, "java/lang/ArithmeticException")
+	cf, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	attr, insns := code(t, cf, "f")
+	if got, want := insns[1].String(), "lookupswitch {10: 28, 20: 30, default: 32}"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	e := attr.ExceptionTable[0]
+	if e.StartPc != 0 || e.EndPc != 36 || e.HandlerPc != 36 || e.CatchType == 0 {
+		t.Errorf("exception table: got %+v", e)
+	}
+	if attr.MaxStack != 2 {
+		t.Errorf("max_stack %d, want 2", attr.MaxStack)
+	}
+}
+
+func TestBuildWideJump(t *testing.T) {
+	c := NewClass("com/x/Far")
+	m := c.Method(publicStatic, "f", "()V").Code()
+	far := m.NewLabel()
+	m.Jump(bytecode.GOTO, far)
+	for i := 0; i < 0x8000; i++ {
+		m.Op(bytecode.NOP)
+	}
+	m.Mark(far).Op(bytecode.RETURN)
+	cf, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, insns := code(t, cf, "f")
+	if insns[0].Opcode != bytecode.GOTO_W || insns[0].Target != 0x8005 {
+		t.Errorf("got %s", insns[0])
+	}
+
+	c = NewClass("com/x/Far")
+	m = c.Method(publicStatic, "f", "(I)V").Code()
+	far = m.NewLabel()
+	m.Local(bytecode.ILOAD, 0).Jump(bytecode.IFEQ, far)
+	for i := 0; i < 0x8000; i++ {
+		m.Op(bytecode.NOP)
+	}
+	m.Mark(far).Op(bytecode.RETURN)
+	if _, err := c.Bytes(); err == nil || !strings.Contains(err.Error(), "ifeq at pc 1 cannot reach") {
+		t.Errorf("got %v, want an out of range ifeq", err)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		build func(m *CodeBuilder)
+		want  string
+	}{
+		{"unmarked label", func(m *CodeBuilder) { m.Jump(bytecode.GOTO, m.NewLabel()) }, "label is not marked"},
+		{"falls off", func(m *CodeBuilder) { m.Push(1).Op(bytecode.POP) }, "falls off the end"},
+		{"operands", func(m *CodeBuilder) { m.Op(bytecode.BIPUSH) }, "bipush takes operands"},
+		{"heights", func(m *CodeBuilder) {
+			l := m.NewLabel()
+			m.Local(bytecode.ILOAD, 0).Jump(bytecode.IFEQ, l).Push(1).Mark(l).Op(bytecode.RETURN)
+		}, "stack height at pc 5 is both"},
+	} {
+		c := NewClass("com/x/Bad")
+		tc.build(c.Method(publicStatic, "f", "(I)V").Code())
+		if _, err := c.Bytes(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestConstantPoolInterning(t *testing.T) {
+	cp := NewClass("com/x/Foo").ConstantPool()
+	if cp.Utf8("com/x/Foo") != 1 || cp.Class("com/x/Foo") != 2 {
+		t.Errorf("NewClass should start the pool with this_class")
+	}
+	long := cp.Long(1)
+	if cp.Integer(1) != long+2 {
+		t.Errorf("a long should take two slots")
+	}
+	if cp.Float(0) == cp.Float(float32(negativeZero())) {
+		t.Errorf("0.0 and -0.0 should be distinct entries")
+	}
+	if a, b := cp.Methodref("a/B", "c", "()V"), cp.Methodref("a/B", "c", "()V"); a != b {
+		t.Errorf("Methodref: %d and %d", a, b)
+	}
+}
+
+func negativeZero() float64 {
+	zero := 0.0
+	return -zero
+}
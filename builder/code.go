@@ -0,0 +1,420 @@
+package builder
+
+import (
+	"sort"
+
+	"gjvm/bytecode"
+	"gjvm/classfile"
+	"gjvm/descriptor"
+)
+
+// Label is a position in the code that branches, switches and exception
+// handlers refer to. It is created with NewLabel and placed with Mark, before
+// or after the instructions that refer to it.
+type Label struct {
+	code *CodeBuilder
+	pc   int
+}
+
+// CodeBuilder appends instructions to a method's Code attribute. Operands are
+// given as names and values; the constant pool entries, short forms and wide
+// prefixes are chosen for them.
+type CodeBuilder struct {
+	method    *MethodBuilder
+	cp        *ConstantPool
+	items     []codeItem
+	handlers  []handler
+	maxLocals int
+}
+
+// codeItem is either an instruction or the position of a label. Branch
+// targets are filled in from target and targets once the code is laid out.
+type codeItem struct {
+	insn    bytecode.Instruction
+	mark    *Label
+	target  *Label
+	targets []*Label
+}
+
+type handler struct {
+	start, end, handler *Label
+	catchType           uint16
+}
+
+func newCodeBuilder(method *MethodBuilder) *CodeBuilder {
+	locals := method.descriptor.ParameterSlots()
+	if !method.flags.IsStatic() {
+		locals++
+	}
+	return &CodeBuilder{method: method, cp: method.class.cp, maxLocals: locals}
+}
+
+func (self *CodeBuilder) fail(format string, a ...any) {
+	self.method.class.fail("%s%s: "+format, append([]any{self.method.name, self.method.desc}, a...)...)
+}
+
+func (self *CodeBuilder) emit(item codeItem) *CodeBuilder {
+	self.items = append(self.items, item)
+	return self
+}
+
+func (self *CodeBuilder) NewLabel() *Label {
+	return &Label{}
+}
+
+// Mark places l at the next instruction.
+func (self *CodeBuilder) Mark(l *Label) *CodeBuilder {
+	if l.code != nil {
+		self.fail("label marked twice")
+		return self
+	}
+	l.code = self
+	return self.emit(codeItem{mark: l})
+}
+
+// Insn appends an instruction as it is. Its constant pool indices must come
+// from the class's ConstantPool. Branches and switches have to be added with
+// Jump, TableSwitch and LookupSwitch.
+func (self *CodeBuilder) Insn(insn bytecode.Instruction) *CodeBuilder {
+	if insn.IsBranch() {
+		self.fail("%s needs a label", insn.Opcode)
+		return self
+	}
+	insn.PC = 0
+	self.useLocal(insn)
+	return self.emit(codeItem{insn: insn})
+}
+
+// Op appends an instruction without operands, e.g. iadd or return.
+func (self *CodeBuilder) Op(op bytecode.Opcode) *CodeBuilder {
+	if !op.IsValid() || op == bytecode.WIDE || (bytecode.Instruction{Opcode: op}).Len() != 1 {
+		self.fail("%s takes operands", op)
+		return self
+	}
+	return self.emit(codeItem{insn: bytecode.Instruction{Opcode: op}})
+}
+
+// Push pushes an int constant with the shortest of iconst, bipush, sipush
+// and ldc.
+func (self *CodeBuilder) Push(v int32) *CodeBuilder {
+	switch {
+	case v >= -1 && v <= 5:
+		return self.Op(bytecode.ICONST_0 + bytecode.Opcode(v))
+	case v >= -0x80 && v < 0x80:
+		return self.emit(codeItem{insn: bytecode.Instruction{Opcode: bytecode.BIPUSH, Value: int(v)}})
+	case v >= -0x8000 && v < 0x8000:
+		return self.emit(codeItem{insn: bytecode.Instruction{Opcode: bytecode.SIPUSH, Value: int(v)}})
+	}
+	return self.Ldc(v)
+}
+
+// Ldc pushes a constant: a string, an int or int32, a float32, an int64 or a
+// float64. ldc, ldc_w or ldc2_w is chosen to fit.
+func (self *CodeBuilder) Ldc(v any) *CodeBuilder {
+	var index uint16
+	op := bytecode.LDC
+	switch v := v.(type) {
+	case string:
+		index = self.cp.String(v)
+	case int:
+		if int(int32(v)) != v {
+			self.fail("ldc %d does not fit in an int", v)
+			return self
+		}
+		index = self.cp.Integer(int32(v))
+	case int32:
+		index = self.cp.Integer(v)
+	case float32:
+		index = self.cp.Float(v)
+	case int64:
+		index, op = self.cp.Long(v), bytecode.LDC2_W
+	case float64:
+		index, op = self.cp.Double(v), bytecode.LDC2_W
+	default:
+		self.fail("ldc of %T", v)
+		return self
+	}
+	if op == bytecode.LDC && index > 0xFF {
+		op = bytecode.LDC_W
+	}
+	return self.emit(codeItem{insn: bytecode.Instruction{Opcode: op, Index: int(index)}})
+}
+
+// Local appends a load, a store or ret of a local variable, using the
+// <op>_<n> form for the first four and wide beyond 255.
+func (self *CodeBuilder) Local(op bytecode.Opcode, index int) *CodeBuilder {
+	if !(op >= bytecode.ILOAD && op <= bytecode.ALOAD || op >= bytecode.ISTORE && op <= bytecode.ASTORE || op == bytecode.RET) {
+		self.fail("%s is not a load, store or ret", op)
+		return self
+	}
+	if index < 0 || index > 0xFFFF {
+		self.fail("%s of local %d", op, index)
+		return self
+	}
+	insn := bytecode.Instruction{Opcode: op, Index: index, Wide: index > 0xFF}
+	self.useLocal(insn)
+	switch {
+	case index > 3 || op == bytecode.RET:
+	case op <= bytecode.ALOAD:
+		insn = bytecode.Instruction{Opcode: bytecode.ILOAD_0 + (op-bytecode.ILOAD)*4 + bytecode.Opcode(index)}
+	default:
+		insn = bytecode.Instruction{Opcode: bytecode.ISTORE_0 + (op-bytecode.ISTORE)*4 + bytecode.Opcode(index)}
+	}
+	return self.emit(codeItem{insn: insn})
+}
+
+func (self *CodeBuilder) Iinc(index, delta int) *CodeBuilder {
+	if index < 0 || index > 0xFFFF || delta < -0x8000 || delta > 0x7FFF {
+		self.fail("iinc %d, %d out of range", index, delta)
+		return self
+	}
+	insn := bytecode.Instruction{Opcode: bytecode.IINC, Index: index, Value: delta}
+	insn.Wide = index > 0xFF || delta < -0x80 || delta > 0x7F
+	self.useLocal(insn)
+	return self.emit(codeItem{insn: insn})
+}
+
+// useLocal grows max_locals to cover the local variable insn uses.
+func (self *CodeBuilder) useLocal(insn bytecode.Instruction) {
+	op := insn.Opcode
+	var slots int
+	switch {
+	case op == bytecode.LLOAD, op == bytecode.DLOAD, op == bytecode.LSTORE, op == bytecode.DSTORE:
+		slots = 2
+	case op >= bytecode.ILOAD && op <= bytecode.ALOAD, op >= bytecode.ISTORE && op <= bytecode.ASTORE,
+		op == bytecode.RET, op == bytecode.IINC:
+		slots = 1
+	case op >= bytecode.ILOAD_0 && op <= bytecode.ALOAD_3:
+		insn.Index = int(op-bytecode.ILOAD_0) % 4
+		slots = kindSlots(int(op-bytecode.ILOAD_0) / 4)
+	case op >= bytecode.ISTORE_0 && op <= bytecode.ASTORE_3:
+		insn.Index = int(op-bytecode.ISTORE_0) % 4
+		slots = kindSlots(int(op-bytecode.ISTORE_0) / 4)
+	default:
+		return
+	}
+	self.maxLocals = max(self.maxLocals, insn.Index+slots)
+}
+
+// Field appends getstatic, putstatic, getfield or putfield.
+func (self *CodeBuilder) Field(op bytecode.Opcode, class, name, desc string) *CodeBuilder {
+	if op < bytecode.GETSTATIC || op > bytecode.PUTFIELD {
+		self.fail("%s is not a field instruction", op)
+		return self
+	}
+	index := self.cp.Fieldref(class, name, desc)
+	return self.emit(codeItem{insn: bytecode.Instruction{Opcode: op, Index: int(index)}})
+}
+
+// Invoke appends invokevirtual, invokespecial, invokestatic or
+// invokeinterface, whose argument count is taken from desc.
+func (self *CodeBuilder) Invoke(op bytecode.Opcode, class, name, desc string) *CodeBuilder {
+	insn := bytecode.Instruction{Opcode: op}
+	switch op {
+	case bytecode.INVOKEVIRTUAL, bytecode.INVOKESPECIAL, bytecode.INVOKESTATIC:
+		insn.Index = int(self.cp.Methodref(class, name, desc))
+	case bytecode.INVOKEINTERFACE:
+		md, err := descriptor.ParseMethodDescriptor(desc)
+		if err != nil {
+			self.fail("%v", err)
+			return self
+		}
+		insn.Index = int(self.cp.InterfaceMethodref(class, name, desc))
+		insn.Value = md.ParameterSlots() + 1
+	default:
+		self.fail("%s is not an invoke instruction", op)
+		return self
+	}
+	return self.emit(codeItem{insn: insn})
+}
+
+// Type appends new, anewarray, checkcast or instanceof of a class or array
+// type in internal form.
+func (self *CodeBuilder) Type(op bytecode.Opcode, class string) *CodeBuilder {
+	switch op {
+	case bytecode.NEW, bytecode.ANEWARRAY, bytecode.CHECKCAST, bytecode.INSTANCEOF:
+	default:
+		self.fail("%s does not take a class", op)
+		return self
+	}
+	return self.emit(codeItem{insn: bytecode.Instruction{Opcode: op, Index: int(self.cp.Class(class))}})
+}
+
+// NewArray appends newarray of one of the bytecode.T_ element types.
+func (self *CodeBuilder) NewArray(atype int) *CodeBuilder {
+	if _, ok := bytecode.ArrayTypeName(atype); !ok {
+		self.fail("newarray of atype %d", atype)
+		return self
+	}
+	return self.emit(codeItem{insn: bytecode.Instruction{Opcode: bytecode.NEWARRAY, Value: atype}})
+}
+
+// MultiANewArray appends multianewarray of an array descriptor such as "[[I".
+func (self *CodeBuilder) MultiANewArray(class string, dimensions int) *CodeBuilder {
+	if dimensions < 1 || dimensions > 0xFF {
+		self.fail("multianewarray of %d dimensions", dimensions)
+		return self
+	}
+	index := self.cp.Class(class)
+	return self.emit(codeItem{insn: bytecode.Instruction{Opcode: bytecode.MULTIANEWARRAY, Index: int(index), Value: dimensions}})
+}
+
+// Jump appends a conditional branch, goto or jsr to l. A goto or jsr whose
+// target is out of reach of a 16-bit offset becomes goto_w or jsr_w.
+func (self *CodeBuilder) Jump(op bytecode.Opcode, l *Label) *CodeBuilder {
+	insn := bytecode.Instruction{Opcode: op}
+	if !insn.IsBranch() || op == bytecode.TABLESWITCH || op == bytecode.LOOKUPSWITCH {
+		self.fail("%s is not a jump", op)
+		return self
+	}
+	return self.emit(codeItem{insn: insn, target: l})
+}
+
+// TableSwitch appends a tableswitch jumping to targets[i] for key low+i.
+func (self *CodeBuilder) TableSwitch(low int32, dflt *Label, targets ...*Label) *CodeBuilder {
+	if len(targets) == 0 || int64(low)+int64(len(targets))-1 > 0x7FFFFFFF {
+		self.fail("tableswitch of %d targets from %d", len(targets), low)
+		return self
+	}
+	insn := bytecode.Instruction{
+		Opcode:  bytecode.TABLESWITCH,
+		Low:     low,
+		High:    low + int32(len(targets)-1),
+		Targets: make([]int, len(targets)),
+	}
+	return self.emit(codeItem{insn: insn, target: dflt, targets: targets})
+}
+
+// LookupSwitch appends a lookupswitch; the keys are sorted as the JVM requires.
+func (self *CodeBuilder) LookupSwitch(dflt *Label, cases map[int32]*Label) *CodeBuilder {
+	insn := bytecode.Instruction{Opcode: bytecode.LOOKUPSWITCH}
+	for key := range cases {
+		insn.Keys = append(insn.Keys, key)
+	}
+	sort.Slice(insn.Keys, func(i, j int) bool { return insn.Keys[i] < insn.Keys[j] })
+	targets := make([]*Label, len(insn.Keys))
+	for i, key := range insn.Keys {
+		targets[i] = cases[key]
+	}
+	insn.Targets = make([]int, len(targets))
+	return self.emit(codeItem{insn: insn, target: dflt, targets: targets})
+}
+
+// Catch adds an exception table entry: exceptions of class, or of any class
+// if class is "", thrown between start and end go to handler.
+func (self *CodeBuilder) Catch(start, end, handlerLabel *Label, class string) *CodeBuilder {
+	h := handler{start: start, end: end, handler: handlerLabel}
+	if class != "" {
+		h.catchType = self.cp.Class(class)
+	}
+	self.handlers = append(self.handlers, h)
+	return self
+}
+
+// layout assigns pcs and resolves labels, widening goto and jsr whose target
+// is out of reach until every offset fits.
+func (self *CodeBuilder) layout() ([]bytecode.Instruction, bool) {
+	for {
+		pc := 0
+		for i := range self.items {
+			item := &self.items[i]
+			if item.mark != nil {
+				item.mark.pc = pc
+				continue
+			}
+			item.insn.PC = pc
+			pc += item.insn.Len()
+		}
+
+		insns := []bytecode.Instruction{}
+		widened := false
+		for i := range self.items {
+			item := &self.items[i]
+			if item.mark != nil {
+				continue
+			}
+			if item.target != nil {
+				item.insn.Target = item.target.pc
+			}
+			for j, l := range item.targets {
+				item.insn.Targets[j] = l.pc
+			}
+			if item.target != nil && item.insn.Len() == 3 {
+				if offset := item.insn.Target - item.insn.PC; offset < -0x8000 || offset > 0x7FFF {
+					switch item.insn.Opcode {
+					case bytecode.GOTO:
+						item.insn.Opcode, widened = bytecode.GOTO_W, true
+					case bytecode.JSR:
+						item.insn.Opcode, widened = bytecode.JSR_W, true
+					default:
+						self.fail("%s at pc %d cannot reach pc %d", item.insn.Opcode, item.insn.PC, item.insn.Target)
+						return nil, false
+					}
+				}
+			}
+			insns = append(insns, item.insn)
+		}
+		if !widened {
+			return insns, true
+		}
+	}
+}
+
+// checkLabels reports a label that is used but not marked in this code.
+func (self *CodeBuilder) checkLabels() bool {
+	labels := []*Label{}
+	for _, item := range self.items {
+		if item.target != nil {
+			labels = append(labels, item.target)
+		}
+		labels = append(labels, item.targets...)
+	}
+	for _, h := range self.handlers {
+		labels = append(labels, h.start, h.end, h.handler)
+	}
+	for _, l := range labels {
+		if l == nil || l.code != self {
+			self.fail("label is not marked in this code")
+			return false
+		}
+	}
+	return true
+}
+
+func (self *CodeBuilder) attribute() classfile.CodeAttribute {
+	attr := classfile.CodeAttribute{Code: []byte{}, ExceptionTable: []classfile.ExceptionTableEntry{}, Attributes: []classfile.AttributeInfo{}}
+	if !self.checkLabels() {
+		return attr
+	}
+	insns, ok := self.layout()
+	if !ok {
+		return attr
+	}
+	code, err := bytecode.EncodeAll(insns)
+	if err != nil {
+		self.fail("%v", err)
+		return attr
+	}
+	if len(code) > 0xFFFF {
+		self.fail("code of %d bytes exceeds 65535", len(code))
+		return attr
+	}
+	for _, h := range self.handlers {
+		attr.ExceptionTable = append(attr.ExceptionTable, classfile.ExceptionTableEntry{
+			StartPc:   uint16(h.start.pc),
+			EndPc:     uint16(h.end.pc),
+			HandlerPc: uint16(h.handler.pc),
+			CatchType: h.catchType,
+		})
+	}
+	maxStack := self.maxStack(insns, attr.ExceptionTable)
+	if maxStack > 0xFFFF || self.maxLocals > 0xFFFF {
+		self.fail("max_stack %d or max_locals %d exceeds 65535", maxStack, self.maxLocals)
+	}
+	attr.MaxStack = uint16(maxStack)
+	attr.MaxLocals = uint16(self.maxLocals)
+	attr.CodeLength = uint32(len(code))
+	attr.Code = code
+	attr.ExceptionTableLength = uint16(len(attr.ExceptionTable))
+	return attr
+}
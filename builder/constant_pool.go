@@ -0,0 +1,144 @@
+package builder
+
+import (
+	"math"
+
+	"gjvm/classfile"
+)
+
+// ConstantPool hands out constant pool indices, adding an entry the first time
+// a constant is asked for and returning the same index after that.
+type ConstantPool struct {
+	entries classfile.ConstantPool
+	indices map[constantKey]uint16
+	fail    func(format string, a ...any)
+}
+
+// constantKey identifies an entry by its tag and contents: s holds the
+// string of a Utf8 entry, v the bits of a number or the packed indices of
+// the other kinds.
+type constantKey struct {
+	tag uint8
+	s   string
+	v   uint64
+}
+
+func newConstantPool(fail func(format string, a ...any)) *ConstantPool {
+	return &ConstantPool{
+		entries: classfile.ConstantPool{nil},
+		indices: map[constantKey]uint16{},
+		fail:    fail,
+	}
+}
+
+// intern returns the index of the entry for key, adding info if there is none.
+func (self *ConstantPool) intern(key constantKey, info classfile.ConstantInfo) uint16 {
+	if index, ok := self.indices[key]; ok {
+		return index
+	}
+	slots := 1
+	if key.tag == classfile.ConstantLongTag || key.tag == classfile.ConstantDoubleTag {
+		slots = 2
+	}
+	if len(self.entries)+slots > 0xFFFF {
+		self.fail("constant pool is full")
+		return 0
+	}
+	index := uint16(len(self.entries))
+	self.entries = append(self.entries, info)
+	if slots == 2 {
+		self.entries = append(self.entries, &classfile.ConstantUnusableInfo{})
+	}
+	self.indices[key] = index
+	return index
+}
+
+func (self *ConstantPool) Utf8(s string) uint16 {
+	b := classfile.EncodeModifiedUTF8(s)
+	if len(b) > 0xFFFF {
+		self.fail("string of %d bytes does not fit in a CONSTANT_Utf8", len(b))
+		return 0
+	}
+	return self.intern(constantKey{tag: classfile.ConstantUtf8Tag, s: s},
+		&classfile.ConstantUtf8Info{Length: uint16(len(b)), Bytes: b})
+}
+
+func (self *ConstantPool) Integer(v int32) uint16 {
+	bits := uint32(v)
+	return self.intern(constantKey{tag: classfile.ConstantIntegerTag, v: uint64(bits)},
+		&classfile.ConstantIntegerInfo{Bytes: []byte{byte(bits >> 24), byte(bits >> 16), byte(bits >> 8), byte(bits)}})
+}
+
+// Float interns v by its bits, so 0.0 and -0.0 are distinct entries.
+func (self *ConstantPool) Float(v float32) uint16 {
+	bits := math.Float32bits(v)
+	return self.intern(constantKey{tag: classfile.ConstantFloatTag, v: uint64(bits)},
+		&classfile.ConstantFloatInfo{Bytes: []byte{byte(bits >> 24), byte(bits >> 16), byte(bits >> 8), byte(bits)}})
+}
+
+func (self *ConstantPool) Long(v int64) uint16 {
+	bits := uint64(v)
+	return self.intern(constantKey{tag: classfile.ConstantLongTag, v: bits},
+		&classfile.ConstantLongInfo{HighBytes: uint32(bits >> 32), LowBytes: uint32(bits)})
+}
+
+func (self *ConstantPool) Double(v float64) uint16 {
+	bits := math.Float64bits(v)
+	return self.intern(constantKey{tag: classfile.ConstantDoubleTag, v: bits},
+		&classfile.ConstantDoubleInfo{HighBytes: uint32(bits >> 32), LowBytes: uint32(bits)})
+}
+
+func (self *ConstantPool) String(s string) uint16 {
+	utf8 := self.Utf8(s)
+	return self.intern(constantKey{tag: classfile.ConstantStringTag, v: uint64(utf8)},
+		&classfile.ConstantStringInfo{StringIndex: utf8})
+}
+
+// Class takes a binary name in internal form, e.g. "java/lang/Object", or an
+// array descriptor such as "[I".
+func (self *ConstantPool) Class(name string) uint16 {
+	utf8 := self.Utf8(name)
+	return self.intern(constantKey{tag: classfile.ConstantClassTag, v: uint64(utf8)},
+		&classfile.ConstantClassInfo{NameIndex: utf8})
+}
+
+func (self *ConstantPool) NameAndType(name, descriptor string) uint16 {
+	n, d := self.Utf8(name), self.Utf8(descriptor)
+	return self.intern(constantKey{tag: classfile.ConstantNameAndTypeTag, v: pack(n, d)},
+		&classfile.ConstantNameAndTypeInfo{NameIndex: n, DescriptorIndex: d})
+}
+
+func (self *ConstantPool) Fieldref(class, name, descriptor string) uint16 {
+	c, nt := self.Class(class), self.NameAndType(name, descriptor)
+	return self.intern(constantKey{tag: classfile.ConstantFieldrefTag, v: pack(c, nt)},
+		&classfile.ConstantFieldrefInfo{ClassIndex: c, NameAndTypeIndex: nt})
+}
+
+func (self *ConstantPool) Methodref(class, name, descriptor string) uint16 {
+	c, nt := self.Class(class), self.NameAndType(name, descriptor)
+	return self.intern(constantKey{tag: classfile.ConstantMethodrefTag, v: pack(c, nt)},
+		&classfile.ConstantMethodrefInfo{ClassIndex: c, NameAndTypeIndex: nt})
+}
+
+func (self *ConstantPool) InterfaceMethodref(class, name, descriptor string) uint16 {
+	c, nt := self.Class(class), self.NameAndType(name, descriptor)
+	return self.intern(constantKey{tag: classfile.ConstantInterfaceMethodrefTag, v: pack(c, nt)},
+		&classfile.ConstantInterfaceMethodrefInfo{ClassIndex: c, NameAndTypeIndex: nt})
+}
+
+// MethodHandle takes one of the REF_ kinds and the index of the field or
+// method reference it applies to.
+func (self *ConstantPool) MethodHandle(kind uint8, reference uint16) uint16 {
+	return self.intern(constantKey{tag: classfile.ConstantMethodHandleTag, v: pack(uint16(kind), reference)},
+		&classfile.ConstantMethodHandleInfo{ReferenceKind: kind, ReferenceIndex: reference})
+}
+
+func (self *ConstantPool) MethodType(descriptor string) uint16 {
+	d := self.Utf8(descriptor)
+	return self.intern(constantKey{tag: classfile.ConstantMethodTypeTag, v: uint64(d)},
+		&classfile.ConstantMethodTypeInfo{DescriptorIndex: d})
+}
+
+func pack(a, b uint16) uint64 {
+	return uint64(a)<<16 | uint64(b)
+}
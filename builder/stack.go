@@ -0,0 +1,247 @@
+package builder
+
+import (
+	"gjvm/bytecode"
+	"gjvm/classfile"
+	"gjvm/descriptor"
+)
+
+// maxStack follows every path through the code from pc 0 and from each
+// exception handler, which starts with the exception on the stack, and
+// returns the deepest the operand stack gets. The stack height where paths
+// join has to agree, as the verifier requires.
+func (self *CodeBuilder) maxStack(insns []bytecode.Instruction, table []classfile.ExceptionTableEntry) int {
+	at := map[int]int{}
+	for i, insn := range insns {
+		at[insn.PC] = i
+	}
+	depth := make([]int, len(insns))
+	for i := range depth {
+		depth[i] = -1
+	}
+	work := []int{}
+	deepest := 0
+	failed := false
+	enter := func(pc, d int) {
+		i, ok := at[pc]
+		switch {
+		case failed:
+		case !ok:
+			self.fail("execution falls off the end of the code")
+			failed = true
+		case depth[i] < 0:
+			depth[i] = d
+			deepest = max(deepest, d)
+			work = append(work, i)
+		case depth[i] != d:
+			self.fail("stack height at pc %d is both %d and %d", pc, depth[i], d)
+			failed = true
+		}
+	}
+	if len(insns) > 0 {
+		enter(0, 0)
+	}
+	for _, e := range table {
+		enter(int(e.HandlerPc), 1)
+	}
+	for len(work) > 0 && !failed {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		insn := insns[i]
+		pop, push, ok := self.stackEffect(insn)
+		if !ok {
+			return deepest
+		}
+		if depth[i] < pop {
+			self.fail("stack underflow at pc %d", insn.PC)
+			return deepest
+		}
+		d := depth[i] - pop + push
+		deepest = max(deepest, d)
+		next := insn.PC + insn.Len()
+
+		switch op := insn.Opcode; {
+		case op == bytecode.GOTO, op == bytecode.GOTO_W:
+			enter(insn.Target, d)
+		case op == bytecode.JSR, op == bytecode.JSR_W:
+			// the subroutine returns with the return address consumed
+			enter(insn.Target, d)
+			enter(next, d-1)
+		case op == bytecode.TABLESWITCH, op == bytecode.LOOKUPSWITCH:
+			enter(insn.Target, d)
+			for _, target := range insn.Targets {
+				enter(target, d)
+			}
+		case insn.IsBranch():
+			enter(insn.Target, d)
+			enter(next, d)
+		case op == bytecode.RET, op == bytecode.ATHROW, op >= bytecode.IRETURN && op <= bytecode.RETURN:
+		default:
+			enter(next, d)
+		}
+	}
+	return deepest
+}
+
+// kindSlots is the size of the k-th type in the opcode groups ordered
+// int, long, float, double, reference (then byte, char, short for arrays).
+func kindSlots(k int) int {
+	if k == 1 || k == 3 {
+		return 2
+	}
+	return 1
+}
+
+// conversions holds the slots popped and pushed by i2l through d2f.
+var conversions = [...][2]int{
+	{1, 2}, {1, 1}, {1, 2}, // i2l, i2f, i2d
+	{2, 1}, {2, 1}, {2, 2}, // l2i, l2f, l2d
+	{1, 1}, {1, 2}, {1, 2}, // f2i, f2l, f2d
+	{2, 1}, {2, 2}, {2, 1}, // d2i, d2l, d2f
+}
+
+// stackEffect returns the number of operand stack slots insn pops and pushes.
+func (self *CodeBuilder) stackEffect(insn bytecode.Instruction) (pop, push int, ok bool) {
+	switch op := insn.Opcode; {
+	case op == bytecode.NOP, op == bytecode.IINC, op == bytecode.GOTO, op == bytecode.GOTO_W,
+		op == bytecode.RET, op == bytecode.RETURN:
+		return 0, 0, true
+	case op == bytecode.LCONST_0, op == bytecode.LCONST_1, op == bytecode.DCONST_0, op == bytecode.DCONST_1,
+		op == bytecode.LDC2_W:
+		return 0, 2, true
+	case op <= bytecode.LDC_W, op == bytecode.JSR, op == bytecode.JSR_W, op == bytecode.NEW:
+		return 0, 1, true
+	case op >= bytecode.ILOAD && op <= bytecode.ALOAD:
+		return 0, kindSlots(int(op - bytecode.ILOAD)), true
+	case op >= bytecode.ILOAD_0 && op <= bytecode.ALOAD_3:
+		return 0, kindSlots(int(op-bytecode.ILOAD_0) / 4), true
+	case op >= bytecode.IALOAD && op <= bytecode.SALOAD:
+		return 2, kindSlots(int(op - bytecode.IALOAD)), true
+	case op >= bytecode.ISTORE && op <= bytecode.ASTORE:
+		return kindSlots(int(op - bytecode.ISTORE)), 0, true
+	case op >= bytecode.ISTORE_0 && op <= bytecode.ASTORE_3:
+		return kindSlots(int(op-bytecode.ISTORE_0) / 4), 0, true
+	case op >= bytecode.IASTORE && op <= bytecode.SASTORE:
+		return 2 + kindSlots(int(op-bytecode.IASTORE)), 0, true
+	case op == bytecode.POP:
+		return 1, 0, true
+	case op == bytecode.POP2:
+		return 2, 0, true
+	case op == bytecode.DUP:
+		return 1, 2, true
+	case op == bytecode.DUP_X1:
+		return 2, 3, true
+	case op == bytecode.DUP_X2:
+		return 3, 4, true
+	case op == bytecode.DUP2:
+		return 2, 4, true
+	case op == bytecode.DUP2_X1:
+		return 3, 5, true
+	case op == bytecode.DUP2_X2:
+		return 4, 6, true
+	case op == bytecode.SWAP:
+		return 2, 2, true
+	case op >= bytecode.IADD && op <= bytecode.DREM:
+		n := kindSlots(int(op-bytecode.IADD) % 4)
+		return 2 * n, n, true
+	case op >= bytecode.INEG && op <= bytecode.DNEG:
+		n := kindSlots(int(op - bytecode.INEG))
+		return n, n, true
+	case op >= bytecode.ISHL && op <= bytecode.LUSHR:
+		if (op-bytecode.ISHL)%2 == 1 {
+			return 3, 2, true
+		}
+		return 2, 1, true
+	case op >= bytecode.IAND && op <= bytecode.LXOR:
+		if (op-bytecode.IAND)%2 == 1 {
+			return 4, 2, true
+		}
+		return 2, 1, true
+	case op >= bytecode.I2L && op <= bytecode.D2F:
+		c := conversions[op-bytecode.I2L]
+		return c[0], c[1], true
+	case op >= bytecode.I2B && op <= bytecode.I2S:
+		return 1, 1, true
+	case op == bytecode.LCMP, op == bytecode.DCMPL, op == bytecode.DCMPG:
+		return 4, 1, true
+	case op == bytecode.FCMPL, op == bytecode.FCMPG:
+		return 2, 1, true
+	case op >= bytecode.IFEQ && op <= bytecode.IFLE, op == bytecode.IFNULL, op == bytecode.IFNONNULL,
+		op == bytecode.TABLESWITCH, op == bytecode.LOOKUPSWITCH, op == bytecode.ATHROW,
+		op == bytecode.MONITORENTER, op == bytecode.MONITOREXIT:
+		return 1, 0, true
+	case op >= bytecode.IF_ICMPEQ && op <= bytecode.IF_ACMPNE:
+		return 2, 0, true
+	case op >= bytecode.IRETURN && op <= bytecode.ARETURN:
+		return kindSlots(int(op - bytecode.IRETURN)), 0, true
+	case op == bytecode.NEWARRAY, op == bytecode.ANEWARRAY, op == bytecode.ARRAYLENGTH,
+		op == bytecode.CHECKCAST, op == bytecode.INSTANCEOF:
+		return 1, 1, true
+	case op == bytecode.MULTIANEWARRAY:
+		return insn.Value, 1, true
+	case op >= bytecode.GETSTATIC && op <= bytecode.PUTFIELD:
+		desc, ok := self.memberDescriptor(insn)
+		if !ok {
+			return 0, 0, false
+		}
+		t, err := descriptor.ParseFieldDescriptor(desc)
+		if err != nil {
+			self.fail("%s at pc %d: %v", op, insn.PC, err)
+			return 0, 0, false
+		}
+		switch op {
+		case bytecode.GETSTATIC:
+			return 0, t.Slots(), true
+		case bytecode.PUTSTATIC:
+			return t.Slots(), 0, true
+		case bytecode.GETFIELD:
+			return 1, t.Slots(), true
+		}
+		return 1 + t.Slots(), 0, true
+	case op >= bytecode.INVOKEVIRTUAL && op <= bytecode.INVOKEDYNAMIC:
+		desc, ok := self.memberDescriptor(insn)
+		if !ok {
+			return 0, 0, false
+		}
+		md, err := descriptor.ParseMethodDescriptor(desc)
+		if err != nil {
+			self.fail("%s at pc %d: %v", op, insn.PC, err)
+			return 0, 0, false
+		}
+		pop = md.ParameterSlots()
+		if op != bytecode.INVOKESTATIC && op != bytecode.INVOKEDYNAMIC {
+			pop++
+		}
+		return pop, md.ReturnType.Slots(), true
+	}
+	self.fail("%s at pc %d has no known stack effect", insn.Opcode, insn.PC)
+	return 0, 0, false
+}
+
+// memberDescriptor returns the descriptor of the field, method or call site
+// a field access or invocation refers to.
+func (self *CodeBuilder) memberDescriptor(insn bytecode.Instruction) (string, bool) {
+	entries := self.cp.entries
+	var nameAndType uint16
+	if insn.Index > 0 && insn.Index < len(entries) {
+		switch info := entries[insn.Index].(type) {
+		case *classfile.ConstantFieldrefInfo:
+			nameAndType = info.NameAndTypeIndex
+		case *classfile.ConstantMethodrefInfo:
+			nameAndType = info.NameAndTypeIndex
+		case *classfile.ConstantInterfaceMethodrefInfo:
+			nameAndType = info.NameAndTypeIndex
+		case *classfile.ConstantInvokeDynamicInfo:
+			nameAndType = info.NameAndTypeIndex
+		}
+	}
+	if int(nameAndType) < len(entries) {
+		if nt, ok := entries[nameAndType].(*classfile.ConstantNameAndTypeInfo); ok {
+			if utf8, ok := entries[nt.DescriptorIndex].(*classfile.ConstantUtf8Info); ok {
+				return utf8.Value(), true
+			}
+		}
+	}
+	self.fail("%s at pc %d: #%d is not a member reference", insn.Opcode, insn.PC, insn.Index)
+	return "", false
+}
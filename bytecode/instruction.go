@@ -0,0 +1,557 @@
+// Package bytecode decodes and encodes the instructions of a Code attribute.
+//
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-6.html
+package bytecode
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Instruction is one decoded instruction. Which operand fields are meaningful
+// depends on Opcode; the others are zero.
+type Instruction struct {
+	// PC is the offset of the instruction in the code array.
+	PC     int
+	Opcode Opcode
+	// Wide is set for a load, store, ret or iinc prefixed by the wide
+	// instruction, whose index and increment are then two bytes wide.
+	// Opcode is the modified instruction, not WIDE.
+	Wide bool
+	// Index is the local variable of a load, store, ret or iinc, or the
+	// constant pool entry of ldc, field access, invocation and type instructions.
+	Index int
+	// Value is the immediate of bipush and sipush, the increment of iinc, the
+	// atype of newarray, the count of invokeinterface or the dimensions of
+	// multianewarray.
+	Value int
+	// Target is the absolute pc a branch jumps to, or a switch's default.
+	Target int
+	// Low and High are the key range of a tableswitch.
+	Low, High int32
+	// Keys are the match keys of a lookupswitch.
+	Keys []int32
+	// Targets are the absolute jump targets of a switch, one per key from Low
+	// to High for tableswitch, one per entry of Keys for lookupswitch.
+	Targets []int
+}
+
+// newarray atypes
+const (
+	T_BOOLEAN = 4
+	T_CHAR    = 5
+	T_FLOAT   = 6
+	T_DOUBLE  = 7
+	T_BYTE    = 8
+	T_SHORT   = 9
+	T_INT     = 10
+	T_LONG    = 11
+)
+
+var arrayTypeNames = map[int]string{
+	T_BOOLEAN: "boolean", T_CHAR: "char", T_FLOAT: "float", T_DOUBLE: "double",
+	T_BYTE: "byte", T_SHORT: "short", T_INT: "int", T_LONG: "long",
+}
+
+// ArrayTypeName returns the element type a newarray atype denotes, e.g. "int".
+func ArrayTypeName(atype int) (string, bool) {
+	name, ok := arrayTypeNames[atype]
+	return name, ok
+}
+
+type operandFormat uint8
+
+const (
+	noOperands operandFormat = iota
+	localIndex
+	byteValue
+	shortValue
+	constantIndex1
+	constantIndex2
+	increment
+	branch2
+	branch4
+	tableSwitch
+	lookupSwitch
+	interfaceCall
+	dynamicCall
+	arrayType
+	multiArray
+	widePrefix
+)
+
+func formatOf(op Opcode) operandFormat {
+	switch {
+	case op == BIPUSH:
+		return byteValue
+	case op == SIPUSH:
+		return shortValue
+	case op == LDC:
+		return constantIndex1
+	case op == LDC_W, op == LDC2_W, op >= GETSTATIC && op <= INVOKESTATIC,
+		op == NEW, op == ANEWARRAY, op == CHECKCAST, op == INSTANCEOF:
+		return constantIndex2
+	case op >= ILOAD && op <= ALOAD, op >= ISTORE && op <= ASTORE, op == RET:
+		return localIndex
+	case op == IINC:
+		return increment
+	case op >= IFEQ && op <= JSR, op == IFNULL, op == IFNONNULL:
+		return branch2
+	case op == GOTO_W, op == JSR_W:
+		return branch4
+	case op == TABLESWITCH:
+		return tableSwitch
+	case op == LOOKUPSWITCH:
+		return lookupSwitch
+	case op == INVOKEINTERFACE:
+		return interfaceCall
+	case op == INVOKEDYNAMIC:
+		return dynamicCall
+	case op == NEWARRAY:
+		return arrayType
+	case op == MULTIANEWARRAY:
+		return multiArray
+	case op == WIDE:
+		return widePrefix
+	}
+	return noOperands
+}
+
+// switchPadding is the number of bytes between a switch opcode at pc and its
+// 4-byte aligned operands.
+func switchPadding(pc int) int {
+	return (4 - (pc+1)%4) % 4
+}
+
+// Len returns the encoded length of the instruction in bytes.
+func (self Instruction) Len() int {
+	if self.Wide {
+		if self.Opcode == IINC {
+			return 6
+		}
+		return 4
+	}
+	switch formatOf(self.Opcode) {
+	case localIndex, byteValue, constantIndex1, arrayType:
+		return 2
+	case shortValue, constantIndex2, increment, branch2:
+		return 3
+	case multiArray:
+		return 4
+	case branch4, interfaceCall, dynamicCall:
+		return 5
+	case tableSwitch:
+		return 1 + switchPadding(self.PC) + 12 + 4*len(self.Targets)
+	case lookupSwitch:
+		return 1 + switchPadding(self.PC) + 8 + 8*len(self.Keys)
+	}
+	return 1
+}
+
+// IsBranch reports whether the instruction transfers control to Target or
+// Targets, i.e. it is a conditional branch, goto, jsr or switch.
+func (self Instruction) IsBranch() bool {
+	switch formatOf(self.Opcode) {
+	case branch2, branch4, tableSwitch, lookupSwitch:
+		return true
+	}
+	return false
+}
+
+func (self Instruction) String() string {
+	name := self.Opcode.String()
+	if self.Wide {
+		name = "wide " + name
+	}
+	switch formatOf(self.Opcode) {
+	case localIndex:
+		return fmt.Sprintf("%s %d", name, self.Index)
+	case byteValue, shortValue:
+		return fmt.Sprintf("%s %d", name, self.Value)
+	case constantIndex1, constantIndex2, dynamicCall:
+		return fmt.Sprintf("%s #%d", name, self.Index)
+	case increment:
+		return fmt.Sprintf("%s %d, %d", name, self.Index, self.Value)
+	case branch2, branch4:
+		return fmt.Sprintf("%s %d", name, self.Target)
+	case tableSwitch, lookupSwitch:
+		cases := []string{}
+		for i, target := range self.Targets {
+			cases = append(cases, fmt.Sprintf("%d: %d", self.Key(i), target))
+		}
+		cases = append(cases, fmt.Sprintf("default: %d", self.Target))
+		return fmt.Sprintf("%s {%s}", name, strings.Join(cases, ", "))
+	case interfaceCall, multiArray:
+		return fmt.Sprintf("%s #%d, %d", name, self.Index, self.Value)
+	case arrayType:
+		if t, ok := ArrayTypeName(self.Value); ok {
+			return name + " " + t
+		}
+		return fmt.Sprintf("%s %d", name, self.Value)
+	}
+	return name
+}
+
+// ConstantIndex returns the constant pool entry the instruction refers to, if
+// it is ldc, a field access, an invocation, new or a type instruction.
+func (self Instruction) ConstantIndex() (uint16, bool) {
+	switch formatOf(self.Opcode) {
+	case constantIndex1, constantIndex2, interfaceCall, dynamicCall, multiArray:
+		return uint16(self.Index), true
+	}
+	return 0, false
+}
+
+// MarshalJSON encodes the instruction with just the operands its opcode has,
+// e.g. {"PC":3,"Opcode":"ldc","Index":13}.
+func (self Instruction) MarshalJSON() ([]byte, error) {
+	v := struct {
+		PC      int
+		Opcode  string
+		Wide    bool    `json:",omitempty"`
+		Index   *int    `json:",omitempty"`
+		Value   *int    `json:",omitempty"`
+		Target  *int    `json:",omitempty"`
+		Low     *int32  `json:",omitempty"`
+		High    *int32  `json:",omitempty"`
+		Keys    []int32 `json:",omitempty"`
+		Targets []int   `json:",omitempty"`
+	}{PC: self.PC, Opcode: self.Opcode.String(), Wide: self.Wide}
+	switch formatOf(self.Opcode) {
+	case localIndex, constantIndex1, constantIndex2, dynamicCall:
+		v.Index = &self.Index
+	case byteValue, shortValue, arrayType:
+		v.Value = &self.Value
+	case increment, interfaceCall, multiArray:
+		v.Index, v.Value = &self.Index, &self.Value
+	case branch2, branch4:
+		v.Target = &self.Target
+	case tableSwitch:
+		v.Target, v.Low, v.High, v.Targets = &self.Target, &self.Low, &self.High, self.Targets
+	case lookupSwitch:
+		v.Target, v.Keys, v.Targets = &self.Target, self.Keys, self.Targets
+	}
+	return json.Marshal(v)
+}
+
+// Key returns the match key of the i-th switch target.
+func (self Instruction) Key(i int) int32 {
+	if self.Opcode == TABLESWITCH {
+		return self.Low + int32(i)
+	}
+	return self.Keys[i]
+}
+
+// DecodeError reports malformed bytecode.
+type DecodeError struct {
+	PC  int
+	Msg string
+}
+
+func (self *DecodeError) Error() string {
+	return fmt.Sprintf("bytecode: %s at pc %d", self.Msg, self.PC)
+}
+
+type decoder struct {
+	code []byte
+	pc   int
+	// start is the pc of the instruction being decoded
+	start int
+	err   error
+}
+
+func (self *decoder) bytes(n int) []byte {
+	if self.err != nil {
+		return make([]byte, n)
+	}
+	if n < 0 || self.pc+n > len(self.code) {
+		self.err = &DecodeError{self.start, "truncated instruction"}
+		return make([]byte, n)
+	}
+	b := self.code[self.pc : self.pc+n]
+	self.pc += n
+	return b
+}
+
+func (self *decoder) u1() int { return int(self.bytes(1)[0]) }
+func (self *decoder) s1() int { return int(int8(self.bytes(1)[0])) }
+func (self *decoder) u2() int { return int(binary.BigEndian.Uint16(self.bytes(2))) }
+func (self *decoder) s2() int { return int(int16(binary.BigEndian.Uint16(self.bytes(2)))) }
+func (self *decoder) s4() int32 {
+	return int32(binary.BigEndian.Uint32(self.bytes(4)))
+}
+
+func (self *decoder) fail(format string, a ...any) {
+	if self.err == nil {
+		self.err = &DecodeError{self.start, fmt.Sprintf(format, a...)}
+	}
+}
+
+// Decode decodes the instruction at pc, which must be within code.
+func Decode(code []byte, pc int) (Instruction, error) {
+	if pc < 0 || pc >= len(code) {
+		return Instruction{}, &DecodeError{pc, "pc outside the code array"}
+	}
+	d := &decoder{code: code, pc: pc, start: pc}
+	insn := d.instruction()
+	if d.err != nil {
+		return Instruction{}, d.err
+	}
+	return insn, nil
+}
+
+// DecodeAll decodes a whole code array.
+func DecodeAll(code []byte) ([]Instruction, error) {
+	insns := []Instruction{}
+	for pc := 0; pc < len(code); {
+		insn, err := Decode(code, pc)
+		if err != nil {
+			return insns, err
+		}
+		insns = append(insns, insn)
+		pc += insn.Len()
+	}
+	return insns, nil
+}
+
+func (self *decoder) instruction() Instruction {
+	insn := Instruction{PC: self.start, Opcode: Opcode(self.u1())}
+	if self.err == nil && !insn.Opcode.IsValid() {
+		self.fail("unknown opcode %#02x", byte(insn.Opcode))
+	}
+	switch formatOf(insn.Opcode) {
+	case localIndex:
+		insn.Index = self.u1()
+	case byteValue:
+		insn.Value = self.s1()
+	case shortValue:
+		insn.Value = self.s2()
+	case constantIndex1:
+		insn.Index = self.u1()
+	case constantIndex2:
+		insn.Index = self.u2()
+	case increment:
+		insn.Index = self.u1()
+		insn.Value = self.s1()
+	case branch2:
+		insn.Target = insn.PC + self.s2()
+	case branch4:
+		insn.Target = insn.PC + int(self.s4())
+	case tableSwitch, lookupSwitch:
+		// The padding is normally zero, but the JVMS does not require it.
+		self.bytes(switchPadding(insn.PC))
+		insn.Target = insn.PC + int(self.s4())
+		var n int
+		if insn.Opcode == TABLESWITCH {
+			insn.Low, insn.High = self.s4(), self.s4()
+			if insn.Low > insn.High {
+				self.fail("tableswitch low %d > high %d", insn.Low, insn.High)
+			}
+			n = int(insn.High) - int(insn.Low) + 1
+		} else {
+			n = int(self.s4())
+			if n < 0 {
+				self.fail("negative lookupswitch npairs %d", n)
+			}
+		}
+		// Each entry takes at least 4 bytes, which bounds n by what is left.
+		if self.err == nil && n > (len(self.code)-self.pc)/4 {
+			self.fail("truncated instruction")
+		}
+		if self.err != nil {
+			break
+		}
+		insn.Targets = make([]int, n)
+		if insn.Opcode == LOOKUPSWITCH {
+			insn.Keys = make([]int32, n)
+		}
+		for i := 0; i < n; i++ {
+			if insn.Opcode == LOOKUPSWITCH {
+				insn.Keys[i] = self.s4()
+				if i > 0 && self.err == nil && insn.Keys[i] <= insn.Keys[i-1] {
+					self.fail("lookupswitch keys not sorted")
+				}
+			}
+			insn.Targets[i] = insn.PC + int(self.s4())
+		}
+	case interfaceCall:
+		insn.Index = self.u2()
+		insn.Value = self.u1()
+		if self.u1() != 0 {
+			self.fail("invokeinterface fourth operand byte is not zero")
+		}
+	case dynamicCall:
+		insn.Index = self.u2()
+		if self.u2() != 0 {
+			self.fail("invokedynamic third and fourth operand bytes are not zero")
+		}
+	case arrayType:
+		insn.Value = self.u1()
+	case multiArray:
+		insn.Index = self.u2()
+		insn.Value = self.u1()
+	case widePrefix:
+		insn.Opcode = Opcode(self.u1())
+		insn.Wide = true
+		switch formatOf(insn.Opcode) {
+		case localIndex:
+			insn.Index = self.u2()
+		case increment:
+			insn.Index = self.u2()
+			insn.Value = self.s2()
+		default:
+			self.fail("wide applied to %s", insn.Opcode)
+		}
+	}
+	return insn
+}
+
+// checkOperands reports the first operand that does not fit the field its
+// opcode encodes it in.
+func (self Instruction) checkOperands() error {
+	type operand struct {
+		name     string
+		v        int
+		min, max int
+	}
+	index1 := operand{"index", self.Index, 0, math.MaxUint8}
+	index2 := operand{"index", self.Index, 0, math.MaxUint16}
+	var operands []operand
+	if self.Wide {
+		operands = append(operands, index2)
+		if self.Opcode == IINC {
+			operands = append(operands, operand{"increment", self.Value, math.MinInt16, math.MaxInt16})
+		}
+	} else {
+		switch formatOf(self.Opcode) {
+		case localIndex, constantIndex1:
+			operands = append(operands, index1)
+		case byteValue:
+			operands = append(operands, operand{"value", self.Value, math.MinInt8, math.MaxInt8})
+		case shortValue:
+			operands = append(operands, operand{"value", self.Value, math.MinInt16, math.MaxInt16})
+		case constantIndex2, dynamicCall:
+			operands = append(operands, index2)
+		case increment:
+			operands = append(operands, index1, operand{"increment", self.Value, math.MinInt8, math.MaxInt8})
+		case branch2:
+			operands = append(operands, operand{"branch offset", self.Target - self.PC, math.MinInt16, math.MaxInt16})
+		case branch4, tableSwitch, lookupSwitch:
+			operands = append(operands, operand{"branch offset", self.Target - self.PC, math.MinInt32, math.MaxInt32})
+			for _, target := range self.Targets {
+				operands = append(operands, operand{"branch offset", target - self.PC, math.MinInt32, math.MaxInt32})
+			}
+		case interfaceCall:
+			operands = append(operands, index2, operand{"count", self.Value, 0, math.MaxUint8})
+		case arrayType:
+			operands = append(operands, operand{"atype", self.Value, 0, math.MaxUint8})
+		case multiArray:
+			operands = append(operands, index2, operand{"dimensions", self.Value, 0, math.MaxUint8})
+		}
+	}
+	for _, o := range operands {
+		if o.v < o.min || o.v > o.max {
+			return &DecodeError{self.PC, fmt.Sprintf("%s %s %d out of range", self.Opcode, o.name, o.v)}
+		}
+	}
+	return nil
+}
+
+// Encode appends the encoding of the instruction to b. Branch targets are
+// converted back to offsets relative to PC, and switch padding is zero. An
+// operand that does not fit its encoding is a *DecodeError.
+func (self Instruction) Encode(b []byte) ([]byte, error) {
+	errorf := func(format string, a ...any) ([]byte, error) {
+		return b, &DecodeError{self.PC, fmt.Sprintf(format, a...)}
+	}
+	if err := self.checkOperands(); err != nil {
+		return b, err
+	}
+	u2 := func(v int) { b = binary.BigEndian.AppendUint16(b, uint16(v)) }
+	s4 := func(v int32) { b = binary.BigEndian.AppendUint32(b, uint32(v)) }
+	offset := func(target int) int { return target - self.PC }
+
+	if self.Wide {
+		b = append(b, byte(WIDE), byte(self.Opcode))
+		switch formatOf(self.Opcode) {
+		case localIndex:
+			u2(self.Index)
+		case increment:
+			u2(self.Index)
+			u2(self.Value)
+		default:
+			return errorf("wide applied to %s", self.Opcode)
+		}
+		return b, nil
+	}
+	if !self.Opcode.IsValid() || self.Opcode == WIDE {
+		return errorf("cannot encode %s", self.Opcode)
+	}
+	b = append(b, byte(self.Opcode))
+	switch formatOf(self.Opcode) {
+	case localIndex, constantIndex1:
+		b = append(b, byte(self.Index))
+	case byteValue, arrayType:
+		b = append(b, byte(self.Value))
+	case shortValue:
+		u2(self.Value)
+	case constantIndex2:
+		u2(self.Index)
+	case increment:
+		b = append(b, byte(self.Index), byte(self.Value))
+	case branch2:
+		u2(offset(self.Target))
+	case branch4:
+		s4(int32(offset(self.Target)))
+	case tableSwitch, lookupSwitch:
+		b = append(b, make([]byte, switchPadding(self.PC))...)
+		s4(int32(offset(self.Target)))
+		if self.Opcode == TABLESWITCH {
+			if int(self.High)-int(self.Low)+1 != len(self.Targets) {
+				return errorf("tableswitch has %d targets for keys %d to %d", len(self.Targets), self.Low, self.High)
+			}
+			s4(self.Low)
+			s4(self.High)
+		} else {
+			if len(self.Keys) != len(self.Targets) {
+				return errorf("lookupswitch has %d keys and %d targets", len(self.Keys), len(self.Targets))
+			}
+			s4(int32(len(self.Keys)))
+		}
+		for i, target := range self.Targets {
+			if self.Opcode == LOOKUPSWITCH {
+				s4(self.Keys[i])
+			}
+			s4(int32(offset(target)))
+		}
+	case interfaceCall:
+		u2(self.Index)
+		b = append(b, byte(self.Value), 0)
+	case dynamicCall:
+		u2(self.Index)
+		u2(0)
+	case multiArray:
+		u2(self.Index)
+		b = append(b, byte(self.Value))
+	}
+	return b, nil
+}
+
+// EncodeAll encodes instructions into a code array. Each instruction's PC must
+// be the offset at which it ends up, as DecodeAll leaves it.
+func EncodeAll(insns []Instruction) ([]byte, error) {
+	code := []byte{}
+	for _, insn := range insns {
+		if insn.PC != len(code) {
+			return nil, &DecodeError{insn.PC, fmt.Sprintf("instruction placed at pc %d", len(code))}
+		}
+		var err error
+		if code, err = insn.Encode(code); err != nil {
+			return nil, err
+		}
+	}
+	return code, nil
+}
@@ -0,0 +1,187 @@
+package bytecode
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// operandBytes returns well-formed operands for op at pc.
+func operandBytes(op Opcode, pc int) []byte {
+	switch formatOf(op) {
+	case localIndex, byteValue, constantIndex1:
+		return []byte{7}
+	case arrayType:
+		return []byte{T_INT}
+	case shortValue, constantIndex2, increment, branch2:
+		return []byte{0xff, 0xfe}
+	case branch4:
+		return []byte{0, 0, 0, 3}
+	case multiArray:
+		return []byte{0, 2, 3}
+	case interfaceCall:
+		return []byte{0, 2, 1, 0}
+	case dynamicCall:
+		return []byte{0, 2, 0, 0}
+	case tableSwitch:
+		b := make([]byte, switchPadding(pc))
+		return append(b, 0, 0, 0, 20, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 12, 0, 0, 0, 16)
+	case lookupSwitch:
+		b := make([]byte, switchPadding(pc))
+		return append(b, 0, 0, 0, 20, 0, 0, 0, 2, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 12, 0, 0, 0, 9, 0, 0, 0, 16)
+	case widePrefix:
+		return []byte{byte(IINC), 1, 0, 0xff, 0xff}
+	}
+	return nil
+}
+
+func TestDecodeEveryOpcode(t *testing.T) {
+	code := []byte{}
+	count := 0
+	for op := 0; op < 256; op++ {
+		if !Opcode(op).IsValid() {
+			continue
+		}
+		pc := len(code)
+		code = append(code, byte(op))
+		code = append(code, operandBytes(Opcode(op), pc)...)
+		count++
+	}
+
+	insns, err := DecodeAll(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(insns) != count {
+		t.Fatalf("decoded %d instructions, want %d", len(insns), count)
+	}
+	pc := 0
+	for _, insn := range insns {
+		if insn.PC != pc {
+			t.Errorf("%s: pc %d, want %d", insn, insn.PC, pc)
+		}
+		pc += insn.Len()
+	}
+	encoded, err := EncodeAll(insns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, code) {
+		t.Errorf("round trip differs:\n got %x\nwant %x", encoded, code)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		code []byte
+		pc   int
+		want string
+		len  int
+	}{
+		{[]byte{byte(BIPUSH), 0x80}, 0, "bipush -128", 2},
+		{[]byte{byte(SIPUSH), 0x01, 0x00}, 0, "sipush 256", 3},
+		{[]byte{byte(WIDE), byte(ILOAD), 0x01, 0x2c}, 0, "wide iload 300", 4},
+		{[]byte{byte(WIDE), byte(IINC), 0x01, 0x2c, 0xfc, 0x18}, 0, "wide iinc 300, -1000", 6},
+		{[]byte{byte(NOP), byte(GOTO), 0xff, 0xff}, 1, "goto 0", 3},
+		{[]byte{byte(INVOKEINTERFACE), 0, 5, 2, 0}, 0, "invokeinterface #5, 2", 5},
+		{[]byte{byte(NEWARRAY), T_BOOLEAN}, 0, "newarray boolean", 2},
+		// pc 1 needs two bytes of padding
+		{[]byte{byte(NOP), byte(TABLESWITCH), 0, 0, 0, 0, 0, 17, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 15}, 1,
+			"tableswitch {1: 16, default: 18}", 19},
+		{[]byte{byte(NOP), byte(NOP), byte(NOP), byte(LOOKUPSWITCH), 0, 0, 0, 5, 0, 0, 0, 0}, 3,
+			"lookupswitch {default: 8}", 9},
+	}
+	for _, tt := range tests {
+		insn, err := Decode(tt.code, tt.pc)
+		if err != nil {
+			t.Errorf("Decode(%x, %d): %v", tt.code, tt.pc, err)
+			continue
+		}
+		if got := insn.String(); got != tt.want {
+			t.Errorf("Decode(%x, %d) = %q, want %q", tt.code, tt.pc, got, tt.want)
+		}
+		if insn.Len() != tt.len {
+			t.Errorf("%s: length %d, want %d", insn, insn.Len(), tt.len)
+		}
+		encoded, err := insn.Encode(nil)
+		if err != nil {
+			t.Errorf("%s: %v", insn, err)
+		} else if !bytes.Equal(encoded, tt.code[tt.pc:]) {
+			t.Errorf("%s: encoded %x, want %x", insn, encoded, tt.code[tt.pc:])
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		code []byte
+		want string
+	}{
+		{[]byte{0xcb}, "bytecode: unknown opcode 0xcb at pc 0"},
+		{[]byte{byte(SIPUSH), 1}, "bytecode: truncated instruction at pc 0"},
+		{[]byte{byte(WIDE), byte(GOTO), 0, 0}, "bytecode: wide applied to goto at pc 0"},
+		{[]byte{byte(INVOKEINTERFACE), 0, 5, 2, 1}, "bytecode: invokeinterface fourth operand byte is not zero at pc 0"},
+		{[]byte{byte(INVOKEDYNAMIC), 0, 5, 0, 1}, "bytecode: invokedynamic third and fourth operand bytes are not zero at pc 0"},
+		{[]byte{byte(TABLESWITCH), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 1}, "bytecode: tableswitch low 2 > high 1 at pc 0"},
+		{[]byte{byte(LOOKUPSWITCH), 0, 0, 0, 0, 0, 0, 0, 0x7f, 0xff, 0xff, 0xff}, "bytecode: truncated instruction at pc 0"},
+	}
+	for _, tt := range tests {
+		_, err := DecodeAll(tt.code)
+		if err == nil {
+			t.Errorf("DecodeAll(%x) succeeded, want %q", tt.code, tt.want)
+		} else if err.Error() != tt.want {
+			t.Errorf("DecodeAll(%x) = %q, want %q", tt.code, err, tt.want)
+		}
+	}
+}
+
+func TestDecodeOutsideCode(t *testing.T) {
+	code := []byte{byte(NOP), byte(RETURN)}
+	for _, pc := range []int{-1, 2, 3} {
+		_, err := Decode(code, pc)
+		want := fmt.Sprintf("bytecode: pc outside the code array at pc %d", pc)
+		if err == nil || err.Error() != want {
+			t.Errorf("Decode(%x, %d) = %v, want %q", code, pc, err, want)
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		insn Instruction
+		want string
+	}{
+		{Instruction{Opcode: BIPUSH, Value: 300}, "bytecode: bipush value 300 out of range at pc 0"},
+		{Instruction{Opcode: BIPUSH, Value: -129}, "bytecode: bipush value -129 out of range at pc 0"},
+		{Instruction{Opcode: SIPUSH, Value: 70000}, "bytecode: sipush value 70000 out of range at pc 0"},
+		{Instruction{Opcode: LDC, Index: 256}, "bytecode: ldc index 256 out of range at pc 0"},
+		{Instruction{Opcode: LDC_W, Index: 70000}, "bytecode: ldc_w index 70000 out of range at pc 0"},
+		{Instruction{Opcode: GETSTATIC, Index: -1}, "bytecode: getstatic index -1 out of range at pc 0"},
+		{Instruction{Opcode: ILOAD, Index: -1}, "bytecode: iload index -1 out of range at pc 0"},
+		{Instruction{Opcode: ILOAD, Index: 256}, "bytecode: iload index 256 out of range at pc 0"},
+		{Instruction{Opcode: ILOAD, Wide: true, Index: 0x10000}, "bytecode: iload index 65536 out of range at pc 0"},
+		{Instruction{Opcode: IINC, Index: 300, Value: 1}, "bytecode: iinc index 300 out of range at pc 0"},
+		{Instruction{Opcode: IINC, Index: 1, Value: 200}, "bytecode: iinc increment 200 out of range at pc 0"},
+		{Instruction{Opcode: IINC, Wide: true, Index: 1, Value: 40000}, "bytecode: iinc increment 40000 out of range at pc 0"},
+		{Instruction{Opcode: GOTO, PC: 4, Target: 0x8004}, "bytecode: goto branch offset 32768 out of range at pc 4"},
+		{Instruction{Opcode: INVOKEINTERFACE, Index: 70000, Value: 1}, "bytecode: invokeinterface index 70000 out of range at pc 0"},
+		{Instruction{Opcode: INVOKEINTERFACE, Index: 1, Value: 256}, "bytecode: invokeinterface count 256 out of range at pc 0"},
+		{Instruction{Opcode: INVOKEDYNAMIC, Index: 70000}, "bytecode: invokedynamic index 70000 out of range at pc 0"},
+		{Instruction{Opcode: NEWARRAY, Value: 256}, "bytecode: newarray atype 256 out of range at pc 0"},
+		{Instruction{Opcode: MULTIANEWARRAY, Index: 70000, Value: 1}, "bytecode: multianewarray index 70000 out of range at pc 0"},
+		{Instruction{Opcode: MULTIANEWARRAY, Index: 1, Value: 256}, "bytecode: multianewarray dimensions 256 out of range at pc 0"},
+	}
+	for _, tt := range tests {
+		b, err := tt.insn.Encode(nil)
+		if err == nil {
+			t.Errorf("%s encoded as %x, want %q", tt.insn, b, tt.want)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.insn, err, tt.want)
+		}
+		if len(b) != 0 {
+			t.Errorf("%s: appended %x before failing", tt.insn, b)
+		}
+	}
+}
@@ -11,6 +11,15 @@ func (self AccessFlags) String() string {
 	if self.IsPublic() {
 		flgs = append(flgs, "public")
 	}
+	if self.IsPrivate() {
+		flgs = append(flgs, "private")
+	}
+	if self.IsProtected() {
+		flgs = append(flgs, "protected")
+	}
+	if self.IsStatic() {
+		flgs = append(flgs, "static")
+	}
 	if self.IsFinal() {
 		flgs = append(flgs, "final")
 	}
@@ -41,6 +50,15 @@ func (self AccessFlags) String() string {
 func (self AccessFlags) IsPublic() bool {
 	return self&ACC_PUBLIC == ACC_PUBLIC
 }
+func (self AccessFlags) IsPrivate() bool {
+	return self&ACC_PRIVATE == ACC_PRIVATE
+}
+func (self AccessFlags) IsProtected() bool {
+	return self&ACC_PROTECTED == ACC_PROTECTED
+}
+func (self AccessFlags) IsStatic() bool {
+	return self&ACC_STATIC == ACC_STATIC
+}
 func (self AccessFlags) IsFinal() bool {
 	return self&ACC_FINAL == ACC_FINAL
 }
@@ -68,6 +86,9 @@ func (self AccessFlags) IsModule() bool {
 
 const (
 	ACC_PUBLIC     = 0x0001
+	ACC_PRIVATE    = 0x0002
+	ACC_PROTECTED  = 0x0004
+	ACC_STATIC     = 0x0008
 	ACC_FINAL      = 0x0010
 	ACC_SUPER      = 0x0020
 	ACC_INTERFACE  = 0x0200
@@ -76,4 +97,13 @@ const (
 	ACC_ANNOTATION = 0x2000
 	ACC_ENUM       = 0x4000
 	ACC_MODULE     = 0x8000
+
+	// method and field flags sharing bits with the ones above
+	ACC_SYNCHRONIZED = 0x0020
+	ACC_VOLATILE     = 0x0040
+	ACC_BRIDGE       = 0x0040
+	ACC_TRANSIENT    = 0x0080
+	ACC_VARARGS      = 0x0080
+	ACC_NATIVE       = 0x0100
+	ACC_STRICT       = 0x0800
 )
@@ -0,0 +1,703 @@
+package classfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gjvm/descriptor"
+)
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.16
+//
+//	RuntimeVisibleAnnotations_attribute {
+//	    u2         attribute_name_index;
+//	    u4         attribute_length;
+//	    u2         num_annotations;
+//	    annotation annotations[num_annotations];
+//	}
+//
+// RuntimeInvisibleAnnotations (4.7.17) has the same layout.
+type AnnotationsAttribute struct {
+	AttributeHeader
+	// Visible tells RuntimeVisibleAnnotations from RuntimeInvisibleAnnotations.
+	Visible     bool
+	Annotations []Annotation
+}
+
+func (self AnnotationsAttribute) String() string {
+	return fmt.Sprintf("%s: %s", visibility(self.Visible, RuntimeVisibleAnnotations, RuntimeInvisibleAnnotations), annotationList(self.Annotations))
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.18
+//
+//	RuntimeVisibleParameterAnnotations_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u1 num_parameters;
+//	    {   u2         num_annotations;
+//	        annotation annotations[num_annotations];
+//	    } parameter_annotations[num_parameters];
+//	}
+//
+// RuntimeInvisibleParameterAnnotations (4.7.19) has the same layout.
+type ParameterAnnotationsAttribute struct {
+	AttributeHeader
+	Visible bool
+	// ParameterAnnotations holds one list per parameter.
+	ParameterAnnotations [][]Annotation
+}
+
+func (self ParameterAnnotationsAttribute) String() string {
+	params := []string{}
+	for i, annotations := range self.ParameterAnnotations {
+		params = append(params, fmt.Sprintf("parameter %d: %s", i, annotationList(annotations)))
+	}
+	return fmt.Sprintf("%s: [%s]", visibility(self.Visible, RuntimeVisibleParameterAnnotations, RuntimeInvisibleParameterAnnotations), strings.Join(params, ", "))
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.20
+//
+//	RuntimeVisibleTypeAnnotations_attribute {
+//	    u2              attribute_name_index;
+//	    u4              attribute_length;
+//	    u2              num_annotations;
+//	    type_annotation annotations[num_annotations];
+//	}
+//
+// RuntimeInvisibleTypeAnnotations (4.7.21) has the same layout.
+type TypeAnnotationsAttribute struct {
+	AttributeHeader
+	Visible     bool
+	Annotations []TypeAnnotation
+}
+
+func (self TypeAnnotationsAttribute) String() string {
+	annotations := []string{}
+	for _, a := range self.Annotations {
+		annotations = append(annotations, a.String())
+	}
+	return fmt.Sprintf("%s: [%s]", visibility(self.Visible, RuntimeVisibleTypeAnnotations, RuntimeInvisibleTypeAnnotations), strings.Join(annotations, ", "))
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.22
+//
+//	AnnotationDefault_attribute {
+//	    u2            attribute_name_index;
+//	    u4            attribute_length;
+//	    element_value default_value;
+//	}
+type AnnotationDefaultAttribute struct {
+	AttributeHeader
+	DefaultValue ElementValue
+}
+
+func (self AnnotationDefaultAttribute) String() string {
+	return fmt.Sprintf("AnnotationDefault: default_value=%s", self.DefaultValue)
+}
+
+//	annotation {
+//	    u2 type_index;
+//	    u2 num_element_value_pairs;
+//	    {   u2            element_name_index;
+//	        element_value value;
+//	    } element_value_pairs[num_element_value_pairs];
+//	}
+type Annotation struct {
+	TypeIndex uint16
+	// Type is a field descriptor, e.g. "Lorg/junit/Test;".
+	Type              string
+	ElementValuePairs []ElementValuePair
+}
+
+// TypeName returns the annotation interface in internal form, e.g. "org/junit/Test".
+func (self Annotation) TypeName() string {
+	return strings.TrimSuffix(strings.TrimPrefix(self.Type, "L"), ";")
+}
+
+// Value returns the value given for the element called name. Elements left at
+// their default are not recorded in the class file.
+func (self Annotation) Value(name string) (ElementValue, bool) {
+	for _, pair := range self.ElementValuePairs {
+		if pair.ElementName == name {
+			return pair.Value, true
+		}
+	}
+	return nil, false
+}
+
+// String renders the annotation as Java source, e.g. `@org.junit.Test(timeout=100L)`.
+func (self Annotation) String() string {
+	s := "@" + javaTypeName(self.Type)
+	if len(self.ElementValuePairs) > 0 {
+		pairs := []string{}
+		for _, pair := range self.ElementValuePairs {
+			pairs = append(pairs, pair.String())
+		}
+		s += "(" + strings.Join(pairs, ", ") + ")"
+	}
+	return s
+}
+
+type ElementValuePair struct {
+	ElementNameIndex uint16
+	ElementName      string
+	Value            ElementValue
+}
+
+func (self ElementValuePair) String() string {
+	return self.ElementName + "=" + self.Value.String()
+}
+
+//	element_value {
+//	    u1 tag;
+//	    union {
+//	        u2 const_value_index;
+//	        {   u2 type_name_index;
+//	            u2 const_name_index;
+//	        } enum_const_value;
+//	        u2 class_info_index;
+//	        annotation annotation_value;
+//	        {   u2            num_values;
+//	            element_value values[num_values];
+//	        } array_value;
+//	    } value;
+//	}
+//
+// An ElementValue is a ConstElementValue, EnumElementValue, ClassElementValue,
+// AnnotationElementValue or ArrayElementValue.
+type ElementValue interface {
+	Tag() byte
+	String() string
+}
+
+// ConstElementValue is any of the tags B C D F I J S Z s.
+type ConstElementValue struct {
+	ValueTag        byte
+	ConstValueIndex uint16
+	// Value is the decoded constant: int32 for B C I S Z, int64 for J,
+	// float32 for F, float64 for D and string for s.
+	Value any
+}
+
+func (self ConstElementValue) Tag() byte {
+	return self.ValueTag
+}
+
+func (self ConstElementValue) String() string {
+	switch self.ValueTag {
+	case 'B':
+		return fmt.Sprintf("(byte)%d", self.Value)
+	case 'S':
+		return fmt.Sprintf("(short)%d", self.Value)
+	case 'C':
+		return strconv.QuoteRune(rune(self.Value.(int32)))
+	case 'Z':
+		return strconv.FormatBool(self.Value.(int32) != 0)
+	case 'J':
+		return fmt.Sprintf("%dL", self.Value)
+	case 'F':
+		return FormatJavaFloat(float64(self.Value.(float32)), 32) + "f"
+	case 'D':
+		return FormatJavaFloat(self.Value.(float64), 64) + "d"
+	case 's':
+		return strconv.Quote(self.Value.(string))
+	}
+	return fmt.Sprint(self.Value)
+}
+
+// EnumElementValue is tag e.
+type EnumElementValue struct {
+	TypeNameIndex  uint16
+	ConstNameIndex uint16
+	// TypeName is a field descriptor, e.g. "Ljava/lang/annotation/RetentionPolicy;".
+	TypeName  string
+	ConstName string
+}
+
+func (self EnumElementValue) Tag() byte {
+	return 'e'
+}
+
+func (self EnumElementValue) String() string {
+	return javaTypeName(self.TypeName) + "." + self.ConstName
+}
+
+// ClassElementValue is tag c.
+type ClassElementValue struct {
+	ClassInfoIndex uint16
+	// ClassInfo is a return descriptor, e.g. "Ljava/lang/String;" or "V".
+	ClassInfo string
+}
+
+func (self ClassElementValue) Tag() byte {
+	return 'c'
+}
+
+func (self ClassElementValue) String() string {
+	return javaTypeName(self.ClassInfo) + ".class"
+}
+
+// AnnotationElementValue is tag @.
+type AnnotationElementValue struct {
+	AnnotationValue Annotation
+}
+
+func (self AnnotationElementValue) Tag() byte {
+	return '@'
+}
+
+func (self AnnotationElementValue) String() string {
+	return self.AnnotationValue.String()
+}
+
+// ArrayElementValue is tag [.
+type ArrayElementValue struct {
+	Values []ElementValue
+}
+
+func (self ArrayElementValue) Tag() byte {
+	return '['
+}
+
+func (self ArrayElementValue) String() string {
+	values := []string{}
+	for _, v := range self.Values {
+		values = append(values, v.String())
+	}
+	return "{" + strings.Join(values, ", ") + "}"
+}
+
+//	type_annotation {
+//	    u1 target_type;
+//	    union {
+//	        type_parameter_target;
+//	        supertype_target;
+//	        type_parameter_bound_target;
+//	        empty_target;
+//	        formal_parameter_target;
+//	        throws_target;
+//	        localvar_target;
+//	        catch_target;
+//	        offset_target;
+//	        type_argument_target;
+//	    } target_info;
+//	    type_path target_path;
+//	    u2        type_index;
+//	    u2        num_element_value_pairs;
+//	    {   u2            element_name_index;
+//	        element_value value;
+//	    } element_value_pairs[num_element_value_pairs];
+//	}
+type TypeAnnotation struct {
+	TargetType uint8
+	TargetInfo TargetInfo
+	TargetPath []TypePathEntry
+	Annotation
+}
+
+func (self TypeAnnotation) String() string {
+	return fmt.Sprintf("%s /* target_type=%#x %s */", self.Annotation, self.TargetType, self.TargetInfo.describe(self.TargetType))
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.20.1
+//
+// Which fields of TargetInfo are meaningful depends on the target_type.
+const (
+	TargetClassTypeParameter           = 0x00 // type_parameter_target
+	TargetMethodTypeParameter          = 0x01 // type_parameter_target
+	TargetClassExtends                 = 0x10 // supertype_target
+	TargetClassTypeParameterBound      = 0x11 // type_parameter_bound_target
+	TargetMethodTypeParameterBound     = 0x12 // type_parameter_bound_target
+	TargetField                        = 0x13 // empty_target
+	TargetMethodReturn                 = 0x14 // empty_target
+	TargetMethodReceiver               = 0x15 // empty_target
+	TargetMethodFormalParameter        = 0x16 // formal_parameter_target
+	TargetThrows                       = 0x17 // throws_target
+	TargetLocalVariable                = 0x40 // localvar_target
+	TargetResourceVariable             = 0x41 // localvar_target
+	TargetExceptionParameter           = 0x42 // catch_target
+	TargetInstanceOf                   = 0x43 // offset_target
+	TargetNew                          = 0x44 // offset_target
+	TargetConstructorReference         = 0x45 // offset_target
+	TargetMethodReference              = 0x46 // offset_target
+	TargetCast                         = 0x47 // type_argument_target
+	TargetConstructorInvocationTypeArg = 0x48 // type_argument_target
+	TargetMethodInvocationTypeArg      = 0x49 // type_argument_target
+	TargetConstructorReferenceTypeArg  = 0x4A // type_argument_target
+	TargetMethodReferenceTypeArg       = 0x4B // type_argument_target
+)
+
+type TargetInfo struct {
+	TypeParameterIndex   uint8
+	SupertypeIndex       uint16
+	BoundIndex           uint8
+	FormalParameterIndex uint8
+	ThrowsTypeIndex      uint16
+	LocalVarTable        []LocalVarTargetEntry
+	ExceptionTableIndex  uint16
+	Offset               uint16
+	TypeArgumentIndex    uint8
+}
+
+//	localvar_target {
+//	    u2 table_length;
+//	    {   u2 start_pc;
+//	        u2 length;
+//	        u2 index;
+//	    } table[table_length];
+//	}
+type LocalVarTargetEntry struct {
+	StartPc uint16
+	Length  uint16
+	Index   uint16
+}
+
+func (self TargetInfo) describe(targetType uint8) string {
+	switch targetType {
+	case TargetClassTypeParameter, TargetMethodTypeParameter:
+		return fmt.Sprintf("type_parameter_index=%d", self.TypeParameterIndex)
+	case TargetClassExtends:
+		return fmt.Sprintf("supertype_index=%d", self.SupertypeIndex)
+	case TargetClassTypeParameterBound, TargetMethodTypeParameterBound:
+		return fmt.Sprintf("type_parameter_index=%d, bound_index=%d", self.TypeParameterIndex, self.BoundIndex)
+	case TargetMethodFormalParameter:
+		return fmt.Sprintf("formal_parameter_index=%d", self.FormalParameterIndex)
+	case TargetThrows:
+		return fmt.Sprintf("throws_type_index=%d", self.ThrowsTypeIndex)
+	case TargetLocalVariable, TargetResourceVariable:
+		entries := []string{}
+		for _, e := range self.LocalVarTable {
+			entries = append(entries, fmt.Sprintf("{start_pc=%d, length=%d, index=%d}", e.StartPc, e.Length, e.Index))
+		}
+		return "table=[" + strings.Join(entries, ", ") + "]"
+	case TargetExceptionParameter:
+		return fmt.Sprintf("exception_table_index=%d", self.ExceptionTableIndex)
+	case TargetInstanceOf, TargetNew, TargetConstructorReference, TargetMethodReference:
+		return fmt.Sprintf("offset=%d", self.Offset)
+	case TargetCast, TargetConstructorInvocationTypeArg, TargetMethodInvocationTypeArg, TargetConstructorReferenceTypeArg, TargetMethodReferenceTypeArg:
+		return fmt.Sprintf("offset=%d, type_argument_index=%d", self.Offset, self.TypeArgumentIndex)
+	}
+	return "empty"
+}
+
+//	type_path {
+//	    u1 path_length;
+//	    {   u1 type_path_kind;
+//	        u1 type_argument_index;
+//	    } path[path_length];
+//	}
+type TypePathEntry struct {
+	TypePathKind      uint8
+	TypeArgumentIndex uint8
+}
+
+// type_path_kind values
+const (
+	TypePathArray        = 0 // deeper in an array type
+	TypePathNested       = 1 // deeper in a nested type
+	TypePathWildcard     = 2 // on the bound of a wildcard type argument
+	TypePathTypeArgument = 3 // on a type argument of a parameterized type
+)
+
+// Annotations returns the annotations in both RuntimeVisibleAnnotations and
+// RuntimeInvisibleAnnotations among attributes.
+func Annotations(attributes []AttributeInfo) []Annotation {
+	annotations := []Annotation{}
+	for _, attr := range attributes {
+		if a, ok := attr.(AnnotationsAttribute); ok {
+			annotations = append(annotations, a.Annotations...)
+		}
+	}
+	return annotations
+}
+
+// HasAnnotation reports whether attributes carry an annotation of the given
+// type, in internal form, e.g. "org/junit/Test".
+func HasAnnotation(attributes []AttributeInfo, typeName string) bool {
+	for _, a := range Annotations(attributes) {
+		if a.TypeName() == typeName {
+			return true
+		}
+	}
+	return false
+}
+
+func visibility(visible bool, visibleName, invisibleName string) string {
+	if visible {
+		return visibleName
+	}
+	return invisibleName
+}
+
+func annotationList(annotations []Annotation) string {
+	s := []string{}
+	for _, a := range annotations {
+		s = append(s, a.String())
+	}
+	return "[" + strings.Join(s, ", ") + "]"
+}
+
+// javaTypeName turns a field or return descriptor into a Java type name, falling
+// back to the raw descriptor if it is malformed.
+func javaTypeName(desc string) string {
+	if desc == "V" {
+		return "void"
+	}
+	t, err := descriptor.ParseFieldDescriptor(desc)
+	if err != nil {
+		return desc
+	}
+	return t.String()
+}
+
+func (self ClassFileParser) parseAnnotationsAttribute(visible bool) (AnnotationsAttribute, error) {
+	annotations, err := self.parseAnnotations()
+	return AnnotationsAttribute{self.header, visible, annotations}, err
+}
+
+func (self ClassFileParser) parseParameterAnnotationsAttribute(visible bool) (ParameterAnnotationsAttribute, error) {
+	numParameters, err := self.reader.ReadU1()
+	if err != nil {
+		return ParameterAnnotationsAttribute{}, within(err, "num_parameters")
+	}
+	params := make([][]Annotation, numParameters)
+	for i := range params {
+		if params[i], err = self.parseAnnotations(); err != nil {
+			return ParameterAnnotationsAttribute{}, within(err, "parameter_annotations[%d]", i)
+		}
+	}
+	return ParameterAnnotationsAttribute{self.header, visible, params}, nil
+}
+
+func (self ClassFileParser) parseTypeAnnotationsAttribute(visible bool) (TypeAnnotationsAttribute, error) {
+	count, err := self.reader.ReadU2()
+	if err != nil {
+		return TypeAnnotationsAttribute{}, within(err, "num_annotations")
+	}
+	annotations := make([]TypeAnnotation, count)
+	for i := range annotations {
+		if annotations[i], err = self.parseTypeAnnotation(); err != nil {
+			return TypeAnnotationsAttribute{}, within(err, "annotations[%d]", i)
+		}
+	}
+	return TypeAnnotationsAttribute{self.header, visible, annotations}, nil
+}
+
+func (self ClassFileParser) parseAnnotationDefaultAttribute() (AnnotationDefaultAttribute, error) {
+	value, err := self.parseElementValue()
+	if err != nil {
+		return AnnotationDefaultAttribute{}, within(err, "default_value")
+	}
+	return AnnotationDefaultAttribute{self.header, value}, nil
+}
+
+func (self ClassFileParser) parseAnnotations() ([]Annotation, error) {
+	count, err := self.reader.ReadU2()
+	if err != nil {
+		return nil, within(err, "num_annotations")
+	}
+	annotations := make([]Annotation, count)
+	for i := range annotations {
+		if annotations[i], err = self.parseAnnotation(); err != nil {
+			return nil, within(err, "annotations[%d]", i)
+		}
+	}
+	return annotations, nil
+}
+
+func (self ClassFileParser) parseAnnotation() (Annotation, error) {
+	var a Annotation
+	var err error
+	if a.TypeIndex, err = self.reader.ReadU2(); err != nil {
+		return a, within(err, "type_index")
+	}
+	if a.Type, err = self.utf8(a.TypeIndex); err != nil {
+		return a, within(err, "type_index")
+	}
+	count, err := self.reader.ReadU2()
+	if err != nil {
+		return a, within(err, "num_element_value_pairs")
+	}
+	a.ElementValuePairs = make([]ElementValuePair, count)
+	for i := range a.ElementValuePairs {
+		pair := &a.ElementValuePairs[i]
+		if pair.ElementNameIndex, err = self.reader.ReadU2(); err != nil {
+			return a, within(err, "element_value_pairs[%d]", i)
+		}
+		if pair.ElementName, err = self.utf8(pair.ElementNameIndex); err != nil {
+			return a, within(err, "element_value_pairs[%d]", i)
+		}
+		if pair.Value, err = self.parseElementValue(); err != nil {
+			return a, within(err, "element_value_pairs[%d]", i)
+		}
+	}
+	return a, nil
+}
+
+func (self ClassFileParser) parseElementValue() (ElementValue, error) {
+	tag, err := self.reader.ReadU1()
+	if err != nil {
+		return nil, within(err, "tag")
+	}
+	switch tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's':
+		index, err := self.reader.ReadU2()
+		if err != nil {
+			return nil, within(err, "const_value_index")
+		}
+		value, err := self.constValue(tag, index)
+		if err != nil {
+			return nil, within(err, "const_value_index")
+		}
+		return ConstElementValue{tag, index, value}, nil
+	case 'e':
+		var v EnumElementValue
+		if v.TypeNameIndex, err = self.reader.ReadU2(); err != nil {
+			return nil, within(err, "type_name_index")
+		}
+		if v.ConstNameIndex, err = self.reader.ReadU2(); err != nil {
+			return nil, within(err, "const_name_index")
+		}
+		if v.TypeName, err = self.utf8(v.TypeNameIndex); err != nil {
+			return nil, within(err, "type_name_index")
+		}
+		if v.ConstName, err = self.utf8(v.ConstNameIndex); err != nil {
+			return nil, within(err, "const_name_index")
+		}
+		return v, nil
+	case 'c':
+		var v ClassElementValue
+		if v.ClassInfoIndex, err = self.reader.ReadU2(); err != nil {
+			return nil, within(err, "class_info_index")
+		}
+		if v.ClassInfo, err = self.utf8(v.ClassInfoIndex); err != nil {
+			return nil, within(err, "class_info_index")
+		}
+		return v, nil
+	case '@':
+		a, err := self.parseAnnotation()
+		if err != nil {
+			return nil, within(err, "annotation_value")
+		}
+		return AnnotationElementValue{a}, nil
+	case '[':
+		count, err := self.reader.ReadU2()
+		if err != nil {
+			return nil, within(err, "num_values")
+		}
+		values := make([]ElementValue, count)
+		for i := range values {
+			if values[i], err = self.parseElementValue(); err != nil {
+				return nil, within(err, "values[%d]", i)
+			}
+		}
+		return ArrayElementValue{values}, nil
+	}
+	return nil, &ClassFormatError{Offset: self.reader.Offset() - 1, Msg: fmt.Sprintf("unknown element_value tag %q", tag)}
+}
+
+// constValue resolves the constant an element_value of the given tag refers to.
+func (self ClassFileParser) constValue(tag byte, index uint16) (any, error) {
+	var entry ConstantInfo
+	if int(index) < len(self.cp) {
+		entry = self.cp[index]
+	}
+	switch c := entry.(type) {
+	case *ConstantIntegerInfo:
+		if strings.IndexByte("BCISZ", tag) >= 0 {
+			return c.Value(), nil
+		}
+	case *ConstantLongInfo:
+		if tag == 'J' {
+			return c.Value(), nil
+		}
+	case *ConstantFloatInfo:
+		if tag == 'F' {
+			return c.Value(), nil
+		}
+	case *ConstantDoubleInfo:
+		if tag == 'D' {
+			return c.Value(), nil
+		}
+	case *ConstantUtf8Info:
+		if tag == 's' {
+			return c.Value(), nil
+		}
+	}
+	return nil, self.errorf("constant pool entry #%d does not fit element_value tag %q", index, tag)
+}
+
+func (self ClassFileParser) parseTypeAnnotation() (TypeAnnotation, error) {
+	var ta TypeAnnotation
+	var err error
+	if ta.TargetType, err = self.reader.ReadU1(); err != nil {
+		return ta, within(err, "target_type")
+	}
+	if ta.TargetInfo, err = self.parseTargetInfo(ta.TargetType); err != nil {
+		return ta, within(err, "target_info")
+	}
+	pathLength, err := self.reader.ReadU1()
+	if err != nil {
+		return ta, within(err, "target_path")
+	}
+	ta.TargetPath = make([]TypePathEntry, pathLength)
+	for i := range ta.TargetPath {
+		entry := &ta.TargetPath[i]
+		for _, field := range []*uint8{&entry.TypePathKind, &entry.TypeArgumentIndex} {
+			if *field, err = self.reader.ReadU1(); err != nil {
+				return ta, within(err, "target_path.path[%d]", i)
+			}
+		}
+	}
+	if ta.Annotation, err = self.parseAnnotation(); err != nil {
+		return ta, err
+	}
+	return ta, nil
+}
+
+func (self ClassFileParser) parseTargetInfo(targetType uint8) (TargetInfo, error) {
+	var info TargetInfo
+	var err error
+	r := self.reader
+	switch targetType {
+	case TargetClassTypeParameter, TargetMethodTypeParameter:
+		info.TypeParameterIndex, err = r.ReadU1()
+	case TargetClassExtends:
+		info.SupertypeIndex, err = r.ReadU2()
+	case TargetClassTypeParameterBound, TargetMethodTypeParameterBound:
+		if info.TypeParameterIndex, err = r.ReadU1(); err == nil {
+			info.BoundIndex, err = r.ReadU1()
+		}
+	case TargetField, TargetMethodReturn, TargetMethodReceiver:
+	case TargetMethodFormalParameter:
+		info.FormalParameterIndex, err = r.ReadU1()
+	case TargetThrows:
+		info.ThrowsTypeIndex, err = r.ReadU2()
+	case TargetLocalVariable, TargetResourceVariable:
+		var length uint16
+		if length, err = r.ReadU2(); err != nil {
+			break
+		}
+		info.LocalVarTable = make([]LocalVarTargetEntry, length)
+		for i := range info.LocalVarTable {
+			entry := &info.LocalVarTable[i]
+			for _, field := range []*uint16{&entry.StartPc, &entry.Length, &entry.Index} {
+				if *field, err = r.ReadU2(); err != nil {
+					return info, within(err, "table[%d]", i)
+				}
+			}
+		}
+	case TargetExceptionParameter:
+		info.ExceptionTableIndex, err = r.ReadU2()
+	case TargetInstanceOf, TargetNew, TargetConstructorReference, TargetMethodReference:
+		info.Offset, err = r.ReadU2()
+	case TargetCast, TargetConstructorInvocationTypeArg, TargetMethodInvocationTypeArg, TargetConstructorReferenceTypeArg, TargetMethodReferenceTypeArg:
+		if info.Offset, err = r.ReadU2(); err == nil {
+			info.TypeArgumentIndex, err = r.ReadU1()
+		}
+	default:
+		return info, &ClassFormatError{Offset: r.Offset() - 1, Msg: fmt.Sprintf("unknown target_type %#x", targetType)}
+	}
+	return info, err
+}
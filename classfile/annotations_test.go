@@ -0,0 +1,80 @@
+package classfile
+
+import (
+	"bytes"
+	"testing"
+)
+
+func utf8Info(s string) *ConstantUtf8Info {
+	return &ConstantUtf8Info{uint16(len(s)), []byte(s)}
+}
+
+func TestParseAnnotations(t *testing.T) {
+	cp := ConstantPool{
+		nil,
+		utf8Info("Lcom/x/Entity;"), // #1
+		utf8Info("name"),           // #2
+		utf8Info("users"),          // #3
+		utf8Info("retention"),      // #4
+		utf8Info("Ljava/lang/annotation/RetentionPolicy;"), // #5
+		utf8Info("RUNTIME"),                      // #6
+		utf8Info("types"),                        // #7
+		utf8Info("Ljava/lang/String;"),           // #8
+		utf8Info("V"),                            // #9
+		&ConstantIntegerInfo{[]byte{0, 0, 0, 1}}, // #10
+		utf8Info("Lcom/x/Index;"),                // #11
+		utf8Info("unique"),                       // #12
+	}
+	b := []byte{
+		0, 1, // num_annotations
+		0, 1, 0, 4, // @Entity, 4 pairs
+		0, 2, 's', 0, 3, // name="users"
+		0, 4, 'e', 0, 5, 0, 6, // retention=RetentionPolicy.RUNTIME
+		0, 7, '[', 0, 2, 'c', 0, 8, 'c', 0, 9, // types={String.class, void.class}
+		0, 12, '@', 0, 11, 0, 1, 0, 12, 'Z', 0, 10, // unique=@Index(unique=true)
+	}
+	parser := ClassFileParser{reader: newClassReader(bytes.NewReader(b)), cp: cp}
+	attr, err := parser.parseAnnotationsAttribute(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `RuntimeVisibleAnnotations: [@com.x.Entity(name="users", retention=java.lang.annotation.RetentionPolicy.RUNTIME, types={java.lang.String.class, void.class}, unique=@com.x.Index(unique=true))]`
+	if got := attr.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if !HasAnnotation([]AttributeInfo{attr}, "com/x/Entity") {
+		t.Errorf("HasAnnotation(com/x/Entity) = false")
+	}
+	if v, ok := attr.Annotations[0].Value("name"); !ok || v.(ConstElementValue).Value != "users" {
+		t.Errorf("Value(name): got %v", v)
+	}
+
+	// 'I' pointing at a Utf8 entry
+	parser = ClassFileParser{reader: newClassReader(bytes.NewReader([]byte{0, 1, 0, 1, 0, 1, 0, 2, 'I', 0, 3})), cp: cp}
+	if _, err := parser.parseAnnotationsAttribute(true); err == nil {
+		t.Errorf("expected a mismatched const_value_index to be rejected")
+	}
+}
+
+func TestParseTypeAnnotations(t *testing.T) {
+	cp := ConstantPool{nil, utf8Info("Lcom/x/NonNull;")}
+	b := []byte{
+		0, 2, // num_annotations
+		TargetLocalVariable, 0, 1, 0, 2, 0, 5, 0, 1, // localvar_target {2, 5, 1}
+		1, TypePathTypeArgument, 0, // type_path
+		0, 1, 0, 0, // @NonNull
+		TargetCast, 0, 9, 1, 0, 0, 1, 0, 0,
+	}
+	parser := ClassFileParser{reader: newClassReader(bytes.NewReader(b)), cp: cp}
+	attr, err := parser.parseTypeAnnotationsAttribute(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := attr.Annotations[0]
+	if len(a.TargetInfo.LocalVarTable) != 1 || a.TargetInfo.LocalVarTable[0].Length != 5 || len(a.TargetPath) != 1 || a.TypeName() != "com/x/NonNull" {
+		t.Errorf("annotations[0]: got %+v", a)
+	}
+	if a := attr.Annotations[1]; a.TargetInfo.Offset != 9 || a.TargetInfo.TypeArgumentIndex != 1 {
+		t.Errorf("annotations[1]: got %+v", a)
+	}
+}
@@ -1,15 +1,32 @@
 package classfile
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
+
+	"gjvm/signature"
 )
 
 type AttributeInfo interface {
 	String() string
 }
 
+// AttributeHeader is embedded in every attribute type. It keeps the
+// attribute_name_index the attribute was read with, so that a class whose
+// pool holds the name twice is written back with the same bytes. It is zero
+// for attributes built in code, which are written under the first
+// CONSTANT_Utf8 entry holding their name.
+type AttributeHeader struct {
+	AttributeNameIndex uint16 `json:"-"`
+}
+
+func (self AttributeHeader) header() AttributeHeader {
+	return self
+}
+
 type NotImplementedAttributeInfo struct {
+	AttributeHeader
 	AttributeName   string
 	AttributeLength uint32
 	Info            []byte
@@ -31,107 +48,238 @@ func (self NotImplementedAttributeInfo) String() string {
 // - PermittedSubclasses
 
 const (
-	ConstantValue string = "ConstantValue"
-	Code                 = "Code"
-	StackMapTable        = "StackMapTable"
-	// Exceptions                                 = "Exceptions"
-	// InnerClasses                               = "InnerClasses"
-	// EnclosingMethod                            = "EnclosingMethod"
-	// Synthetic                                  = "Synthetic"
-	// Signature                                  = "Signature"
-	// SourceFile                                 = "SourceFile"
-	// SourceDebugExtension                       = "SourceDebugExtension"
-	// LineNumberTable = "LineNumberTable"
-	// LocalVariableTable                         = "LocalVariableTable"
-	// LocalVariableTypeTable                     = "LocalVariableTypeTable"
-	// Deprecated                                 = "Deprecated"
-	// RuntimeVisibleAnnotation                   = "RuntimeVisibleAnnotation"
-	// RuntimeInvisibleAnnotation                 = "RuntimeInvisibleAnnotation"
-	// RuntimeVisibleParameterAnnotation          = "RuntimeVisibleParameterAnnotation"
-	// RuntimeInvisibleParameterAnnotation        = "RuntimeInvisibleParameterAnnotation"
-	// AnnotationDefault                          = "AnnotationDefault"
-	BootstrapMethods = "BootstrapMethods"
-	// MethodParameters                           = "MethodParameters"
-	// Module                                     = "Module"
-	// ModulePackages                             = "ModulePackages"
-	// ModuleMainClass                            = "ModuleMainClass"
-	NestHost    = "NestHost"
-	NestMembers = "NestMembers"
-	//	Record                                     = "Record"
-	PermittedSubclasses = "PermittedSubclasses"
+	ConstantValue                        string = "ConstantValue"
+	Code                                        = "Code"
+	StackMapTable                               = "StackMapTable"
+	Exceptions                                  = "Exceptions"
+	InnerClasses                                = "InnerClasses"
+	EnclosingMethod                             = "EnclosingMethod"
+	Synthetic                                   = "Synthetic"
+	Signature                                   = "Signature"
+	SourceFile                                  = "SourceFile"
+	SourceDebugExtension                        = "SourceDebugExtension"
+	LineNumberTable                             = "LineNumberTable"
+	LocalVariableTable                          = "LocalVariableTable"
+	LocalVariableTypeTable                      = "LocalVariableTypeTable"
+	Deprecated                                  = "Deprecated"
+	RuntimeVisibleAnnotations                   = "RuntimeVisibleAnnotations"
+	RuntimeInvisibleAnnotations                 = "RuntimeInvisibleAnnotations"
+	RuntimeVisibleParameterAnnotations          = "RuntimeVisibleParameterAnnotations"
+	RuntimeInvisibleParameterAnnotations        = "RuntimeInvisibleParameterAnnotations"
+	RuntimeVisibleTypeAnnotations               = "RuntimeVisibleTypeAnnotations"
+	RuntimeInvisibleTypeAnnotations             = "RuntimeInvisibleTypeAnnotations"
+	AnnotationDefault                           = "AnnotationDefault"
+	BootstrapMethods                            = "BootstrapMethods"
+	MethodParameters                            = "MethodParameters"
+	Module                                      = "Module"
+	ModulePackages                              = "ModulePackages"
+	ModuleMainClass                             = "ModuleMainClass"
+	NestHost                                    = "NestHost"
+	NestMembers                                 = "NestMembers"
+	Record                                      = "Record"
+	PermittedSubclasses                         = "PermittedSubclasses"
 )
 
-func (self ClassFileParser) parseAttributeInfo(size uint16) ([]AttributeInfo, error) {
+// FindAttribute returns the first attribute of type T, e.g.
+//
+//	code, ok := FindAttribute[CodeAttribute](method.Attributes)
+func FindAttribute[T AttributeInfo](attributes []AttributeInfo) (T, bool) {
+	for _, attr := range attributes {
+		if t, ok := attr.(T); ok {
+			return t, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// AttributeName returns the name attr is stored under in a class file, e.g.
+// "Code". Attributes left undecoded or decoded by an AttributeDecoder keep the
+// name they were read with.
+func AttributeName(attr AttributeInfo) string {
+	switch attr := attr.(type) {
+	case ConstantValueAttribute:
+		return ConstantValue
+	case CodeAttribute:
+		return Code
+	case StackMapTableAttribute:
+		return StackMapTable
+	case ExceptionsAttribute:
+		return Exceptions
+	case InnerClassesAttribute:
+		return InnerClasses
+	case EnclosingMethodAttribute:
+		return EnclosingMethod
+	case SyntheticAttribute:
+		return Synthetic
+	case SignatureAttribute:
+		return Signature
+	case SourceFileAttribute:
+		return SourceFile
+	case SourceDebugExtensionAttribute:
+		return SourceDebugExtension
+	case LineNumberTableAttribute:
+		return LineNumberTable
+	case LocalVariableTableAttribute:
+		return LocalVariableTable
+	case LocalVariableTypeTableAttribute:
+		return LocalVariableTypeTable
+	case DeprecatedAttribute:
+		return Deprecated
+	case AnnotationsAttribute:
+		if attr.Visible {
+			return RuntimeVisibleAnnotations
+		}
+		return RuntimeInvisibleAnnotations
+	case ParameterAnnotationsAttribute:
+		if attr.Visible {
+			return RuntimeVisibleParameterAnnotations
+		}
+		return RuntimeInvisibleParameterAnnotations
+	case TypeAnnotationsAttribute:
+		if attr.Visible {
+			return RuntimeVisibleTypeAnnotations
+		}
+		return RuntimeInvisibleTypeAnnotations
+	case AnnotationDefaultAttribute:
+		return AnnotationDefault
+	case BootstrapMethodsAttribute:
+		return BootstrapMethods
+	case MethodParametersAttribute:
+		return MethodParameters
+	case ModuleAttribute:
+		return Module
+	case ModulePackagesAttribute:
+		return ModulePackages
+	case ModuleMainClassAttribute:
+		return ModuleMainClass
+	case NestHostAttribute:
+		return NestHost
+	case NestMembersAttribute:
+		return NestMembers
+	case RecordAttribute:
+		return Record
+	case PermittedSubclassesAttribute:
+		return PermittedSubclasses
+	case interface{ Raw() RawAttribute }:
+		return attr.Raw().AttributeName
+	}
+	return ""
+}
+
+func (self ClassFileParser) parseAttributeInfo(size uint16, context AttributeContext) ([]AttributeInfo, error) {
 	attributes := make([]AttributeInfo, size)
 	for i := 0; i < int(size); i++ {
-		nameIndex := self.reader.ReadU2()
-		attrName := self.cp[nameIndex].(*ConstantUtf8Info).Value()
-		attrLen := self.reader.ReadU4()
-
-		switch attrName {
-		case ConstantValue:
-			attributes[i] = self.parseConstantValueAttribute()
-		case Code:
-			attributes[i] = self.parseCodeAttribute()
-		// case StackMapTable:
-		// 	attributes[i] = self.parseStackMapTableAttribute(nameIndex, attrLen)
-		// case Exceptions:
-		// 	attributes[i] = self.parseExceptionsAttribute(nameIndex, attrLen)
-		// case InnerClasses:
-		// 	attributes[i] = self.parseInnerClassesAttribute(nameIndex, attrLen)
-		// case EnclosingMethod:
-		// 	attributes[i] = self.parseEnclosingMethodAttribute(nameIndex, attrLen)
-		// case Synthetic:
-		// 	attributes[i] = self.parseSyntheticAttribute(nameIndex, attrLen)
-		// case Signature:
-		// 	attributes[i] = self.parseSignatureAttribute(nameIndex, attrLen)
-		// case SourceFile:
-		// 	attributes[i] = self.parseSourceFileAttribute(nameIndex, attrLen)
-		// case SourceDebugExtension:
-		// 	attributes[i] = self.parseSourceDebugExtensionAttribute(nameIndex, attrLen)
-		// case LineNumberTable:
-		//	attributes[i] = self.parseLineNumberTableAttribute(nameIndex, attrLen)
-		// case LocalVariableTable:
-		// 	attributes[i] = self.parseLocalVariableTableAttribute(nameIndex, attrLen)
-		// case LocalVariableTypeTable:
-		// 	attributes[i] = self.parseLocalVariableTypeTableAttribute(nameIndex, attrLen)
-		// case Deprecated:
-		// 	attributes[i] = self.parseDeprecatedAttribute(nameIndex, attrLen)
-		// case RuntimeVisibleAnnotation:
-		// 	attributes[i] = self.parseRuntimeVisibleAnnotationAttribute(nameIndex, attrLen)
-		// case RuntimeInvisibleAnnotation:
-		// 	attributes[i] = self.parseRuntimeInvisibleAnnotationAttribute(nameIndex, attrLen)
-		// case RuntimeVisibleParameterAnnotation:
-		// 	attributes[i] = self.parseRuntimeVisibleParameterAnnotationAttribute(nameIndex, attrLen)
-		// case RuntimeInvisibleParameterAnnotation:
-		// 	attributes[i] = self.parseRuntimeInvisibleParameterAnnotationAttribute(nameIndex, attrLen)
-		// case AnnotationDefault:
-		// 	attributes[i] = self.parseAnnotationDefaultAttribute(nameIndex, attrLen)
-		case BootstrapMethods:
-			attributes[i] = self.parseBootstrapMethodsAttribute(nameIndex, attrLen)
-		// case MethodParameters:
-		// 	attributes[i] = self.parseMethodParametersAttribute(nameIndex, attrLen)
-		// case Module:
-		// 	attributes[i] = self.parseModuleAttribute(nameIndex, attrLen)
-		// case ModulePackages:
-		// 	attributes[i] = self.parseModulePackagesAttribute(nameIndex, attrLen)
-		// case ModuleMainClass:
-		// 	attributes[i] = self.parseModuleMainClassAttribute(nameIndex, attrLen)
-		// case NestHost:
-		// 	attributes[i] = self.parseNestHostAttribute(nameIndex, attrLen)
-		// case NestMembers:
-		// 	attributes[i] = self.parseNestMembersAttribute(nameIndex, attrLen)
-		// case Record:
-		// 	attributes[i] = self.parseRecordAttribute(nameIndex, attrLen)
-		// case PermittedSubclasses:
-		// 	attributes[i] = self.parsePermittedSubclassesAttribute(nameIndex, attrLen)
-		default:
-			attributes[i] = NotImplementedAttributeInfo{attrName, attrLen, self.reader.Read(attrLen)}
+		attr, err := self.parseAttribute(context)
+		if err != nil {
+			return nil, within(err, "attributes[%d]", i)
 		}
+		attributes[i] = attr
 	}
 	return attributes, nil
 }
 
+// parseAttribute reads one attribute_info. The body is read in full first and
+// decoded from its own reader, so a decoder can neither run past attribute_length
+// nor leave part of it unread without that being reported. Attributes the
+// parser does not know go to the decoder registered for them in context, if any.
+func (self ClassFileParser) parseAttribute(context AttributeContext) (AttributeInfo, error) {
+	nameIndex, err := self.reader.ReadU2()
+	if err != nil {
+		return nil, within(err, "attribute_name_index")
+	}
+	attrName, err := self.utf8(nameIndex)
+	if err != nil {
+		return nil, within(err, "attribute_name_index")
+	}
+	attrLen, err := self.reader.ReadU4()
+	if err != nil {
+		return nil, within(err, "attribute_length")
+	}
+	start := self.reader.Offset()
+	info, err := self.reader.Read(attrLen)
+	if err != nil {
+		return nil, within(err, "%s", attrName)
+	}
+	body := self
+	body.reader = &ClassReader{reader: bytes.NewReader(info), offset: start}
+	body.header = AttributeHeader{nameIndex}
+
+	var attr AttributeInfo
+	switch attrName {
+	case ConstantValue:
+		attr, err = body.parseConstantValueAttribute()
+	case Code:
+		attr, err = body.parseCodeAttribute()
+	case StackMapTable:
+		attr, err = body.parseStackMapTableAttribute()
+	case Exceptions:
+		attr, err = body.parseExceptionsAttribute()
+	case InnerClasses:
+		attr, err = body.parseInnerClassesAttribute()
+	case EnclosingMethod:
+		attr, err = body.parseEnclosingMethodAttribute()
+	case Synthetic:
+		attr = SyntheticAttribute{body.header}
+	case Signature:
+		attr, err = body.parseSignatureAttribute()
+	case SourceFile:
+		attr, err = body.parseSourceFileAttribute()
+	case SourceDebugExtension:
+		attr, err = body.parseSourceDebugExtensionAttribute(attrLen)
+	case LineNumberTable:
+		attr, err = body.parseLineNumberTableAttribute()
+	case LocalVariableTable:
+		attr, err = body.parseLocalVariableTableAttribute()
+	case LocalVariableTypeTable:
+		attr, err = body.parseLocalVariableTypeTableAttribute()
+	case Deprecated:
+		attr = DeprecatedAttribute{body.header}
+	case RuntimeVisibleAnnotations, RuntimeInvisibleAnnotations:
+		attr, err = body.parseAnnotationsAttribute(attrName == RuntimeVisibleAnnotations)
+	case RuntimeVisibleParameterAnnotations, RuntimeInvisibleParameterAnnotations:
+		attr, err = body.parseParameterAnnotationsAttribute(attrName == RuntimeVisibleParameterAnnotations)
+	case RuntimeVisibleTypeAnnotations, RuntimeInvisibleTypeAnnotations:
+		attr, err = body.parseTypeAnnotationsAttribute(attrName == RuntimeVisibleTypeAnnotations)
+	case AnnotationDefault:
+		attr, err = body.parseAnnotationDefaultAttribute()
+	case BootstrapMethods:
+		attr, err = body.parseBootstrapMethodsAttribute(nameIndex, attrLen)
+	case MethodParameters:
+		attr, err = body.parseMethodParametersAttribute()
+	case Module:
+		attr, err = body.parseModuleAttribute()
+	case ModulePackages:
+		attr, err = body.parseModulePackagesAttribute()
+	case ModuleMainClass:
+		attr, err = body.parseModuleMainClassAttribute()
+	case NestHost:
+		attr, err = body.parseNestHostAttribute()
+	case NestMembers:
+		attr, err = body.parseNestMembersAttribute()
+	case Record:
+		attr, err = body.parseRecordAttribute()
+	case PermittedSubclasses:
+		attr, err = body.parsePermittedSubclassesAttribute()
+	default:
+		attr, ok, err := self.decodeCustomAttribute(RawAttribute{body.header, attrName, info}, context, start)
+		if err != nil {
+			return nil, within(err, "%s", attrName)
+		}
+		if !ok {
+			attr = NotImplementedAttributeInfo{body.header, attrName, attrLen, info}
+		}
+		return attr, nil
+	}
+	if err != nil {
+		return nil, within(err, "%s", attrName)
+	}
+	if rest := int64(attrLen) - (body.reader.Offset() - start); rest != 0 {
+		return nil, &ClassFormatError{Offset: body.reader.Offset(), Msg: fmt.Sprintf("%d unread bytes at end of attribute", rest), Context: []string{attrName}}
+	}
+	return attr, nil
+}
+
 // https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.2
 //
 //	ConstantValue_attribute {
@@ -140,6 +288,7 @@ func (self ClassFileParser) parseAttributeInfo(size uint16) ([]AttributeInfo, er
 //	    u2 constantvalue_index;
 //	}
 type ConstantValueAttribute struct {
+	AttributeHeader
 	ConstantValueIndex uint16
 }
 
@@ -147,8 +296,9 @@ func (self ConstantValueAttribute) String() string {
 	return fmt.Sprintf("ConstantValue: #%d", self.ConstantValueIndex)
 }
 
-func (self ClassFileParser) parseConstantValueAttribute() ConstantValueAttribute {
-	return ConstantValueAttribute{self.reader.ReadU2()}
+func (self ClassFileParser) parseConstantValueAttribute() (ConstantValueAttribute, error) {
+	index, err := self.reader.ReadU2()
+	return ConstantValueAttribute{self.header, index}, err
 }
 
 // https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.3
@@ -170,6 +320,7 @@ func (self ClassFileParser) parseConstantValueAttribute() ConstantValueAttribute
 //	    attribute_info attributes[attributes_count];
 //	}
 type CodeAttribute struct {
+	AttributeHeader
 	MaxStack             uint16
 	MaxLocals            uint16
 	CodeLength           uint32
@@ -203,49 +354,86 @@ type ExceptionTableEntry struct {
 	CatchType uint16
 }
 
-func (self ClassFileParser) parseCodeAttribute() CodeAttribute {
-	maxStack := self.reader.ReadU2()
-	maxLocals := self.reader.ReadU2()
-	codeLength := self.reader.ReadU4()
-	code := self.reader.Read(codeLength)
-	exceptionTableLength := self.reader.ReadU2()
-	exceptionTable := make([]ExceptionTableEntry, exceptionTableLength)
-	for i := range exceptionTable {
-		exceptionTable[i] = ExceptionTableEntry{
-			StartPc:   self.reader.ReadU2(),
-			EndPc:     self.reader.ReadU2(),
-			HandlerPc: self.reader.ReadU2(),
-			CatchType: self.reader.ReadU2(),
+func (self ClassFileParser) parseCodeAttribute() (CodeAttribute, error) {
+	attr := CodeAttribute{AttributeHeader: self.header}
+	var err error
+	if attr.MaxStack, err = self.reader.ReadU2(); err != nil {
+		return attr, within(err, "max_stack")
+	}
+	if attr.MaxLocals, err = self.reader.ReadU2(); err != nil {
+		return attr, within(err, "max_locals")
+	}
+	if attr.CodeLength, err = self.reader.ReadU4(); err != nil {
+		return attr, within(err, "code_length")
+	}
+	if attr.Code, err = self.reader.Read(attr.CodeLength); err != nil {
+		return attr, within(err, "code")
+	}
+	if attr.ExceptionTableLength, err = self.reader.ReadU2(); err != nil {
+		return attr, within(err, "exception_table_length")
+	}
+	attr.ExceptionTable = make([]ExceptionTableEntry, attr.ExceptionTableLength)
+	for i := range attr.ExceptionTable {
+		entry := &attr.ExceptionTable[i]
+		for _, field := range []*uint16{&entry.StartPc, &entry.EndPc, &entry.HandlerPc, &entry.CatchType} {
+			if *field, err = self.reader.ReadU2(); err != nil {
+				return attr, within(err, "exception_table[%d]", i)
+			}
 		}
 	}
-	attributesCount := self.reader.ReadU2()
-	attributes, _ := self.parseAttributeInfo(attributesCount)
-	return CodeAttribute{
-		MaxStack:             maxStack,
-		MaxLocals:            maxLocals,
-		CodeLength:           codeLength,
-		Code:                 code,
-		ExceptionTableLength: exceptionTableLength,
-		ExceptionTable:       exceptionTable,
-		AttributeCount:       attributesCount,
-		Attributes:           attributes,
+	if attr.AttributeCount, err = self.reader.ReadU2(); err != nil {
+		return attr, within(err, "attributes_count")
+	}
+	if attr.Attributes, err = self.parseAttributeInfo(attr.AttributeCount, CodeContext); err != nil {
+		return attr, err
 	}
+	return attr, nil
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.9
+//
+//	Signature_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 signature_index;
+//	}
+//
+// Whether the signature is a class, method or field signature depends on where
+// the attribute appears, so it is kept as a string and parsed on demand. The
+// JVM itself does not check it at load time, so neither does the parser.
+type SignatureAttribute struct {
+	AttributeHeader
+	SignatureIndex uint16
+	Signature      string
 }
 
-// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.4
-// StackMapTable_attribute {
-//     u2              attribute_name_index;
-//     u4              attribute_length;
-//     u2              number_of_entries;
-//     stack_map_frame entries[number_of_entries];
-// }
-// type StackMapTableAttribute struct {
-// 	NumberOfEntries uint16
-// 	Entries         []StackMapFrame
-// }
-// type StackMapFrame interface {
-// 	FrameType() uint8
-// }
+func (self SignatureAttribute) String() string {
+	return fmt.Sprintf("Signature: #%d // %s", self.SignatureIndex, self.Signature)
+}
+
+func (self SignatureAttribute) ClassSignature() (*signature.ClassSignature, error) {
+	return signature.ParseClassSignature(self.Signature)
+}
+
+func (self SignatureAttribute) MethodSignature() (*signature.MethodSignature, error) {
+	return signature.ParseMethodSignature(self.Signature)
+}
+
+func (self SignatureAttribute) FieldSignature() (signature.FieldSignature, error) {
+	return signature.ParseFieldSignature(self.Signature)
+}
+
+func (self ClassFileParser) parseSignatureAttribute() (SignatureAttribute, error) {
+	index, err := self.reader.ReadU2()
+	if err != nil {
+		return SignatureAttribute{}, within(err, "signature_index")
+	}
+	sig, err := self.utf8(index)
+	if err != nil {
+		return SignatureAttribute{}, within(err, "signature_index")
+	}
+	return SignatureAttribute{self.header, index, sig}, nil
+}
 
 // https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.23
 //
@@ -259,6 +447,7 @@ func (self ClassFileParser) parseCodeAttribute() CodeAttribute {
 //	  } bootstrap_methods[num_bootstrap_methods];
 //	}
 type BootstrapMethodsAttribute struct {
+	AttributeHeader
 	BootstrapMethods []BootstrapMethod
 }
 
@@ -283,20 +472,31 @@ func (self BootstrapMethod) String() string {
 	return fmt.Sprintf("BootStrapMethod: #%d, args=%s", self.BootstrapMethodRef, strings.Join(args, ", "))
 }
 
-func (self ClassFileParser) parseBootstrapMethodsAttribute(nameIndex uint16, attrLen uint32) BootstrapMethodsAttribute {
-	methodNum := self.reader.ReadU2()
+func (self ClassFileParser) parseBootstrapMethodsAttribute(nameIndex uint16, attrLen uint32) (BootstrapMethodsAttribute, error) {
+	methodNum, err := self.reader.ReadU2()
+	if err != nil {
+		return BootstrapMethodsAttribute{}, within(err, "num_bootstrap_methods")
+	}
 	methods := make([]BootstrapMethod, methodNum)
 	for i := range methods {
-		methodRef := self.reader.ReadU2()
-		argNum := self.reader.ReadU2()
+		methodRef, err := self.reader.ReadU2()
+		if err != nil {
+			return BootstrapMethodsAttribute{}, within(err, "bootstrap_methods[%d]", i)
+		}
+		argNum, err := self.reader.ReadU2()
+		if err != nil {
+			return BootstrapMethodsAttribute{}, within(err, "bootstrap_methods[%d]", i)
+		}
 		args := make([]uint16, argNum)
 		for j := range args {
-			args[j] = self.reader.ReadU2()
+			if args[j], err = self.reader.ReadU2(); err != nil {
+				return BootstrapMethodsAttribute{}, within(err, "bootstrap_methods[%d].bootstrap_arguments[%d]", i, j)
+			}
 		}
 		methods[i] = BootstrapMethod{
 			BootstrapMethodRef: methodRef,
 			BootstrapArguments: args,
 		}
 	}
-	return BootstrapMethodsAttribute{methods}
+	return BootstrapMethodsAttribute{self.header, methods}, nil
 }
@@ -34,5 +34,30 @@ type ClassFile struct {
 	Fields            []FieldInfo
 	MethodCount       uint16
 	Methods           []MethodInfo
-	//	AttributeTable
+	AttributesCount   uint16
+	Attributes        []AttributeInfo
+}
+
+// BootstrapMethods returns the class's BootstrapMethods attribute, which
+// CONSTANT_Dynamic and CONSTANT_InvokeDynamic entries index into.
+func (self ClassFile) BootstrapMethods() (BootstrapMethodsAttribute, bool) {
+	return FindAttribute[BootstrapMethodsAttribute](self.Attributes)
+}
+
+// Signature returns the class's generic signature, if it has one.
+func (self ClassFile) Signature() (SignatureAttribute, bool) {
+	return FindAttribute[SignatureAttribute](self.Attributes)
+}
+
+// Name returns the name of the class in internal form, e.g. "com/x/Foo", or
+// "" if this_class is not a CONSTANT_Class naming a CONSTANT_Utf8.
+func (self ClassFile) Name() string {
+	if self.ConstantPool.IsUsable(self.ThisClass) {
+		if class, ok := self.ConstantPool[self.ThisClass].(*ConstantClassInfo); ok && self.ConstantPool.IsUsable(class.NameIndex) {
+			if name, ok := self.ConstantPool[class.NameIndex].(*ConstantUtf8Info); ok {
+				return name.Value()
+			}
+		}
+	}
+	return ""
 }
@@ -0,0 +1,43 @@
+package classfile
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ClassFormatError reports a truncated or malformed class file, in the spirit of
+// java.lang.ClassFormatError.
+//
+//	java.lang.ClassFormatError: unexpected EOF at offset 0x1a3 while reading method_info[2].attributes[0]
+type ClassFormatError struct {
+	// Offset is the position in the class file at which the problem was detected.
+	Offset int64
+	// Context is the path of structures being read, outermost first.
+	Context []string
+	Msg     string
+	// Err is the underlying cause, e.g. io.ErrUnexpectedEOF. It may be nil.
+	Err error
+}
+
+func (self *ClassFormatError) Error() string {
+	s := fmt.Sprintf("java.lang.ClassFormatError: %s at offset %#x", self.Msg, self.Offset)
+	if len(self.Context) > 0 {
+		s += " while reading " + strings.Join(self.Context, ".")
+	}
+	return s
+}
+
+func (self *ClassFormatError) Unwrap() error {
+	return self.Err
+}
+
+// within records that err happened while reading the structure described by
+// format. Errors bubble up from the innermost structure, so each call prepends.
+func within(err error, format string, a ...any) error {
+	var cfe *ClassFormatError
+	if errors.As(err, &cfe) {
+		cfe.Context = append([]string{fmt.Sprintf(format, a...)}, cfe.Context...)
+	}
+	return err
+}
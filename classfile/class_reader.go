@@ -2,45 +2,97 @@ package classfile
 
 import (
 	"encoding/binary"
+	"errors"
 	"io"
 )
 
 /// A Reader which has some convenience methods for reading Java class files
+///
+/// Every read reports a ClassFormatError carrying the offset it started at when
+/// the underlying reader runs dry, so a truncated file never decodes as zeros.
 type ClassReader struct {
 	reader io.Reader
+	offset int64
 }
 
 func newClassReader(r io.Reader) *ClassReader {
-	return &ClassReader{r}
+	return &ClassReader{reader: r}
 }
 
-func (cr *ClassReader) Read(size uint32) []byte {
-	data := make([]byte, size)
-	cr.reader.Read(data)
-	return data
+// Offset returns the number of bytes consumed from the start of the class file.
+func (cr *ClassReader) Offset() int64 {
+	return cr.offset
 }
 
-func (cr *ClassReader) ReadU1() uint8 {
-	data := make([]byte, 1)
-	cr.reader.Read(data)
-	return data[0]
+// Read reads exactly size bytes.
+func (cr *ClassReader) Read(size uint32) ([]byte, error) {
+	start := cr.offset
+	var data []byte
+	var err error
+	if size <= 1<<16 {
+		data = make([]byte, size)
+		var n int
+		n, err = io.ReadFull(cr.reader, data)
+		cr.offset += int64(n)
+	} else {
+		// A corrupt length field must not make us allocate gigabytes up front,
+		// so large reads grow with the data that is actually there.
+		data, err = io.ReadAll(io.LimitReader(cr.reader, int64(size)))
+		cr.offset += int64(len(data))
+		if err == nil && len(data) < int(size) {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, &ClassFormatError{Offset: start, Msg: err.Error(), Err: err}
+	}
+	return data, nil
+}
+
+func (cr *ClassReader) ReadU1() (uint8, error) {
+	data, err := cr.Read(1)
+	if err != nil {
+		return 0, err
+	}
+	return data[0], nil
 }
 
-func (cr *ClassReader) ReadU2() uint16 {
-	data := make([]byte, 2)
-	cr.reader.Read(data)
-	return binary.BigEndian.Uint16(data)
+func (cr *ClassReader) ReadU2() (uint16, error) {
+	data, err := cr.Read(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(data), nil
 }
 
-func (cr *ClassReader) ReadU4() uint32 {
-	data := make([]byte, 4)
-	cr.reader.Read(data)
-	return binary.BigEndian.Uint32(data)
+func (cr *ClassReader) ReadU4() (uint32, error) {
+	data, err := cr.Read(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(data), nil
 }
 
-func (cr *ClassReader) Read4() int32 {
-	data := make([]byte, 4)
-	cr.reader.Read(data)
-	return int32(binary.BigEndian.Uint32(data))
+func (cr *ClassReader) Read4() (int32, error) {
+	v, err := cr.ReadU4()
+	return int32(v), err
 }
 
+// AtEOF reports whether the input is exhausted. If it is not, one byte is consumed.
+// A read failure other than io.EOF is a ClassFormatError.
+func (cr *ClassReader) AtEOF() (bool, error) {
+	start := cr.offset
+	data := make([]byte, 1)
+	n, err := io.ReadFull(cr.reader, data)
+	cr.offset += int64(n)
+	if errors.Is(err, io.EOF) {
+		return true, nil
+	}
+	if err != nil {
+		return false, &ClassFormatError{Offset: start, Msg: err.Error(), Err: err}
+	}
+	return false, nil
+}
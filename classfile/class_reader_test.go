@@ -2,6 +2,8 @@ package classfile
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"testing"
 )
 
@@ -10,7 +12,7 @@ var data = []byte{0x01, 0x02, 0x03, 0x04}
 func TestReadU1(t *testing.T) {
 	r := bytes.NewReader(data)
 	reader := newClassReader(r)
-	if reader.ReadU1() != 1 {
+	if v, err := reader.ReadU1(); err != nil || v != 1 {
 		t.Errorf("readU1() failed")
 	}
 }
@@ -18,7 +20,7 @@ func TestReadU1(t *testing.T) {
 func TestReadU2(t *testing.T) {
 	r := bytes.NewReader(data)
 	reader := newClassReader(r)
-	if reader.ReadU2() != 0x0102 {
+	if v, err := reader.ReadU2(); err != nil || v != 0x0102 {
 		t.Errorf("readU2() failed")
 	}
 }
@@ -26,7 +28,7 @@ func TestReadU2(t *testing.T) {
 func TestReadU4(t *testing.T) {
 	r := bytes.NewReader(data)
 	reader := newClassReader(r)
-	if reader.ReadU4() != 0x01020304 {
+	if v, err := reader.ReadU4(); err != nil || v != 0x01020304 {
 		t.Errorf("readU4() failed")
 	}
 }
@@ -34,7 +36,28 @@ func TestReadU4(t *testing.T) {
 func TestRead4(t *testing.T) {
 	r := bytes.NewReader(data)
 	reader := newClassReader(r)
-	if reader.Read4() != 0x01020304 {
+	if v, err := reader.Read4(); err != nil || v != 0x01020304 {
 		t.Errorf("read4() failed")
 	}
 }
+
+func TestReadPastEOF(t *testing.T) {
+	r := bytes.NewReader(data)
+	reader := newClassReader(r)
+	reader.ReadU2()
+	_, err := reader.ReadU4()
+	var cfe *ClassFormatError
+	if !errors.As(err, &cfe) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("readU4() past EOF: got %v", err)
+	}
+	if cfe.Offset != 2 {
+		t.Errorf("offset: got %d, want 2", cfe.Offset)
+	}
+}
+
+func TestReadHugeLength(t *testing.T) {
+	reader := newClassReader(bytes.NewReader(data))
+	if _, err := reader.Read(0xFFFFFFFF); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Read(0xFFFFFFFF): got %v", err)
+	}
+}
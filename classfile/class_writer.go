@@ -0,0 +1,518 @@
+package classfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// WriteTo writes the class file in its binary form. Counts and lengths are
+// computed from the slices, not taken from fields such as MethodCount, so a
+// modified ClassFile is written consistently. A class that is parsed and
+// written back unchanged yields exactly the bytes it was parsed from.
+//
+// Attribute names are written with the attribute_name_index they were read
+// with; attributes built in code use the first CONSTANT_Utf8 entry holding
+// the name. Attributes decoded by an AttributeDecoder are written from the
+// RawAttribute they embed.
+func (self ClassFile) WriteTo(w io.Writer) (int64, error) {
+	b, err := self.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// Bytes returns the binary form of the class file, as WriteTo writes it.
+func (self ClassFile) Bytes() ([]byte, error) {
+	cw := &classWriter{cp: self.ConstantPool}
+	cw.classFile(self)
+	if cw.err != nil {
+		return nil, cw.err
+	}
+	return cw.b, nil
+}
+
+// classWriter appends the parts of a class file to b. The first failure is
+// kept in err and everything after it is skipped, so the writing code does
+// not have to check every count.
+type classWriter struct {
+	b   []byte
+	cp  ConstantPool
+	err error
+	// names maps attribute names to their CONSTANT_Utf8 index; built on first use.
+	names map[string]uint16
+}
+
+func (self *classWriter) fail(format string, a ...any) {
+	if self.err == nil {
+		self.err = fmt.Errorf("classfile: "+format, a...)
+	}
+}
+
+func (self *classWriter) u1(v uint8) {
+	self.b = append(self.b, v)
+}
+
+func (self *classWriter) u2(v uint16) {
+	self.b = binary.BigEndian.AppendUint16(self.b, v)
+}
+
+func (self *classWriter) u4(v uint32) {
+	self.b = binary.BigEndian.AppendUint32(self.b, v)
+}
+
+// count writes the u2 length of a table.
+func (self *classWriter) count(n int, name string) {
+	if n > 0xFFFF {
+		self.fail("%s %d does not fit in a u2", name, n)
+	}
+	self.u2(uint16(n))
+}
+
+// count1 writes the u1 length of a table.
+func (self *classWriter) count1(n int, name string) {
+	if n > 0xFF {
+		self.fail("%s %d does not fit in a u1", name, n)
+	}
+	self.u1(uint8(n))
+}
+
+// indexList writes a u2 count followed by the indices, the counterpart of
+// parseIndexList.
+func (self *classWriter) indexList(indices []uint16, name string) {
+	self.count(len(indices), name+"_count")
+	for _, index := range indices {
+		self.u2(index)
+	}
+}
+
+func (self *classWriter) classFile(cf ClassFile) {
+	self.u4(0xCAFEBABE)
+	self.u2(cf.MinorVersion)
+	self.u2(cf.MajorVersion)
+	self.constantPool()
+	self.u2(uint16(cf.AccessFlags))
+	self.u2(cf.ThisClass)
+	self.u2(cf.SuperClass)
+	self.indexList(cf.Interfaces, "interfaces")
+	self.count(len(cf.Fields), "fields_count")
+	for _, field := range cf.Fields {
+		self.member(field.AccessFlags, field.NameIndex, field.DescriptorIndex, field.Attributes)
+	}
+	self.count(len(cf.Methods), "methods_count")
+	for _, method := range cf.Methods {
+		self.member(method.AccessFlags, method.NameIndex, method.DescriptorIndex, method.Attributes)
+	}
+	self.attributes(cf.Attributes)
+}
+
+func (self *classWriter) member(flags AccessFlags, nameIndex, descriptorIndex uint16, attrs []AttributeInfo) {
+	self.u2(uint16(flags))
+	self.u2(nameIndex)
+	self.u2(descriptorIndex)
+	self.attributes(attrs)
+}
+
+func (self *classWriter) constantPool() {
+	self.count(len(self.cp), "constant_pool_count")
+	for i := 1; i < len(self.cp); i++ {
+		switch info := self.cp[i].(type) {
+		case *ConstantClassInfo:
+			self.u1(ConstantClassTag)
+			self.u2(info.NameIndex)
+		case *ConstantFieldrefInfo:
+			self.u1(ConstantFieldrefTag)
+			self.u2(info.ClassIndex)
+			self.u2(info.NameAndTypeIndex)
+		case *ConstantMethodrefInfo:
+			self.u1(ConstantMethodrefTag)
+			self.u2(info.ClassIndex)
+			self.u2(info.NameAndTypeIndex)
+		case *ConstantInterfaceMethodrefInfo:
+			self.u1(ConstantInterfaceMethodrefTag)
+			self.u2(info.ClassIndex)
+			self.u2(info.NameAndTypeIndex)
+		case *ConstantStringInfo:
+			self.u1(ConstantStringTag)
+			self.u2(info.StringIndex)
+		case *ConstantIntegerInfo:
+			self.u1(ConstantIntegerTag)
+			self.fixedBytes(info.Bytes, 4, i)
+		case *ConstantFloatInfo:
+			self.u1(ConstantFloatTag)
+			self.fixedBytes(info.Bytes, 4, i)
+		case *ConstantLongInfo:
+			self.u1(ConstantLongTag)
+			self.u4(info.HighBytes)
+			self.u4(info.LowBytes)
+			i++
+			if i < len(self.cp) {
+				if _, ok := self.cp[i].(*ConstantUnusableInfo); !ok {
+					self.fail("constant_pool[%d] follows an 8-byte constant but is %T", i, self.cp[i])
+				}
+			}
+		case *ConstantDoubleInfo:
+			self.u1(ConstantDoubleTag)
+			self.u4(info.HighBytes)
+			self.u4(info.LowBytes)
+			i++
+			if i < len(self.cp) {
+				if _, ok := self.cp[i].(*ConstantUnusableInfo); !ok {
+					self.fail("constant_pool[%d] follows an 8-byte constant but is %T", i, self.cp[i])
+				}
+			}
+		case *ConstantNameAndTypeInfo:
+			self.u1(ConstantNameAndTypeTag)
+			self.u2(info.NameIndex)
+			self.u2(info.DescriptorIndex)
+		case *ConstantUtf8Info:
+			self.u1(ConstantUtf8Tag)
+			self.count(len(info.Bytes), fmt.Sprintf("constant_pool[%d].length", i))
+			self.b = append(self.b, info.Bytes...)
+		case *ConstantMethodHandleInfo:
+			self.u1(ConstantMethodHandleTag)
+			self.u1(info.ReferenceKind)
+			self.u2(info.ReferenceIndex)
+		case *ConstantMethodTypeInfo:
+			self.u1(ConstantMethodTypeTag)
+			self.u2(info.DescriptorIndex)
+		case *ConstantDynamicInfo:
+			self.u1(ConstantDynamicTag)
+			self.u2(info.BootstrapMethodAttrIndex)
+			self.u2(info.NameAndTypeIndex)
+		case *ConstantInvokeDynamicInfo:
+			self.u1(ConstantInvokeDynamicTag)
+			self.u2(info.BootstrapMethodAttrIndex)
+			self.u2(info.NameAndTypeIndex)
+		case *ConstantModuleInfo:
+			self.u1(ConstantModuleTag)
+			self.u2(info.NameIndex)
+		case *ConstantPackageInfo:
+			self.u1(ConstantPackageTag)
+			self.u2(info.NameIndex)
+		default:
+			self.fail("cannot write constant_pool[%d] of type %T", i, info)
+		}
+	}
+}
+
+func (self *classWriter) fixedBytes(b []byte, n int, index int) {
+	if len(b) != n {
+		self.fail("constant_pool[%d] has %d bytes, want %d", index, len(b), n)
+	}
+	self.b = append(self.b, b...)
+}
+
+// attributeNameIndex is the attribute_name_index attr was read with, if it
+// still holds name, or else the first CONSTANT_Utf8 entry that does.
+func (self *classWriter) attributeNameIndex(attr AttributeInfo, name string) uint16 {
+	if h, ok := attr.(interface{ header() AttributeHeader }); ok {
+		index := h.header().AttributeNameIndex
+		if self.cp.IsUsable(index) {
+			if info, ok := self.cp[index].(*ConstantUtf8Info); ok && info.Value() == name {
+				return index
+			}
+		}
+	}
+	return self.nameIndex(name)
+}
+
+// nameIndex finds the CONSTANT_Utf8 entry for an attribute name.
+func (self *classWriter) nameIndex(name string) uint16 {
+	if self.names == nil {
+		self.names = map[string]uint16{}
+		for i := len(self.cp) - 1; i > 0; i-- {
+			if info, ok := self.cp[i].(*ConstantUtf8Info); ok {
+				self.names[info.Value()] = uint16(i)
+			}
+		}
+	}
+	index, ok := self.names[name]
+	if !ok {
+		self.fail("no CONSTANT_Utf8 entry for attribute name %q", name)
+	}
+	return index
+}
+
+func (self *classWriter) attributes(attrs []AttributeInfo) {
+	self.count(len(attrs), "attributes_count")
+	for _, attr := range attrs {
+		self.attribute(attr)
+	}
+}
+
+// attribute writes attribute_name_index and attribute_length around the
+// body; the length is filled in once the body is written.
+func (self *classWriter) attribute(attr AttributeInfo) {
+	name := AttributeName(attr)
+	if name == "" {
+		self.fail("cannot write attribute of type %T", attr)
+		return
+	}
+	self.u2(self.attributeNameIndex(attr, name))
+	at := len(self.b)
+	self.u4(0)
+	self.attributeBody(attr)
+	binary.BigEndian.PutUint32(self.b[at:], uint32(len(self.b)-at-4))
+}
+
+func (self *classWriter) attributeBody(attr AttributeInfo) {
+	switch attr := attr.(type) {
+	case ConstantValueAttribute:
+		self.u2(attr.ConstantValueIndex)
+	case CodeAttribute:
+		self.u2(attr.MaxStack)
+		self.u2(attr.MaxLocals)
+		self.u4(uint32(len(attr.Code)))
+		self.b = append(self.b, attr.Code...)
+		self.count(len(attr.ExceptionTable), "exception_table_length")
+		for _, e := range attr.ExceptionTable {
+			self.u2(e.StartPc)
+			self.u2(e.EndPc)
+			self.u2(e.HandlerPc)
+			self.u2(e.CatchType)
+		}
+		self.attributes(attr.Attributes)
+	case StackMapTableAttribute:
+		self.count(len(attr.Entries), "number_of_entries")
+		for _, frame := range attr.Entries {
+			self.stackMapFrame(frame)
+		}
+	case ExceptionsAttribute:
+		self.indexList(attr.ExceptionIndexTable, "number_of_exceptions")
+	case InnerClassesAttribute:
+		self.count(len(attr.Classes), "number_of_classes")
+		for _, c := range attr.Classes {
+			self.u2(c.InnerClassInfoIndex)
+			self.u2(c.OuterClassInfoIndex)
+			self.u2(c.InnerNameIndex)
+			self.u2(uint16(c.InnerClassAccessFlags))
+		}
+	case EnclosingMethodAttribute:
+		self.u2(attr.ClassIndex)
+		self.u2(attr.MethodIndex)
+	case SyntheticAttribute, DeprecatedAttribute:
+	case SignatureAttribute:
+		self.u2(attr.SignatureIndex)
+	case SourceFileAttribute:
+		self.u2(attr.SourceFileIndex)
+	case SourceDebugExtensionAttribute:
+		self.b = append(self.b, attr.DebugExtension...)
+	case LineNumberTableAttribute:
+		self.count(len(attr.LineNumberTable), "line_number_table_length")
+		for _, e := range attr.LineNumberTable {
+			self.u2(e.StartPc)
+			self.u2(e.LineNumber)
+		}
+	case LocalVariableTableAttribute:
+		self.count(len(attr.LocalVariableTable), "local_variable_table_length")
+		for _, e := range attr.LocalVariableTable {
+			self.localVariableRow(e.StartPc, e.Length, e.NameIndex, e.DescriptorIndex, e.Index)
+		}
+	case LocalVariableTypeTableAttribute:
+		self.count(len(attr.LocalVariableTypeTable), "local_variable_type_table_length")
+		for _, e := range attr.LocalVariableTypeTable {
+			self.localVariableRow(e.StartPc, e.Length, e.NameIndex, e.SignatureIndex, e.Index)
+		}
+	case AnnotationsAttribute:
+		self.annotations(attr.Annotations)
+	case ParameterAnnotationsAttribute:
+		self.count1(len(attr.ParameterAnnotations), "num_parameters")
+		for _, annotations := range attr.ParameterAnnotations {
+			self.annotations(annotations)
+		}
+	case TypeAnnotationsAttribute:
+		self.count(len(attr.Annotations), "num_annotations")
+		for _, a := range attr.Annotations {
+			self.typeAnnotation(a)
+		}
+	case AnnotationDefaultAttribute:
+		self.elementValue(attr.DefaultValue)
+	case BootstrapMethodsAttribute:
+		self.count(len(attr.BootstrapMethods), "num_bootstrap_methods")
+		for _, m := range attr.BootstrapMethods {
+			self.u2(m.BootstrapMethodRef)
+			self.indexList(m.BootstrapArguments, "num_bootstrap_arguments")
+		}
+	case MethodParametersAttribute:
+		self.count1(len(attr.Parameters), "parameters_count")
+		for _, p := range attr.Parameters {
+			self.u2(p.NameIndex)
+			self.u2(uint16(p.AccessFlags))
+		}
+	case ModuleAttribute:
+		self.module(attr)
+	case ModulePackagesAttribute:
+		self.indexList(attr.PackageIndex, "package")
+	case ModuleMainClassAttribute:
+		self.u2(attr.MainClassIndex)
+	case NestHostAttribute:
+		self.u2(attr.HostClassIndex)
+	case NestMembersAttribute:
+		self.indexList(attr.ClassIndex, "number_of_classes")
+	case PermittedSubclassesAttribute:
+		self.indexList(attr.ClassIndex, "number_of_classes")
+	case RecordAttribute:
+		self.count(len(attr.Components), "components_count")
+		for _, c := range attr.Components {
+			self.u2(c.NameIndex)
+			self.u2(c.DescriptorIndex)
+			self.attributes(c.Attributes)
+		}
+	case interface{ Raw() RawAttribute }:
+		self.b = append(self.b, attr.Raw().Info...)
+	default:
+		self.fail("cannot write attribute of type %T", attr)
+	}
+}
+
+func (self *classWriter) localVariableRow(startPc, length, nameIndex, typeIndex, index uint16) {
+	for _, v := range []uint16{startPc, length, nameIndex, typeIndex, index} {
+		self.u2(v)
+	}
+}
+
+func (self *classWriter) stackMapFrame(frame StackMapFrame) {
+	h := frame.Header()
+	self.u1(h.FrameType)
+	if h.FrameType >= FrameTypeSameLocals1StackItemExtended {
+		self.u2(h.OffsetDelta)
+	}
+	switch f := frame.(type) {
+	case SameFrame, ChopFrame:
+	case SameLocals1StackItemFrame:
+		self.verificationTypeInfo(f.Stack)
+	case AppendFrame:
+		for _, t := range f.Locals {
+			self.verificationTypeInfo(t)
+		}
+	case FullFrame:
+		self.count(len(f.Locals), "number_of_locals")
+		for _, t := range f.Locals {
+			self.verificationTypeInfo(t)
+		}
+		self.count(len(f.Stack), "number_of_stack_items")
+		for _, t := range f.Stack {
+			self.verificationTypeInfo(t)
+		}
+	default:
+		self.fail("cannot write stack map frame of type %T", frame)
+	}
+}
+
+func (self *classWriter) verificationTypeInfo(t VerificationTypeInfo) {
+	self.u1(t.Tag)
+	switch t.Tag {
+	case ItemObject:
+		self.u2(t.CpoolIndex)
+	case ItemUninitialized:
+		self.u2(t.Offset)
+	}
+}
+
+func (self *classWriter) annotations(annotations []Annotation) {
+	self.count(len(annotations), "num_annotations")
+	for _, a := range annotations {
+		self.annotation(a)
+	}
+}
+
+func (self *classWriter) annotation(a Annotation) {
+	self.u2(a.TypeIndex)
+	self.count(len(a.ElementValuePairs), "num_element_value_pairs")
+	for _, pair := range a.ElementValuePairs {
+		self.u2(pair.ElementNameIndex)
+		self.elementValue(pair.Value)
+	}
+}
+
+func (self *classWriter) elementValue(value ElementValue) {
+	self.u1(value.Tag())
+	switch v := value.(type) {
+	case ConstElementValue:
+		self.u2(v.ConstValueIndex)
+	case EnumElementValue:
+		self.u2(v.TypeNameIndex)
+		self.u2(v.ConstNameIndex)
+	case ClassElementValue:
+		self.u2(v.ClassInfoIndex)
+	case AnnotationElementValue:
+		self.annotation(v.AnnotationValue)
+	case ArrayElementValue:
+		self.count(len(v.Values), "num_values")
+		for _, element := range v.Values {
+			self.elementValue(element)
+		}
+	default:
+		self.fail("cannot write element_value of type %T", value)
+	}
+}
+
+func (self *classWriter) typeAnnotation(a TypeAnnotation) {
+	self.u1(a.TargetType)
+	info := a.TargetInfo
+	switch a.TargetType {
+	case TargetClassTypeParameter, TargetMethodTypeParameter:
+		self.u1(info.TypeParameterIndex)
+	case TargetClassExtends:
+		self.u2(info.SupertypeIndex)
+	case TargetClassTypeParameterBound, TargetMethodTypeParameterBound:
+		self.u1(info.TypeParameterIndex)
+		self.u1(info.BoundIndex)
+	case TargetField, TargetMethodReturn, TargetMethodReceiver:
+	case TargetMethodFormalParameter:
+		self.u1(info.FormalParameterIndex)
+	case TargetThrows:
+		self.u2(info.ThrowsTypeIndex)
+	case TargetLocalVariable, TargetResourceVariable:
+		self.count(len(info.LocalVarTable), "table_length")
+		for _, e := range info.LocalVarTable {
+			self.u2(e.StartPc)
+			self.u2(e.Length)
+			self.u2(e.Index)
+		}
+	case TargetExceptionParameter:
+		self.u2(info.ExceptionTableIndex)
+	case TargetInstanceOf, TargetNew, TargetConstructorReference, TargetMethodReference:
+		self.u2(info.Offset)
+	case TargetCast, TargetConstructorInvocationTypeArg, TargetMethodInvocationTypeArg, TargetConstructorReferenceTypeArg, TargetMethodReferenceTypeArg:
+		self.u2(info.Offset)
+		self.u1(info.TypeArgumentIndex)
+	default:
+		self.fail("cannot write target_type %#x", a.TargetType)
+	}
+	self.count1(len(a.TargetPath), "path_length")
+	for _, p := range a.TargetPath {
+		self.u1(p.TypePathKind)
+		self.u1(p.TypeArgumentIndex)
+	}
+	self.annotation(a.Annotation)
+}
+
+func (self *classWriter) module(m ModuleAttribute) {
+	self.u2(m.ModuleNameIndex)
+	self.u2(uint16(m.ModuleFlags))
+	self.u2(m.ModuleVersionIndex)
+	self.count(len(m.Requires), "requires_count")
+	for _, r := range m.Requires {
+		self.u2(r.RequiresIndex)
+		self.u2(uint16(r.RequiresFlags))
+		self.u2(r.RequiresVersionIndex)
+	}
+	for _, directives := range [][]ModuleExports{m.Exports, m.Opens} {
+		self.count(len(directives), "exports_count")
+		for _, d := range directives {
+			self.u2(d.Index)
+			self.u2(uint16(d.Flags))
+			self.indexList(d.ToIndex, "exports_to")
+		}
+	}
+	self.indexList(m.UsesIndex, "uses")
+	self.count(len(m.Provides), "provides_count")
+	for _, p := range m.Provides {
+		self.u2(p.ProvidesIndex)
+		self.indexList(p.ProvidesWithIndex, "provides_with")
+	}
+}
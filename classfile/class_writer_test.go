@@ -0,0 +1,203 @@
+package classfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func roundTrip(t *testing.T, b []byte) {
+	t.Helper()
+	cf, err := NewClassFileParser(bytes.NewReader(b)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	n, err := cf.WriteTo(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(out.Len()) {
+		t.Errorf("WriteTo returned %d, wrote %d bytes", n, out.Len())
+	}
+	if got := out.Bytes(); !bytes.Equal(got, b) {
+		i := 0
+		for i < len(got) && i < len(b) && got[i] == b[i] {
+			i++
+		}
+		t.Errorf("round trip differs at offset %#x: wrote %d bytes, want %d", i, len(got), len(b))
+	}
+}
+
+func TestWriteHello(t *testing.T) {
+	roundTrip(t, readHello(t))
+}
+
+// TestWriteDuplicateAttributeName round-trips a class whose SourceFile
+// attribute is named by a second CONSTANT_Utf8 "SourceFile" entry.
+func TestWriteDuplicateAttributeName(t *testing.T) {
+	cf, err := NewClassFileParser(bytes.NewReader(readHello(t))).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dup := uint16(len(cf.ConstantPool))
+	cf.ConstantPool = append(cf.ConstantPool, &ConstantUtf8Info{10, []byte("SourceFile")})
+	sf := cf.Attributes[0].(SourceFileAttribute)
+	sf.AttributeNameIndex = dup
+	cf.Attributes[0] = sf
+	b, err := cf.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := bytes.LastIndex(b, []byte{0, byte(dup), 0, 0, 0, 2}); i != len(b)-8 {
+		t.Fatalf("SourceFile not written under #%d", dup)
+	}
+	roundTrip(t, b)
+}
+
+// attr encodes an attribute with the given name index and body.
+func attr(nameIndex uint16, body ...byte) []byte {
+	b := binary.BigEndian.AppendUint16(nil, nameIndex)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(body)))
+	return append(b, body...)
+}
+
+func cat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func utf8Entry(s string) []byte {
+	return cat([]byte{ConstantUtf8Tag, 0, byte(len(s))}, []byte(s))
+}
+
+// TestWriteEveryAttribute round-trips a class carrying every attribute and
+// constant the parser decodes, plus one it does not know.
+func TestWriteEveryAttribute(t *testing.T) {
+	names := []string{
+		"Foo", "java/lang/Object", "x", "I", "m", "(I)V", // #1-#6
+		"ConstantValue", "Code", "StackMapTable", "LineNumberTable", // #7-#10
+		"LocalVariableTable", "LocalVariableTypeTable", "Exceptions", // #11-#13
+		"MethodParameters", "RuntimeVisibleAnnotations", // #14, #15
+		"RuntimeInvisibleParameterAnnotations", "RuntimeVisibleTypeAnnotations", // #16, #17
+		"AnnotationDefault", "SourceFile", "InnerClasses", "NestMembers", // #18-#21
+		"BootstrapMethods", "Signature", "Deprecated", "Synthetic", // #22-#25
+		"SourceDebugExtension", "Vendor", "Record", "PermittedSubclasses", // #26-#29
+		"EnclosingMethod", "NestHost", "Module", "ModulePackages", // #30-#33
+		"ModuleMainClass", "LAnno;", "Foo.java", "Foo$Bar", "Bar", "mod", "pkg", // #34-#40
+	}
+	var entries [][]byte
+	for _, name := range names {
+		entries = append(entries, utf8Entry(name))
+	}
+	entries = append(entries,
+		[]byte{ConstantClassTag, 0, 1},                          // #41 Foo
+		[]byte{ConstantClassTag, 0, 2},                          // #42 java/lang/Object
+		[]byte{ConstantClassTag, 0, 37},                         // #43 Foo$Bar
+		[]byte{ConstantIntegerTag, 0, 0, 0, 42},                 // #44
+		[]byte{ConstantLongTag, 0, 0, 0, 0, 0, 0, 0, 7},         // #45, #46
+		[]byte{ConstantDoubleTag, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, // #47, #48
+		[]byte{ConstantFloatTag, 0x40, 0x20, 0, 0},              // #49
+		[]byte{ConstantStringTag, 0, 3},                         // #50
+		[]byte{ConstantNameAndTypeTag, 0, 5, 0, 6},              // #51 m:(I)V
+		[]byte{ConstantMethodrefTag, 0, 41, 0, 51},              // #52
+		[]byte{ConstantNameAndTypeTag, 0, 3, 0, 4},              // #53 x:I
+		[]byte{ConstantFieldrefTag, 0, 41, 0, 53},               // #54
+		[]byte{ConstantInterfaceMethodrefTag, 0, 41, 0, 51},     // #55
+		[]byte{ConstantMethodHandleTag, 6, 0, 52},               // #56
+		[]byte{ConstantMethodTypeTag, 0, 6},                     // #57
+		[]byte{ConstantInvokeDynamicTag, 0, 0, 0, 51},           // #58
+		[]byte{ConstantDynamicTag, 0, 0, 0, 53},                 // #59
+		[]byte{ConstantModuleTag, 0, 39},                        // #60
+		[]byte{ConstantPackageTag, 0, 40},                       // #61
+	)
+
+	code := attr(8, cat(
+		[]byte{0, 2, 0, 2, 0, 0, 0, 6, 0x1b, 0x99, 0, 4, 0xb1, 0xb1}, // max_stack, max_locals, code
+		[]byte{0, 1, 0, 0, 0, 4, 0, 5, 0, 42},                        // exception_table
+		[]byte{0, 5},
+		attr(9, // StackMapTable
+			0, 7,
+			5,     // same_frame
+			64, 1, // same_locals_1_stack_item_frame, int
+			247, 0, 0, 7, 0, 41, // extended, Foo
+			248, 0, 0, // chop_frame
+			251, 0, 0, // same_frame_extended
+			252, 0, 0, 8, 0, 0, // append_frame, uninitialized(0)
+			255, 0, 0, 0, 2, 0, 4, 0, 2, 5, 6, // full_frame
+		),
+		attr(10, 0, 2, 0, 0, 0, 3, 0, 4, 0, 4),        // LineNumberTable
+		attr(11, 0, 1, 0, 0, 0, 6, 0, 3, 0, 4, 0, 1),  // LocalVariableTable
+		attr(12, 0, 1, 0, 0, 0, 6, 0, 3, 0, 23, 0, 1), // LocalVariableTypeTable
+		attr(17, 0, 2, // RuntimeVisibleTypeAnnotations
+			0x40, 0, 1, 0, 0, 0, 6, 0, 1, 0, 0, 35, 0, 0, // local variable
+			0x47, 0, 1, 0, 1, 1, 0, 0, 35, 0, 0, // cast, path [0,0]
+		),
+	)...)
+	method := cat(
+		[]byte{0, 0x09, 0, 5, 0, 6, 0, 9},
+		code,
+		attr(13, 0, 1, 0, 42), // Exceptions
+		attr(14, 1, 0, 3, 0, 0x10),
+		attr(15, 0, 1, 0, 35, 0, 6, // RuntimeVisibleAnnotations with every element kind
+			0, 3, 'I', 0, 44,
+			0, 3, 'J', 0, 45,
+			0, 3, 'D', 0, 47,
+			0, 3, 'F', 0, 49,
+			0, 3, 's', 0, 3,
+			0, 3, '[', 0, 3,
+			'e', 0, 35, 0, 3,
+			'c', 0, 4,
+			'@', 0, 35, 0, 0,
+		),
+		attr(16, 1, 0, 1, 0, 35, 0, 0), // RuntimeInvisibleParameterAnnotations
+		attr(18, 'I', 0, 44),           // AnnotationDefault
+		attr(23, 0, 6),                 // Signature
+		attr(24),                       // Deprecated
+		attr(25),                       // Synthetic
+	)
+	field := cat(
+		[]byte{0, 0x1a, 0, 3, 0, 4, 0, 1},
+		attr(7, 0, 44), // ConstantValue
+	)
+	body := cat(
+		[]byte{0, 0x21, 0, 41, 0, 42, 0, 0},
+		[]byte{0, 1}, field,
+		[]byte{0, 1}, method,
+		[]byte{0, 15},
+		attr(19, 0, 36), // SourceFile
+		attr(20, 0, 1, 0, 43, 0, 41, 0, 38, 0, 0x09),                           // InnerClasses
+		attr(21, 0, 1, 0, 43),                                                  // NestMembers
+		attr(22, 0, 1, 0, 56, 0, 2, 0, 57, 0, 50),                              // BootstrapMethods
+		attr(26, 'S', 'M', 'A', 'P'),                                           // SourceDebugExtension
+		attr(27, 1, 2, 3),                                                      // Vendor, undecoded
+		attr(28, append([]byte{0, 1, 0, 3, 0, 4, 0, 1}, attr(23, 0, 4)...)...), // Record
+		attr(29, 0, 1, 0, 43),                                                  // PermittedSubclasses
+		attr(30, 0, 41, 0, 51),                                                 // EnclosingMethod
+		attr(31, 0, 41),                                                        // NestHost
+		attr(32, 0, 60, 0, 0x20, 0, 0, // Module
+			0, 1, 0, 60, 0x80, 0, 0, 0, // requires
+			0, 1, 0, 61, 0, 0, 0, 1, 0, 60, // exports
+			0, 1, 0, 61, 0, 0, 0, 0, // opens
+			0, 1, 0, 41, // uses
+			0, 1, 0, 41, 0, 1, 0, 43, // provides
+		),
+		attr(33, 0, 1, 0, 61), // ModulePackages
+		attr(34, 0, 41),       // ModuleMainClass
+		attr(17, 0, 1, 0x10, 0xff, 0xff, 0, 0, 35, 0, 0), // RuntimeVisibleTypeAnnotations, extends
+		attr(15, 0, 0), // RuntimeVisibleAnnotations
+	)
+	header := []byte{0xca, 0xfe, 0xba, 0xbe, 0, 0, 0, 61, 0, 62}
+	roundTrip(t, cat(header, cat(entries...), body))
+}
+
+func TestWriteMissingAttributeName(t *testing.T) {
+	cf := ClassFile{
+		ConstantPool: ConstantPool{nil, utf8Info("Foo")},
+		Attributes:   []AttributeInfo{DeprecatedAttribute{}},
+	}
+	_, err := cf.Bytes()
+	if err == nil || !strings.Contains(err.Error(), `"Deprecated"`) {
+		t.Errorf("got %v, want a missing attribute name error", err)
+	}
+}
@@ -1,9 +1,13 @@
 package classfile
 
 import (
+	"encoding/binary"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
-	"regexp"
+
+	"gjvm/descriptor"
 )
 
 type ConstantPool []ConstantInfo
@@ -46,15 +50,16 @@ type ConstantFieldrefInfo struct {
 func (self ConstantFieldrefInfo) String() string {
 	return fmt.Sprintf("ConstantFieldInfo: classIndex #%d, nameAndTypeIndex #%d", self.ClassIndex, self.NameAndTypeIndex)
 }
-func (self ConstantFieldrefInfo) Resolve(cp ConstantPool) FieldRef {
-	class := cp[self.ClassIndex].(*ConstantClassInfo)
-	className := cp[class.NameIndex].(*ConstantUtf8Info).Value()
-	className = strings.ReplaceAll(className, "/", ".")
-
-	nameAndType := cp[self.NameAndTypeIndex].(*ConstantNameAndTypeInfo)
-	name := cp[nameAndType.NameIndex].(*ConstantUtf8Info).Value()
-	descriptor := cp[nameAndType.DescriptorIndex].(*ConstantUtf8Info).Value()
-	return FieldRef{className, name, descriptor}
+func (self ConstantFieldrefInfo) Resolve(cp ConstantPool) (FieldRef, error) {
+	className, err := cp.binaryName(self.ClassIndex)
+	if err != nil {
+		return FieldRef{}, err
+	}
+	name, descriptor, err := cp.nameAndType(self.NameAndTypeIndex)
+	if err != nil {
+		return FieldRef{}, err
+	}
+	return FieldRef{className, name, descriptor}, nil
 }
 
 type FieldRef struct {
@@ -71,28 +76,35 @@ func (self ConstantMethodrefInfo) String() string {
 	return fmt.Sprintf("ConstantMethodrefInfo: classIndex #%d, nameAndTypeIndex #%d", self.ClassIndex, self.NameAndTypeIndex)
 }
 
-// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.3.2
-const ArgPattern = `L([^;]+);`
-var argRegexp = regexp.MustCompile(ArgPattern)
-func (self ConstantMethodrefInfo) Resolve(cp ConstantPool) MethodRef {
-	class := cp[self.ClassIndex].(*ConstantClassInfo)
-	className := cp[class.NameIndex].(*ConstantUtf8Info).Value()
-	className = strings.ReplaceAll(className, "/", ".")
-	nameAndType := cp[self.NameAndTypeIndex].(*ConstantNameAndTypeInfo)
-	name := cp[nameAndType.NameIndex].(*ConstantUtf8Info).Value()
-	descriptor := cp[nameAndType.DescriptorIndex].(*ConstantUtf8Info).Value()
-	args := []string{}
-	for _, match := range argRegexp.FindAllStringSubmatch(descriptor, -1) {
-		arg := strings.ReplaceAll(match[1], "/", ".")
-		args = append(args, arg)
+func (self ConstantMethodrefInfo) Resolve(cp ConstantPool) (MethodRef, error) {
+	return resolveMethodRef(cp, self.ClassIndex, self.NameAndTypeIndex)
+}
+
+func resolveMethodRef(cp ConstantPool, classIndex, nameAndTypeIndex uint16) (MethodRef, error) {
+	className, err := cp.binaryName(classIndex)
+	if err != nil {
+		return MethodRef{}, err
+	}
+	name, desc, err := cp.nameAndType(nameAndTypeIndex)
+	if err != nil {
+		return MethodRef{}, err
+	}
+	md, err := descriptor.ParseMethodDescriptor(desc)
+	if err != nil {
+		return MethodRef{}, err
+	}
+	args := make([]string, len(md.Parameters))
+	for i, param := range md.Parameters {
+		args[i] = param.String()
 	}
 
-	return MethodRef{className, name, args}
+	return MethodRef{className, name, args, md}, nil
 }
 type MethodRef struct {
 	Class string
 	Name string
 	ArgTypes []string
+	Descriptor descriptor.MethodDescriptor
 }
 
 type ConstantInterfaceMethodrefInfo struct {
@@ -104,6 +116,10 @@ func (self ConstantInterfaceMethodrefInfo) String() string {
 	return fmt.Sprintf("ConstantInterfaceMethodrefInfo: classIndex #%d, nameAndTypeIndex #%d", self.ClassIndex, self.NameAndTypeIndex)
 }
 
+func (self ConstantInterfaceMethodrefInfo) Resolve(cp ConstantPool) (MethodRef, error) {
+	return resolveMethodRef(cp, self.ClassIndex, self.NameAndTypeIndex)
+}
+
 // https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.4.3
 type ConstantStringInfo struct {
 	StringIndex uint16
@@ -112,8 +128,8 @@ func (self ConstantStringInfo) String() string {
 	return fmt.Sprintf("ConstantStringInfo: stringIndex #%d", self.StringIndex)
 }
 
-func (self ConstantStringInfo) Resolve(cp ConstantPool) string {
-	return cp[self.StringIndex].(*ConstantUtf8Info).Value()
+func (self ConstantStringInfo) Resolve(cp ConstantPool) (string, error) {
+	return cp.utf8(self.StringIndex)
 }
 
 // https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.4.4
@@ -121,14 +137,23 @@ type ConstantIntegerInfo struct {
 	Bytes []byte
 }
 func (self ConstantIntegerInfo) String() string {
-	return fmt.Sprintf("ConstantIntegerInfo: %d", self.Bytes)
+	return fmt.Sprintf("ConstantIntegerInfo: %d", self.Value())
+}
+func (self ConstantIntegerInfo) Value() int32 {
+	return int32(binary.BigEndian.Uint32(self.Bytes))
 }
 
 type ConstantFloatInfo struct {
 	Bytes []byte
 }
 func (self ConstantFloatInfo) String() string {
-	return fmt.Sprintf("ConstantFloatInfo: %d", self.Bytes)
+	return fmt.Sprintf("ConstantFloatInfo: %sf", FormatJavaFloat(float64(self.Value()), 32))
+}
+// Value decodes the IEEE 754 single format. 0x7f800000 and 0xff800000 are the
+// infinities; any bit pattern in 0x7f800001..0x7fffffff or 0xff800001..0xffffffff
+// is a NaN, and its payload is kept.
+func (self ConstantFloatInfo) Value() float32 {
+	return math.Float32frombits(binary.BigEndian.Uint32(self.Bytes))
 }
 
 // https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.4.5
@@ -137,7 +162,10 @@ type ConstantLongInfo struct {
 	LowBytes  uint32
 }
 func (self ConstantLongInfo) String() string {
-	return fmt.Sprintf("ConstantLongInfo: highBytes %d, lowBytes %d", self.HighBytes, self.LowBytes)
+	return fmt.Sprintf("ConstantLongInfo: %dL", self.Value())
+}
+func (self ConstantLongInfo) Value() int64 {
+	return int64(uint64(self.HighBytes)<<32 | uint64(self.LowBytes))
 }
 
 type ConstantDoubleInfo struct {
@@ -145,7 +173,108 @@ type ConstantDoubleInfo struct {
 	LowBytes  uint32
 }
 func (self ConstantDoubleInfo) String() string {
-	return fmt.Sprintf("ConstantDoubleInfo: highBytes %d, lowBytes %d", self.HighBytes, self.LowBytes)
+	return fmt.Sprintf("ConstantDoubleInfo: %sd", FormatJavaFloat(self.Value(), 64))
+}
+// Value decodes the IEEE 754 double format, with the same infinity and NaN
+// rules as ConstantFloatInfo applied to the 64-bit patterns.
+func (self ConstantDoubleInfo) Value() float64 {
+	return math.Float64frombits(uint64(self.HighBytes)<<32 | uint64(self.LowBytes))
+}
+
+// FormatJavaFloat renders v the way Float.toString / Double.toString do:
+// "1.5", "1.0E10", "1.0E-5", "NaN", "-Infinity".
+func FormatJavaFloat(v float64, bitSize int) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	}
+	if abs := math.Abs(v); abs == 0 || (abs >= 1e-3 && abs < 1e7) {
+		s := strconv.FormatFloat(v, 'f', -1, bitSize)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	}
+	s := strconv.FormatFloat(v, 'E', -1, bitSize)
+	mantissa, exp, _ := strings.Cut(s, "E")
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	n, _ := strconv.Atoi(exp)
+	return fmt.Sprintf("%sE%d", mantissa, n)
+}
+
+// The entry following a ConstantLongInfo or ConstantDoubleInfo. It occupies an
+// index of the pool but is never referenced.
+type ConstantUnusableInfo struct{}
+func (self ConstantUnusableInfo) String() string {
+	return "(unusable)"
+}
+
+// IsUsable reports whether index refers to a real entry, i.e. neither the
+// reserved index 0 nor the slot after a long or double.
+func (cp ConstantPool) IsUsable(index uint16) bool {
+	if int(index) >= len(cp) || cp[index] == nil {
+		return false
+	}
+	_, unusable := cp[index].(*ConstantUnusableInfo)
+	return !unusable
+}
+
+// lookup returns entry index of type T, failing instead of panicking on an
+// unusable index or an entry of the wrong kind.
+func lookup[T ConstantInfo](cp ConstantPool, index uint16, kind string) (T, error) {
+	var zero T
+	if !cp.IsUsable(index) {
+		return zero, fmt.Errorf("constant pool index #%d is not usable", index)
+	}
+	info, ok := cp[index].(T)
+	if !ok {
+		return zero, fmt.Errorf("constant pool entry #%d is not %s", index, kind)
+	}
+	return info, nil
+}
+
+// utf8 looks up a CONSTANT_Utf8 entry.
+func (self ConstantPool) utf8(index uint16) (string, error) {
+	info, err := lookup[*ConstantUtf8Info](self, index, "CONSTANT_Utf8")
+	if err != nil {
+		return "", err
+	}
+	return info.Value(), nil
+}
+
+// className looks up a CONSTANT_Class entry and returns the name in internal
+// form, e.g. "com/x/Foo".
+func (self ConstantPool) className(index uint16) (string, error) {
+	class, err := lookup[*ConstantClassInfo](self, index, "CONSTANT_Class")
+	if err != nil {
+		return "", err
+	}
+	return self.utf8(class.NameIndex)
+}
+
+// binaryName is className with the name in binary form, e.g. "com.x.Foo".
+func (self ConstantPool) binaryName(index uint16) (string, error) {
+	name, err := self.className(index)
+	return strings.ReplaceAll(name, "/", "."), err
+}
+
+// nameAndType looks up a CONSTANT_NameAndType entry and both of its names.
+func (self ConstantPool) nameAndType(index uint16) (name, descriptor string, err error) {
+	nt, err := lookup[*ConstantNameAndTypeInfo](self, index, "CONSTANT_NameAndType")
+	if err != nil {
+		return "", "", err
+	}
+	if name, err = self.utf8(nt.NameIndex); err != nil {
+		return "", "", err
+	}
+	descriptor, err = self.utf8(nt.DescriptorIndex)
+	return name, descriptor, err
 }
 
 // https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.4.6
@@ -163,10 +292,21 @@ type ConstantUtf8Info struct {
 	Bytes  []byte
 }
 func (self ConstantUtf8Info) String() string {
-	return fmt.Sprintf("ConstantUtf8Info: length %d, %s", self.Length, string(self.Bytes))
+	return fmt.Sprintf("ConstantUtf8Info: length %d, %s", self.Length, self.Value())
 }
+// Value decodes the modified UTF-8 bytes. The parser rejects malformed
+// entries, so for a parsed pool this never loses information.
 func (self ConstantUtf8Info) Value() string {
-	return string(self.Bytes)
+	s, err := DecodeModifiedUTF8(self.Bytes)
+	if err != nil {
+		return string(self.Bytes)
+	}
+	return s
+}
+// UTF16 returns the string as UTF-16 code units, as java.lang.String holds it.
+// Unlike Value, it reports malformed bytes instead of falling back.
+func (self ConstantUtf8Info) UTF16() ([]uint16, error) {
+	return ModifiedUTF8ToUTF16(self.Bytes)
 }
 
 // https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.4.8
@@ -178,6 +318,30 @@ func (self ConstantMethodHandleInfo) String() string {
 	return fmt.Sprintf("ConstantMethodHandleInfo: referenceKind %d, referenceIndex #%d", self.ReferenceKind, self.ReferenceIndex)
 }
 
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-5.html#jvms-5.4.3.5
+const (
+	REF_getField         = 1
+	REF_getStatic        = 2
+	REF_putField         = 3
+	REF_putStatic        = 4
+	REF_invokeVirtual    = 5
+	REF_invokeStatic     = 6
+	REF_invokeSpecial    = 7
+	REF_newInvokeSpecial = 8
+	REF_invokeInterface  = 9
+)
+
+var referenceKindNames = [...]string{"", "REF_getField", "REF_getStatic", "REF_putField", "REF_putStatic",
+	"REF_invokeVirtual", "REF_invokeStatic", "REF_invokeSpecial", "REF_newInvokeSpecial", "REF_invokeInterface"}
+
+// ReferenceKindName returns the JVMS name of reference_kind, e.g. "REF_invokeStatic".
+func (self ConstantMethodHandleInfo) ReferenceKindName() string {
+	if self.ReferenceKind == 0 || int(self.ReferenceKind) >= len(referenceKindNames) {
+		return fmt.Sprintf("REF_%d", self.ReferenceKind)
+	}
+	return referenceKindNames[self.ReferenceKind]
+}
+
 // https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.4.9
 type ConstantMethodTypeInfo struct {
 	DescriptorIndex uint16
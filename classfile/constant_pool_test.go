@@ -0,0 +1,86 @@
+package classfile
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNumericConstantValues(t *testing.T) {
+	if v := (ConstantIntegerInfo{[]byte{0xff, 0xff, 0xff, 0xfe}}).Value(); v != -2 {
+		t.Errorf("int: got %d", v)
+	}
+	if v := (ConstantLongInfo{0x80000000, 0}).Value(); v != math.MinInt64 {
+		t.Errorf("long: got %d", v)
+	}
+	if v := (ConstantFloatInfo{[]byte{0x7f, 0x80, 0, 0}}).Value(); !math.IsInf(float64(v), 1) {
+		t.Errorf("float +inf: got %v", v)
+	}
+	if v := (ConstantFloatInfo{[]byte{0xff, 0x80, 0, 1}}).Value(); !math.IsNaN(float64(v)) {
+		t.Errorf("float NaN: got %v", v)
+	}
+	if v := (ConstantDoubleInfo{0xfff00000, 0}).Value(); !math.IsInf(v, -1) {
+		t.Errorf("double -inf: got %v", v)
+	}
+	if v := (ConstantDoubleInfo{0x7ff00000, 1}).Value(); !math.IsNaN(v) {
+		t.Errorf("double NaN: got %v", v)
+	}
+}
+
+func TestNumericConstantStrings(t *testing.T) {
+	cases := []struct {
+		got, want string
+	}{
+		{ConstantIntegerInfo{[]byte{0, 0, 0, 42}}.String(), "ConstantIntegerInfo: 42"},
+		{ConstantFloatInfo{[]byte{0x3f, 0xc0, 0, 0}}.String(), "ConstantFloatInfo: 1.5f"},
+		{ConstantFloatInfo{[]byte{0x3d, 0xcc, 0xcc, 0xcd}}.String(), "ConstantFloatInfo: 0.1f"},
+		{ConstantFloatInfo{[]byte{0x7f, 0xc0, 0, 0}}.String(), "ConstantFloatInfo: NaNf"},
+		{ConstantLongInfo{0, 123}.String(), "ConstantLongInfo: 123L"},
+		{ConstantDoubleInfo{0x3ff00000, 0}.String(), "ConstantDoubleInfo: 1.0d"},
+		{ConstantDoubleInfo{0x4202a05f, 0x20000000}.String(), "ConstantDoubleInfo: 1.0E10d"},
+		{ConstantDoubleInfo{0x3ee4f8b5, 0x88e368f1}.String(), "ConstantDoubleInfo: 1.0E-5d"},
+		{ConstantDoubleInfo{0x80000000, 0}.String(), "ConstantDoubleInfo: -0.0d"},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("got %q, want %q", c.got, c.want)
+		}
+	}
+}
+
+func TestResolveBadIndex(t *testing.T) {
+	cp := ConstantPool{
+		nil,
+		&ConstantLongInfo{0, 1},
+		&ConstantUnusableInfo{},
+		utf8Info("java/lang/System"),
+		&ConstantClassInfo{3},
+		utf8Info("out"),
+		utf8Info("Ljava/io/PrintStream;"),
+		&ConstantNameAndTypeInfo{5, 6},
+	}
+	if ref, err := (ConstantFieldrefInfo{4, 7}).Resolve(cp); err != nil || ref.Class != "java.lang.System" || ref.Name != "out" {
+		t.Errorf("got %v, %v", ref, err)
+	}
+	if name, err := cp.className(4); err != nil || name != "java/lang/System" {
+		t.Errorf("className: got %q, %v", name, err)
+	}
+	if name, err := cp.binaryName(4); err != nil || name != "java.lang.System" {
+		t.Errorf("binaryName: got %q, %v", name, err)
+	}
+	for _, tc := range []struct {
+		resolve func() error
+		want    string
+	}{
+		{func() error { _, err := (ConstantFieldrefInfo{2, 7}).Resolve(cp); return err }, "constant pool index #2 is not usable"},
+		{func() error { _, err := (ConstantFieldrefInfo{4, 99}).Resolve(cp); return err }, "constant pool index #99 is not usable"},
+		{func() error { _, err := (ConstantMethodrefInfo{4, 3}).Resolve(cp); return err }, "constant pool entry #3 is not CONSTANT_NameAndType"},
+		{func() error { _, err := (ConstantMethodrefInfo{4, 7}).Resolve(cp); return err }, "expected '('"},
+		{func() error { _, err := (ConstantStringInfo{2}).Resolve(cp); return err }, "constant pool index #2 is not usable"},
+		{func() error { _, err := (ConstantStringInfo{4}).Resolve(cp); return err }, "constant pool entry #4 is not CONSTANT_Utf8"},
+	} {
+		if err := tc.resolve(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("got %v, want %s", err, tc.want)
+		}
+	}
+}
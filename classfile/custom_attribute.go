@@ -0,0 +1,102 @@
+package classfile
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AttributeContext is the structure an attribute is attached to.
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7-320
+type AttributeContext uint8
+
+const (
+	ClassContext AttributeContext = iota
+	FieldContext
+	MethodContext
+	CodeContext
+	RecordComponentContext
+)
+
+func (self AttributeContext) String() string {
+	switch self {
+	case ClassContext:
+		return "ClassFile"
+	case FieldContext:
+		return "field_info"
+	case MethodContext:
+		return "method_info"
+	case CodeContext:
+		return "Code"
+	case RecordComponentContext:
+		return "record_component_info"
+	default:
+		return fmt.Sprintf("AttributeContext(%d)", uint8(self))
+	}
+}
+
+// RawAttribute is an attribute as it appears in the class file, before decoding.
+type RawAttribute struct {
+	AttributeHeader
+	AttributeName string
+	Info          []byte
+}
+
+// Raw returns the attribute as it was read. Types returned by an
+// AttributeDecoder embed the RawAttribute they were given, so the original
+// bytes stay available when the class is written back.
+func (self RawAttribute) Raw() RawAttribute {
+	return self
+}
+
+// Raw returns the undecoded attribute.
+func (self NotImplementedAttributeInfo) Raw() RawAttribute {
+	return RawAttribute{self.AttributeHeader, self.AttributeName, self.Info}
+}
+
+// AttributeDecoder decodes the body of a non-standard attribute, e.g. Scala's
+// ScalaSig or a vendor attribute. cp is the constant pool of the class being
+// parsed. An error is reported as a ClassFormatError at the attribute. The
+// attribute returned must embed raw, so that it can be written back; the
+// parser rejects any that has no Raw method.
+type AttributeDecoder func(raw RawAttribute, cp ConstantPool) (AttributeInfo, error)
+
+type attributeKey struct {
+	name    string
+	context AttributeContext
+}
+
+// RegisterAttributeDecoder makes the parser decode attributes named name that
+// are attached to context with decoder, instead of leaving them as
+// NotImplementedAttributeInfo. Attributes the parser decodes itself, such as
+// Code, cannot be overridden.
+//
+//	parser.RegisterAttributeDecoder("ScalaSig", classfile.ClassContext, decodeScalaSig)
+func (self *ClassFileParser) RegisterAttributeDecoder(name string, context AttributeContext, decoder AttributeDecoder) {
+	if self.decoders == nil {
+		self.decoders = map[attributeKey]AttributeDecoder{}
+	}
+	self.decoders[attributeKey{name, context}] = decoder
+}
+
+// decodeCustomAttribute runs the decoder registered for the attribute, if any.
+func (self ClassFileParser) decodeCustomAttribute(raw RawAttribute, context AttributeContext, offset int64) (AttributeInfo, bool, error) {
+	decoder, ok := self.decoders[attributeKey{raw.AttributeName, context}]
+	if !ok {
+		return nil, false, nil
+	}
+	attr, err := decoder(raw, self.cp)
+	if err != nil {
+		var cfe *ClassFormatError
+		if !errors.As(err, &cfe) {
+			err = &ClassFormatError{Offset: offset, Msg: err.Error(), Err: err}
+		}
+		return nil, true, err
+	}
+	if attr == nil {
+		return nil, true, &ClassFormatError{Offset: offset, Msg: "attribute decoder returned nil"}
+	}
+	if _, ok := attr.(interface{ Raw() RawAttribute }); !ok {
+		return nil, true, &ClassFormatError{Offset: offset, Msg: fmt.Sprintf("attribute decoder returned %T, which does not embed RawAttribute", attr)}
+	}
+	return attr, true, nil
+}
@@ -0,0 +1,71 @@
+package classfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type vendorVersionAttribute struct {
+	RawAttribute
+	Version uint16
+}
+
+func (self vendorVersionAttribute) String() string {
+	return fmt.Sprintf("VendorVersion: %d", self.Version)
+}
+
+func TestRegisterAttributeDecoder(t *testing.T) {
+	cp := ConstantPool{nil, utf8Info("VendorVersion")}
+	b := []byte{0, 1, 0, 0, 0, 2, 0, 7}
+	decode := func(raw RawAttribute, cp ConstantPool) (AttributeInfo, error) {
+		if len(raw.Info) != 2 {
+			return nil, errors.New("VendorVersion must be 2 bytes")
+		}
+		return vendorVersionAttribute{raw, binary.BigEndian.Uint16(raw.Info)}, nil
+	}
+	parser := &ClassFileParser{reader: newClassReader(bytes.NewReader(b)), cp: cp}
+	parser.RegisterAttributeDecoder("VendorVersion", MethodContext, decode)
+
+	attrs, err := parser.parseAttributeInfo(1, MethodContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attr, ok := FindAttribute[vendorVersionAttribute](attrs)
+	if !ok || attr.Version != 7 {
+		t.Fatalf("got %v", attrs)
+	}
+	if raw := attr.Raw(); raw.AttributeName != "VendorVersion" || !bytes.Equal(raw.Info, []byte{0, 7}) {
+		t.Errorf("Raw: got %+v", raw)
+	}
+
+	// The decoder is registered for methods only.
+	parser.reader = newClassReader(bytes.NewReader(b))
+	attrs, err = parser.parseAttributeInfo(1, FieldContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := attrs[0].(NotImplementedAttributeInfo); !ok {
+		t.Errorf("field attribute: got %T", attrs[0])
+	}
+
+	parser.reader = newClassReader(bytes.NewReader([]byte{0, 1, 0, 0, 0, 1, 7}))
+	_, err = parser.parseAttributeInfo(1, MethodContext)
+	want := "java.lang.ClassFormatError: VendorVersion must be 2 bytes at offset 0x6 while reading attributes[0].VendorVersion"
+	if err == nil || err.Error() != want {
+		t.Errorf("got  %v\nwant %s", err, want)
+	}
+
+	// Without the raw bytes the attribute could not be written back.
+	parser.RegisterAttributeDecoder("VendorVersion", FieldContext, func(raw RawAttribute, cp ConstantPool) (AttributeInfo, error) {
+		return DeprecatedAttribute{}, nil
+	})
+	parser.reader = newClassReader(bytes.NewReader(b))
+	_, err = parser.parseAttributeInfo(1, FieldContext)
+	want = "java.lang.ClassFormatError: attribute decoder returned classfile.DeprecatedAttribute, which does not embed RawAttribute at offset 0x6 while reading attributes[0].VendorVersion"
+	if err == nil || err.Error() != want {
+		t.Errorf("got  %v\nwant %s", err, want)
+	}
+}
@@ -0,0 +1,276 @@
+package classfile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.10
+//
+//	SourceFile_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 sourcefile_index;
+//	}
+type SourceFileAttribute struct {
+	AttributeHeader
+	SourceFileIndex uint16
+	SourceFile      string
+}
+
+func (self SourceFileAttribute) String() string {
+	return fmt.Sprintf("SourceFile: #%d // %s", self.SourceFileIndex, self.SourceFile)
+}
+
+func (self ClassFileParser) parseSourceFileAttribute() (SourceFileAttribute, error) {
+	index, err := self.reader.ReadU2()
+	if err != nil {
+		return SourceFileAttribute{}, within(err, "sourcefile_index")
+	}
+	name, err := self.utf8(index)
+	if err != nil {
+		return SourceFileAttribute{}, within(err, "sourcefile_index")
+	}
+	return SourceFileAttribute{self.header, index, name}, nil
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.12
+//
+//	LineNumberTable_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 line_number_table_length;
+//	    {   u2 start_pc;
+//	        u2 line_number;
+//	    } line_number_table[line_number_table_length];
+//	}
+type LineNumberTableAttribute struct {
+	AttributeHeader
+	LineNumberTable []LineNumberTableEntry
+}
+
+type LineNumberTableEntry struct {
+	StartPc    uint16
+	LineNumber uint16
+}
+
+func (self LineNumberTableAttribute) String() string {
+	lines := []string{}
+	for _, entry := range self.LineNumberTable {
+		lines = append(lines, fmt.Sprintf("line %d: %d", entry.LineNumber, entry.StartPc))
+	}
+	return fmt.Sprintf("LineNumberTable: [%s]", strings.Join(lines, ", "))
+}
+
+func (self ClassFileParser) parseLineNumberTableAttribute() (LineNumberTableAttribute, error) {
+	length, err := self.reader.ReadU2()
+	if err != nil {
+		return LineNumberTableAttribute{}, within(err, "line_number_table_length")
+	}
+	table := make([]LineNumberTableEntry, length)
+	for i := range table {
+		entry := &table[i]
+		for _, field := range []*uint16{&entry.StartPc, &entry.LineNumber} {
+			if *field, err = self.reader.ReadU2(); err != nil {
+				return LineNumberTableAttribute{}, within(err, "line_number_table[%d]", i)
+			}
+		}
+	}
+	return LineNumberTableAttribute{self.header, table}, nil
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.13
+//
+//	LocalVariableTable_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 local_variable_table_length;
+//	    {   u2 start_pc;
+//	        u2 length;
+//	        u2 name_index;
+//	        u2 descriptor_index;
+//	        u2 index;
+//	    } local_variable_table[local_variable_table_length];
+//	}
+type LocalVariableTableAttribute struct {
+	AttributeHeader
+	LocalVariableTable []LocalVariableTableEntry
+}
+
+type LocalVariableTableEntry struct {
+	StartPc         uint16
+	Length          uint16
+	NameIndex       uint16
+	DescriptorIndex uint16
+	Index           uint16
+	Name            string
+	Descriptor      string
+}
+
+func (self LocalVariableTableAttribute) String() string {
+	vars := []string{}
+	for _, entry := range self.LocalVariableTable {
+		vars = append(vars, fmt.Sprintf("%d %s %s [%d, %d)", entry.Index, entry.Name, entry.Descriptor, entry.StartPc, int(entry.StartPc)+int(entry.Length)))
+	}
+	return fmt.Sprintf("LocalVariableTable: [%s]", strings.Join(vars, ", "))
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.14
+//
+//	LocalVariableTypeTable_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 local_variable_type_table_length;
+//	    {   u2 start_pc;
+//	        u2 length;
+//	        u2 name_index;
+//	        u2 signature_index;
+//	        u2 index;
+//	    } local_variable_type_table[local_variable_type_table_length];
+//	}
+type LocalVariableTypeTableAttribute struct {
+	AttributeHeader
+	LocalVariableTypeTable []LocalVariableTypeTableEntry
+}
+
+type LocalVariableTypeTableEntry struct {
+	StartPc        uint16
+	Length         uint16
+	NameIndex      uint16
+	SignatureIndex uint16
+	Index          uint16
+	Name           string
+	Signature      string
+}
+
+func (self LocalVariableTypeTableAttribute) String() string {
+	vars := []string{}
+	for _, entry := range self.LocalVariableTypeTable {
+		vars = append(vars, fmt.Sprintf("%d %s %s [%d, %d)", entry.Index, entry.Name, entry.Signature, entry.StartPc, int(entry.StartPc)+int(entry.Length)))
+	}
+	return fmt.Sprintf("LocalVariableTypeTable: [%s]", strings.Join(vars, ", "))
+}
+
+// The two local variable tables share a layout; only the meaning of the
+// fourth field differs.
+type localVariableRow struct {
+	startPc, length, nameIndex, typeIndex, index uint16
+	name, typ                                    string
+}
+
+func (self ClassFileParser) parseLocalVariableRows(name string) ([]localVariableRow, error) {
+	length, err := self.reader.ReadU2()
+	if err != nil {
+		return nil, within(err, "%s_length", name)
+	}
+	rows := make([]localVariableRow, length)
+	for i := range rows {
+		row := &rows[i]
+		for _, field := range []*uint16{&row.startPc, &row.length, &row.nameIndex, &row.typeIndex, &row.index} {
+			if *field, err = self.reader.ReadU2(); err != nil {
+				return nil, within(err, "%s[%d]", name, i)
+			}
+		}
+		if row.name, err = self.utf8(row.nameIndex); err != nil {
+			return nil, within(err, "%s[%d].name_index", name, i)
+		}
+		if row.typ, err = self.utf8(row.typeIndex); err != nil {
+			return nil, within(err, "%s[%d]", name, i)
+		}
+	}
+	return rows, nil
+}
+
+func (self ClassFileParser) parseLocalVariableTableAttribute() (LocalVariableTableAttribute, error) {
+	rows, err := self.parseLocalVariableRows("local_variable_table")
+	if err != nil {
+		return LocalVariableTableAttribute{}, err
+	}
+	table := make([]LocalVariableTableEntry, len(rows))
+	for i, r := range rows {
+		table[i] = LocalVariableTableEntry{r.startPc, r.length, r.nameIndex, r.typeIndex, r.index, r.name, r.typ}
+	}
+	return LocalVariableTableAttribute{self.header, table}, nil
+}
+
+func (self ClassFileParser) parseLocalVariableTypeTableAttribute() (LocalVariableTypeTableAttribute, error) {
+	rows, err := self.parseLocalVariableRows("local_variable_type_table")
+	if err != nil {
+		return LocalVariableTypeTableAttribute{}, err
+	}
+	table := make([]LocalVariableTypeTableEntry, len(rows))
+	for i, r := range rows {
+		table[i] = LocalVariableTypeTableEntry{r.startPc, r.length, r.nameIndex, r.typeIndex, r.index, r.name, r.typ}
+	}
+	return LocalVariableTypeTableAttribute{self.header, table}, nil
+}
+
+// LineNumber returns the source line of the instruction at pc, i.e. the line of
+// the entry with the greatest start_pc not after pc. A method may carry several
+// LineNumberTable attributes; they are searched together.
+func (self CodeAttribute) LineNumber(pc uint16) (int, bool) {
+	line, best, found := 0, uint16(0), false
+	for _, attr := range self.Attributes {
+		table, ok := attr.(LineNumberTableAttribute)
+		if !ok {
+			continue
+		}
+		for _, entry := range table.LineNumberTable {
+			if entry.StartPc <= pc && (!found || entry.StartPc >= best) {
+				line, best, found = int(entry.LineNumber), entry.StartPc, true
+			}
+		}
+	}
+	return line, found
+}
+
+// LocalVariable merges what LocalVariableTable and LocalVariableTypeTable say
+// about one local variable.
+type LocalVariable struct {
+	Index      uint16
+	Name       string
+	Descriptor string
+	// Signature is the generic signature, empty unless the variable has a
+	// parameterized type.
+	Signature string
+	StartPc   uint16
+	Length    uint16
+}
+
+// LocalVariablesAt returns the local variables live at pc, that is those whose
+// range [start_pc, start_pc+length) contains it, ordered by slot.
+func (self CodeAttribute) LocalVariablesAt(pc uint16) []LocalVariable {
+	live := func(startPc, length uint16) bool {
+		return startPc <= pc && int(pc) < int(startPc)+int(length)
+	}
+	vars := []LocalVariable{}
+	signatures := map[uint16]string{}
+	for _, attr := range self.Attributes {
+		switch table := attr.(type) {
+		case LocalVariableTableAttribute:
+			for _, e := range table.LocalVariableTable {
+				if live(e.StartPc, e.Length) {
+					vars = append(vars, LocalVariable{e.Index, e.Name, e.Descriptor, "", e.StartPc, e.Length})
+				}
+			}
+		case LocalVariableTypeTableAttribute:
+			for _, e := range table.LocalVariableTypeTable {
+				if live(e.StartPc, e.Length) {
+					signatures[e.Index] = e.Signature
+				}
+			}
+		}
+	}
+	for i := range vars {
+		vars[i].Signature = signatures[vars[i].Index]
+	}
+	sort.SliceStable(vars, func(i, j int) bool { return vars[i].Index < vars[j].Index })
+	return vars
+}
+
+// SourceFile returns the name recorded in the class's SourceFile attribute.
+func (self ClassFile) SourceFile() (string, bool) {
+	sf, ok := FindAttribute[SourceFileAttribute](self.Attributes)
+	return sf.SourceFile, ok
+}
@@ -0,0 +1,33 @@
+package classfile
+
+import (
+	"testing"
+)
+
+func TestLocalVariablesAt(t *testing.T) {
+	code := CodeAttribute{Attributes: []AttributeInfo{
+		LocalVariableTableAttribute{AttributeHeader{}, []LocalVariableTableEntry{
+			{StartPc: 4, Length: 10, Index: 2, Name: "list", Descriptor: "Ljava/util/List;"},
+			{StartPc: 0, Length: 20, Index: 0, Name: "args", Descriptor: "[Ljava/lang/String;"},
+			{StartPc: 14, Length: 6, Index: 2, Name: "n", Descriptor: "I"},
+		}},
+		LocalVariableTypeTableAttribute{AttributeHeader{}, []LocalVariableTypeTableEntry{
+			{StartPc: 4, Length: 10, Index: 2, Name: "list", Signature: "Ljava/util/List<Ljava/lang/String;>;"},
+		}},
+	}}
+
+	vars := code.LocalVariablesAt(13)
+	if len(vars) != 2 || vars[0].Name != "args" || vars[1].Name != "list" {
+		t.Fatalf("pc 13: got %v", vars)
+	}
+	if vars[1].Signature != "Ljava/util/List<Ljava/lang/String;>;" {
+		t.Errorf("pc 13: signature %q", vars[1].Signature)
+	}
+	vars = code.LocalVariablesAt(14)
+	if len(vars) != 2 || vars[1].Name != "n" || vars[1].Signature != "" {
+		t.Errorf("pc 14: got %v", vars)
+	}
+	if vars := code.LocalVariablesAt(20); len(vars) != 0 {
+		t.Errorf("pc 20: got %v", vars)
+	}
+}
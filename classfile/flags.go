@@ -0,0 +1,93 @@
+package classfile
+
+// AccessFlag names one bit of an access_flags item: its JVMS name and the
+// Java modifier it stands for, or "" when it has none. The tables below list
+// the flags allowed in each position, in the order javap prints them.
+type AccessFlag struct {
+	Mask     uint16
+	Name     string
+	Modifier string
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.1-200-E.1
+var ClassFlagTable = []AccessFlag{
+	{ACC_PUBLIC, "ACC_PUBLIC", "public"},
+	{ACC_FINAL, "ACC_FINAL", "final"},
+	{ACC_SUPER, "ACC_SUPER", ""},
+	{ACC_INTERFACE, "ACC_INTERFACE", ""},
+	{ACC_ABSTRACT, "ACC_ABSTRACT", "abstract"},
+	{ACC_SYNTHETIC, "ACC_SYNTHETIC", ""},
+	{ACC_ANNOTATION, "ACC_ANNOTATION", ""},
+	{ACC_ENUM, "ACC_ENUM", ""},
+	{ACC_MODULE, "ACC_MODULE", ""},
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.5-200-A.1
+var FieldFlagTable = []AccessFlag{
+	{ACC_PUBLIC, "ACC_PUBLIC", "public"},
+	{ACC_PRIVATE, "ACC_PRIVATE", "private"},
+	{ACC_PROTECTED, "ACC_PROTECTED", "protected"},
+	{ACC_STATIC, "ACC_STATIC", "static"},
+	{ACC_FINAL, "ACC_FINAL", "final"},
+	{ACC_VOLATILE, "ACC_VOLATILE", "volatile"},
+	{ACC_TRANSIENT, "ACC_TRANSIENT", "transient"},
+	{ACC_SYNTHETIC, "ACC_SYNTHETIC", ""},
+	{ACC_ENUM, "ACC_ENUM", ""},
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.6-200-A.1
+var MethodFlagTable = []AccessFlag{
+	{ACC_PUBLIC, "ACC_PUBLIC", "public"},
+	{ACC_PRIVATE, "ACC_PRIVATE", "private"},
+	{ACC_PROTECTED, "ACC_PROTECTED", "protected"},
+	{ACC_STATIC, "ACC_STATIC", "static"},
+	{ACC_FINAL, "ACC_FINAL", "final"},
+	{ACC_SYNCHRONIZED, "ACC_SYNCHRONIZED", "synchronized"},
+	{ACC_BRIDGE, "ACC_BRIDGE", ""},
+	{ACC_VARARGS, "ACC_VARARGS", ""},
+	{ACC_NATIVE, "ACC_NATIVE", "native"},
+	{ACC_ABSTRACT, "ACC_ABSTRACT", "abstract"},
+	{ACC_STRICT, "ACC_STRICT", "strictfp"},
+	{ACC_SYNTHETIC, "ACC_SYNTHETIC", ""},
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.6-300-D.1-D.1
+var InnerClassFlagTable = []AccessFlag{
+	{ACC_PUBLIC, "ACC_PUBLIC", "public"},
+	{ACC_PRIVATE, "ACC_PRIVATE", "private"},
+	{ACC_PROTECTED, "ACC_PROTECTED", "protected"},
+	{ACC_STATIC, "ACC_STATIC", "static"},
+	{ACC_FINAL, "ACC_FINAL", "final"},
+	{ACC_INTERFACE, "ACC_INTERFACE", ""},
+	{ACC_ABSTRACT, "ACC_ABSTRACT", "abstract"},
+	{ACC_SYNTHETIC, "ACC_SYNTHETIC", ""},
+	{ACC_ANNOTATION, "ACC_ANNOTATION", ""},
+	{ACC_ENUM, "ACC_ENUM", ""},
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.24
+var ParameterFlagTable = []AccessFlag{
+	{ACC_FINAL, "ACC_FINAL", "final"},
+	{ACC_SYNTHETIC, "ACC_SYNTHETIC", ""},
+	{ACC_MANDATED, "ACC_MANDATED", ""},
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.25
+var ModuleFlagTable = []AccessFlag{
+	{ACC_OPEN, "ACC_OPEN", "open"},
+	{ACC_SYNTHETIC, "ACC_SYNTHETIC", ""},
+	{ACC_MANDATED, "ACC_MANDATED", ""},
+}
+
+var RequiresFlagTable = []AccessFlag{
+	{ACC_TRANSITIVE, "ACC_TRANSITIVE", "transitive"},
+	{ACC_STATIC_PHASE, "ACC_STATIC_PHASE", "static"},
+	{ACC_SYNTHETIC, "ACC_SYNTHETIC", ""},
+	{ACC_MANDATED, "ACC_MANDATED", ""},
+}
+
+// ExportsFlagTable are the flags of both exports and opens.
+var ExportsFlagTable = []AccessFlag{
+	{ACC_SYNTHETIC, "ACC_SYNTHETIC", ""},
+	{ACC_MANDATED, "ACC_MANDATED", ""},
+}
@@ -0,0 +1,382 @@
+package classfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"gjvm/bytecode"
+)
+
+// The JSON form of a class file is meant for tools. Field names follow the Go
+// types, every reference carries both its raw constant pool index and what it
+// resolves to, flags are arrays of their JVMS names and attributes are tagged
+// with the name they have in the class file:
+//
+//	{"Name": "SourceFile", "Info": {"SourceFileIndex": 24, "SourceFile": "Hello.java"}}
+//
+// A reference that does not resolve is encoded with an empty name rather than
+// failing, so that damaged classes can still be dumped.
+
+// flagNames lists the flags set in flags. Bits without a name in table are
+// kept as a hex number, so the array always accounts for every bit.
+func flagNames(flags uint16, table []AccessFlag) []string {
+	names := []string{}
+	for _, f := range table {
+		if flags&f.Mask != 0 {
+			names = append(names, f.Name)
+			flags &^= f.Mask
+		}
+	}
+	if flags != 0 {
+		names = append(names, fmt.Sprintf("0x%04x", flags))
+	}
+	return names
+}
+
+type jsonClassFile struct {
+	MinorVersion uint16
+	MajorVersion uint16
+	ConstantPool []jsonConstant
+	AccessFlags  []string
+	ThisClass    jsonClassRef
+	// SuperClass is null for java.lang.Object and module-info.
+	SuperClass *jsonClassRef
+	Interfaces []jsonClassRef
+	Fields     []jsonMember
+	Methods    []jsonMember
+	Attributes []jsonAttribute
+}
+
+type jsonClassRef struct {
+	Index uint16
+	Name  string
+}
+
+type jsonConstant struct {
+	Index            uint16
+	Tag              string
+	NameIndex        uint16  `json:",omitempty"`
+	DescriptorIndex  uint16  `json:",omitempty"`
+	ClassIndex       uint16  `json:",omitempty"`
+	NameAndTypeIndex uint16  `json:",omitempty"`
+	StringIndex      uint16  `json:",omitempty"`
+	ReferenceKind    string  `json:",omitempty"`
+	ReferenceIndex   uint16  `json:",omitempty"`
+	BootstrapMethod  *uint16 `json:",omitempty"`
+	// Value is what the entry resolves to: a name, "class.name:descriptor"
+	// for member references, or the literal of a numeric constant.
+	Value string
+}
+
+type jsonMember struct {
+	AccessFlags     []string
+	NameIndex       uint16
+	Name            string
+	DescriptorIndex uint16
+	Descriptor      string
+	Attributes      []jsonAttribute
+}
+
+type jsonAttribute struct {
+	Name string
+	Info any
+}
+
+type jsonCode struct {
+	MaxStack     uint16
+	MaxLocals    uint16
+	Code         []byte
+	Instructions []jsonInstruction
+	// InstructionError is set when Code does not decode; Instructions then
+	// holds what came before the bad instruction.
+	InstructionError string `json:",omitempty"`
+	ExceptionTable   []jsonExceptionHandler
+	Attributes       []jsonAttribute
+}
+
+// jsonInstruction is an instruction with the constant it refers to, if any,
+// resolved as in the constant pool's Value.
+type jsonInstruction struct {
+	bytecode.Instruction
+	Constant string
+}
+
+func (self jsonInstruction) MarshalJSON() ([]byte, error) {
+	b, err := self.Instruction.MarshalJSON()
+	if err != nil || self.Constant == "" {
+		return b, err
+	}
+	constant, err := marshalJSON(self.Constant)
+	if err != nil {
+		return nil, err
+	}
+	// append "Constant" to the instruction's object
+	b = append(b[:len(b)-1], `,"Constant":`...)
+	return append(append(b, constant...), '}'), nil
+}
+
+type jsonExceptionHandler struct {
+	StartPc   uint16
+	EndPc     uint16
+	HandlerPc uint16
+	CatchType uint16
+	// CatchClass is empty for a handler that catches everything.
+	CatchClass string
+}
+
+type jsonRecordComponent struct {
+	NameIndex       uint16
+	Name            string
+	DescriptorIndex uint16
+	Descriptor      string
+	Attributes      []jsonAttribute
+}
+
+// MarshalJSON encodes the class in the JSON form described above.
+func (self ClassFile) MarshalJSON() ([]byte, error) {
+	cp := self.ConstantPool
+	class := jsonClassFile{
+		MinorVersion: self.MinorVersion,
+		MajorVersion: self.MajorVersion,
+		ConstantPool: []jsonConstant{},
+		AccessFlags:  flagNames(uint16(self.AccessFlags), ClassFlagTable),
+		ThisClass:    jsonClassRef{self.ThisClass, cp.jsonClassName(self.ThisClass)},
+		Interfaces:   []jsonClassRef{},
+		Fields:       []jsonMember{},
+		Methods:      []jsonMember{},
+		Attributes:   cp.jsonAttributes(self.Attributes),
+	}
+	for i := 1; i < len(cp); i++ {
+		if cp.IsUsable(uint16(i)) {
+			class.ConstantPool = append(class.ConstantPool, cp.jsonConstant(uint16(i)))
+		}
+	}
+	if self.SuperClass != 0 {
+		class.SuperClass = &jsonClassRef{self.SuperClass, cp.jsonClassName(self.SuperClass)}
+	}
+	for _, index := range self.Interfaces {
+		class.Interfaces = append(class.Interfaces, jsonClassRef{index, cp.jsonClassName(index)})
+	}
+	for _, field := range self.Fields {
+		class.Fields = append(class.Fields, jsonMember{
+			AccessFlags:     flagNames(uint16(field.AccessFlags), FieldFlagTable),
+			NameIndex:       field.NameIndex,
+			Name:            cp.jsonUtf8(field.NameIndex),
+			DescriptorIndex: field.DescriptorIndex,
+			Descriptor:      cp.jsonUtf8(field.DescriptorIndex),
+			Attributes:      cp.jsonAttributes(field.Attributes),
+		})
+	}
+	for _, method := range self.Methods {
+		class.Methods = append(class.Methods, jsonMember{
+			AccessFlags:     flagNames(uint16(method.AccessFlags), MethodFlagTable),
+			NameIndex:       method.NameIndex,
+			Name:            cp.jsonUtf8(method.NameIndex),
+			DescriptorIndex: method.DescriptorIndex,
+			Descriptor:      cp.jsonUtf8(method.DescriptorIndex),
+			Attributes:      cp.jsonAttributes(method.Attributes),
+		})
+	}
+	return marshalJSON(class)
+}
+
+// marshalJSON is json.Marshal without the escaping of <, > and &, which
+// would turn every "<init>" into "\u003cinit\u003e".
+func marshalJSON(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// jsonUtf8, jsonClassName and jsonNameAndType are the lookups of
+// constant_pool.go, with "" for an entry that does not resolve.
+func (self ConstantPool) jsonUtf8(index uint16) string {
+	value, _ := self.utf8(index)
+	return value
+}
+
+func (self ConstantPool) jsonClassName(index uint16) string {
+	name, _ := self.className(index)
+	return name
+}
+
+func (self ConstantPool) jsonNameAndType(index uint16) string {
+	name, descriptor, err := self.nameAndType(index)
+	if err != nil {
+		return ""
+	}
+	return name + ":" + descriptor
+}
+
+func (self ConstantPool) jsonMemberRef(classIndex, nameAndTypeIndex uint16) string {
+	return self.jsonClassName(classIndex) + "." + self.jsonNameAndType(nameAndTypeIndex)
+}
+
+func (self ConstantPool) jsonConstant(index uint16) jsonConstant {
+	c := jsonConstant{Index: index}
+	switch info := self[index].(type) {
+	case *ConstantClassInfo:
+		c.Tag, c.NameIndex, c.Value = "Class", info.NameIndex, self.jsonUtf8(info.NameIndex)
+	case *ConstantFieldrefInfo:
+		c.Tag, c.ClassIndex, c.NameAndTypeIndex = "Fieldref", info.ClassIndex, info.NameAndTypeIndex
+		c.Value = self.jsonMemberRef(info.ClassIndex, info.NameAndTypeIndex)
+	case *ConstantMethodrefInfo:
+		c.Tag, c.ClassIndex, c.NameAndTypeIndex = "Methodref", info.ClassIndex, info.NameAndTypeIndex
+		c.Value = self.jsonMemberRef(info.ClassIndex, info.NameAndTypeIndex)
+	case *ConstantInterfaceMethodrefInfo:
+		c.Tag, c.ClassIndex, c.NameAndTypeIndex = "InterfaceMethodref", info.ClassIndex, info.NameAndTypeIndex
+		c.Value = self.jsonMemberRef(info.ClassIndex, info.NameAndTypeIndex)
+	case *ConstantStringInfo:
+		c.Tag, c.StringIndex, c.Value = "String", info.StringIndex, self.jsonUtf8(info.StringIndex)
+	case *ConstantIntegerInfo:
+		c.Tag, c.Value = "Integer", strconv.Itoa(int(info.Value()))
+	case *ConstantFloatInfo:
+		c.Tag, c.Value = "Float", FormatJavaFloat(float64(info.Value()), 32)
+	case *ConstantLongInfo:
+		c.Tag, c.Value = "Long", strconv.FormatInt(info.Value(), 10)
+	case *ConstantDoubleInfo:
+		c.Tag, c.Value = "Double", FormatJavaFloat(info.Value(), 64)
+	case *ConstantNameAndTypeInfo:
+		c.Tag, c.NameIndex, c.DescriptorIndex = "NameAndType", info.NameIndex, info.DescriptorIndex
+		c.Value = self.jsonNameAndType(index)
+	case *ConstantUtf8Info:
+		c.Tag, c.Value = "Utf8", info.Value()
+	case *ConstantMethodHandleInfo:
+		c.Tag, c.ReferenceKind, c.ReferenceIndex = "MethodHandle", info.ReferenceKindName(), info.ReferenceIndex
+		if self.IsUsable(info.ReferenceIndex) {
+			switch ref := self[info.ReferenceIndex].(type) {
+			case *ConstantFieldrefInfo:
+				c.Value = self.jsonMemberRef(ref.ClassIndex, ref.NameAndTypeIndex)
+			case *ConstantMethodrefInfo:
+				c.Value = self.jsonMemberRef(ref.ClassIndex, ref.NameAndTypeIndex)
+			case *ConstantInterfaceMethodrefInfo:
+				c.Value = self.jsonMemberRef(ref.ClassIndex, ref.NameAndTypeIndex)
+			}
+		}
+	case *ConstantMethodTypeInfo:
+		c.Tag, c.DescriptorIndex, c.Value = "MethodType", info.DescriptorIndex, self.jsonUtf8(info.DescriptorIndex)
+	case *ConstantDynamicInfo:
+		c.Tag, c.BootstrapMethod, c.NameAndTypeIndex = "Dynamic", &info.BootstrapMethodAttrIndex, info.NameAndTypeIndex
+		c.Value = self.jsonNameAndType(info.NameAndTypeIndex)
+	case *ConstantInvokeDynamicInfo:
+		c.Tag, c.BootstrapMethod, c.NameAndTypeIndex = "InvokeDynamic", &info.BootstrapMethodAttrIndex, info.NameAndTypeIndex
+		c.Value = self.jsonNameAndType(info.NameAndTypeIndex)
+	case *ConstantModuleInfo:
+		c.Tag, c.NameIndex, c.Value = "Module", info.NameIndex, self.jsonUtf8(info.NameIndex)
+	case *ConstantPackageInfo:
+		c.Tag, c.NameIndex, c.Value = "Package", info.NameIndex, self.jsonUtf8(info.NameIndex)
+	}
+	return c
+}
+
+func (self ConstantPool) jsonAttributes(attrs []AttributeInfo) []jsonAttribute {
+	out := []jsonAttribute{}
+	for _, attr := range attrs {
+		out = append(out, jsonAttribute{AttributeName(attr), self.jsonAttributeInfo(attr)})
+	}
+	return out
+}
+
+// jsonAttributeInfo handles the attributes that hold attributes or flags of
+// their own; the rest are encoded as they are.
+func (self ConstantPool) jsonAttributeInfo(attr AttributeInfo) any {
+	switch attr := attr.(type) {
+	case CodeAttribute:
+		code := jsonCode{
+			MaxStack:       attr.MaxStack,
+			MaxLocals:      attr.MaxLocals,
+			Code:           attr.Code,
+			ExceptionTable: []jsonExceptionHandler{},
+			Attributes:     self.jsonAttributes(attr.Attributes),
+		}
+		insns, err := bytecode.DecodeAll(attr.Code)
+		code.Instructions = []jsonInstruction{}
+		for _, insn := range insns {
+			i := jsonInstruction{Instruction: insn}
+			if index, ok := insn.ConstantIndex(); ok && self.IsUsable(index) {
+				i.Constant = self.jsonConstant(index).Value
+			}
+			code.Instructions = append(code.Instructions, i)
+		}
+		if err != nil {
+			code.InstructionError = err.Error()
+		}
+		for _, e := range attr.ExceptionTable {
+			code.ExceptionTable = append(code.ExceptionTable, jsonExceptionHandler{
+				e.StartPc, e.EndPc, e.HandlerPc, e.CatchType, self.jsonClassName(e.CatchType),
+			})
+		}
+		return code
+	case RecordAttribute:
+		components := []jsonRecordComponent{}
+		for _, c := range attr.Components {
+			components = append(components, jsonRecordComponent{
+				c.NameIndex, c.Name, c.DescriptorIndex, c.Descriptor, self.jsonAttributes(c.Attributes),
+			})
+		}
+		return struct{ Components []jsonRecordComponent }{components}
+	case ModuleAttribute:
+		// ModuleFlags is encoded as names here rather than by a method, which
+		// ModuleDescriptor would inherit.
+		return struct {
+			ModuleAttribute
+			ModuleFlags []string
+		}{attr, flagNames(uint16(attr.ModuleFlags), ModuleFlagTable)}
+	}
+	return attr
+}
+
+func (self InnerClassEntry) MarshalJSON() ([]byte, error) {
+	type plain InnerClassEntry
+	return marshalJSON(struct {
+		plain
+		InnerClassAccessFlags []string
+	}{plain(self), flagNames(uint16(self.InnerClassAccessFlags), InnerClassFlagTable)})
+}
+
+func (self MethodParameter) MarshalJSON() ([]byte, error) {
+	type plain MethodParameter
+	return marshalJSON(struct {
+		plain
+		AccessFlags []string
+	}{plain(self), flagNames(uint16(self.AccessFlags), ParameterFlagTable)})
+}
+
+func (self ModuleRequires) MarshalJSON() ([]byte, error) {
+	type plain ModuleRequires
+	return marshalJSON(struct {
+		plain
+		RequiresFlags []string
+	}{plain(self), flagNames(uint16(self.RequiresFlags), RequiresFlagTable)})
+}
+
+func (self ModuleExports) MarshalJSON() ([]byte, error) {
+	type plain ModuleExports
+	return marshalJSON(struct {
+		plain
+		Flags []string
+	}{plain(self), flagNames(uint16(self.Flags), ExportsFlagTable)})
+}
+
+// MarshalJSON encodes floating-point values as Java literals, since JSON has
+// no NaN or infinities.
+func (self ConstElementValue) MarshalJSON() ([]byte, error) {
+	value := self.Value
+	switch v := self.Value.(type) {
+	case float32:
+		value = FormatJavaFloat(float64(v), 32)
+	case float64:
+		value = FormatJavaFloat(v, 64)
+	}
+	return marshalJSON(struct {
+		ValueTag        string
+		ConstValueIndex uint16
+		Value           any
+	}{string(rune(self.ValueTag)), self.ConstValueIndex, value})
+}
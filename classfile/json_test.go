@@ -0,0 +1,106 @@
+package classfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	cf, err := NewClassFileParser(bytes.NewReader(readHello(t))).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(b) {
+		t.Fatalf("invalid JSON: %s", b)
+	}
+	for _, want := range []string{
+		`"AccessFlags":["ACC_PUBLIC","ACC_SUPER"]`,
+		`"ThisClass":{"Index":21,"Name":"Hello"}`,
+		`{"Index":7,"Tag":"Fieldref","ClassIndex":8,"NameAndTypeIndex":9,"Value":"java/lang/System.out:Ljava/io/PrintStream;"}`,
+		`"AccessFlags":["ACC_PUBLIC","ACC_STATIC"],"NameIndex":25,"Name":"main"`,
+		`{"PC":3,"Opcode":"ldc","Index":13,"Constant":"Hello world"}`,
+		`{"Name":"SourceFile","Info":{"SourceFileIndex":28,"SourceFile":"Hello.java"}}`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("JSON lacks %s\ngot %s", want, b)
+		}
+	}
+
+	// the encoding is stable
+	again, err := json.Marshal(cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, again) {
+		t.Error("two encodings of the same class differ")
+	}
+}
+
+func TestMarshalJSONAttributes(t *testing.T) {
+	attrs := []AttributeInfo{
+		InnerClassesAttribute{Classes: []InnerClassEntry{{InnerClassInfoIndex: 1, InnerClass: "A$B", InnerClassAccessFlags: ACC_PRIVATE | ACC_STATIC}}},
+		MethodParametersAttribute{Parameters: []MethodParameter{{Name: "x", AccessFlags: ACC_FINAL | 0x8000}}},
+		NotImplementedAttributeInfo{AttributeHeader{}, "Custom", 1, []byte{1}},
+	}
+	b, err := json.Marshal(ConstantPool{nil}.jsonAttributes(attrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"Name":"InnerClasses","Info":{"Classes":[{"InnerClassInfoIndex":1,"InnerClass":"A$B","OuterClassInfoIndex":0,"OuterClass":"","InnerNameIndex":0,"InnerName":"","InnerClassAccessFlags":["ACC_PRIVATE","ACC_STATIC"]}]}},` +
+		`{"Name":"MethodParameters","Info":{"Parameters":[{"NameIndex":0,"Name":"x","AccessFlags":["ACC_FINAL","ACC_MANDATED"]}]}},` +
+		`{"Name":"Custom","Info":{"AttributeName":"Custom","AttributeLength":1,"Info":"AQ=="}}]`
+	if string(b) != want {
+		t.Errorf("got  %s\nwant %s", b, want)
+	}
+}
+
+func TestFlagNames(t *testing.T) {
+	got := flagNames(ACC_PUBLIC|ACC_FINAL|0x0100, ClassFlagTable)
+	want := []string{"ACC_PUBLIC", "ACC_FINAL", "0x0100"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestMarshalJSONMethodHandle(t *testing.T) {
+	cp := ConstantPool{
+		nil,
+		&ConstantMethodHandleInfo{6, 1}, // refers to itself
+		&ConstantMethodHandleInfo{6, 3},
+		&ConstantMethodrefInfo{4, 6},
+		&ConstantClassInfo{5},
+		utf8Info("A"),
+		&ConstantNameAndTypeInfo{7, 8},
+		utf8Info("f"),
+		utf8Info("()V"),
+		&ConstantMethodHandleInfo{6, 2},
+	}
+	for index, want := range map[uint16]string{1: "", 2: "A.f:()V", 9: ""} {
+		if got := cp.jsonConstant(index).Value; got != want {
+			t.Errorf("#%d: got %q, want %q", index, got, want)
+		}
+	}
+}
+
+func TestMarshalJSONUnresolvable(t *testing.T) {
+	cp := ConstantPool{
+		nil,
+		&ConstantClassInfo{9},
+		&ConstantFieldrefInfo{1, 4},
+		&ConstantStringInfo{1},
+		&ConstantNameAndTypeInfo{5, 9},
+		utf8Info("x"),
+	}
+	for index, want := range map[uint16]string{1: "", 2: ".", 3: "", 4: ""} {
+		if got := cp.jsonConstant(index).Value; got != want {
+			t.Errorf("#%d: got %q, want %q", index, got, want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package classfile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.5
+//
+//	Exceptions_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 number_of_exceptions;
+//	    u2 exception_index_table[number_of_exceptions];
+//	}
+type ExceptionsAttribute struct {
+	AttributeHeader
+	ExceptionIndexTable []uint16
+	Exceptions          []string
+}
+
+func (self ExceptionsAttribute) String() string {
+	return fmt.Sprintf("Exceptions: [%s]", strings.Join(self.Exceptions, ", "))
+}
+
+func (self ClassFileParser) parseExceptionsAttribute() (ExceptionsAttribute, error) {
+	indices, names, err := self.parseIndexList("number_of_exceptions", "exception_index_table", self.className)
+	return ExceptionsAttribute{self.header, indices, names}, err
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.24
+//
+//	MethodParameters_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u1 parameters_count;
+//	    {   u2 name_index;
+//	        u2 access_flags;
+//	    } parameters[parameters_count];
+//	}
+type MethodParametersAttribute struct {
+	AttributeHeader
+	Parameters []MethodParameter
+}
+
+// MethodParameter is one formal parameter. Name is empty for a parameter
+// without a name, i.e. name_index 0. The only meaningful flags are
+// ACC_FINAL, ACC_SYNTHETIC and ACC_MANDATED.
+type MethodParameter struct {
+	NameIndex   uint16
+	Name        string
+	AccessFlags AccessFlags
+}
+
+func (self MethodParameter) IsMandated() bool {
+	return self.AccessFlags&ACC_MANDATED == ACC_MANDATED
+}
+
+func (self MethodParameter) String() string {
+	name := self.Name
+	if name == "" {
+		name = "<no name>"
+	}
+	flags := []string{}
+	if self.AccessFlags.IsFinal() {
+		flags = append(flags, "final")
+	}
+	if self.AccessFlags.IsSynthetic() {
+		flags = append(flags, "synthetic")
+	}
+	if self.IsMandated() {
+		flags = append(flags, "mandated")
+	}
+	return strings.Join(append(flags, name), " ")
+}
+
+func (self MethodParametersAttribute) String() string {
+	params := []string{}
+	for _, p := range self.Parameters {
+		params = append(params, p.String())
+	}
+	return fmt.Sprintf("MethodParameters: [%s]", strings.Join(params, ", "))
+}
+
+func (self ClassFileParser) parseMethodParametersAttribute() (MethodParametersAttribute, error) {
+	count, err := self.reader.ReadU1()
+	if err != nil {
+		return MethodParametersAttribute{}, within(err, "parameters_count")
+	}
+	params := make([]MethodParameter, count)
+	for i := range params {
+		p := &params[i]
+		var flags uint16
+		for _, field := range []*uint16{&p.NameIndex, &flags} {
+			if *field, err = self.reader.ReadU2(); err != nil {
+				return MethodParametersAttribute{}, within(err, "parameters[%d]", i)
+			}
+		}
+		p.AccessFlags = AccessFlags(flags)
+		if p.Name, err = self.optionalUtf8(p.NameIndex); err != nil {
+			return MethodParametersAttribute{}, within(err, "parameters[%d].name_index", i)
+		}
+	}
+	return MethodParametersAttribute{self.header, params}, nil
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.15
+//
+//	Deprecated_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	}
+type DeprecatedAttribute struct {
+	AttributeHeader
+}
+
+func (self DeprecatedAttribute) String() string {
+	return "Deprecated: true"
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.8
+//
+//	Synthetic_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	}
+type SyntheticAttribute struct {
+	AttributeHeader
+}
+
+func (self SyntheticAttribute) String() string {
+	return "Synthetic: true"
+}
+
+// IsDeprecated reports whether a class, field or method carries the
+// Deprecated attribute.
+func IsDeprecated(attributes []AttributeInfo) bool {
+	_, ok := FindAttribute[DeprecatedAttribute](attributes)
+	return ok
+}
+
+// IsSynthetic reports whether a class, field or method carries the Synthetic
+// attribute, which older compilers emit instead of setting ACC_SYNTHETIC.
+func IsSynthetic(attributes []AttributeInfo) bool {
+	_, ok := FindAttribute[SyntheticAttribute](attributes)
+	return ok
+}
+
+// Exceptions returns the checked exceptions the method declares to throw.
+func (m MethodInfo) Exceptions() []string {
+	attr, _ := FindAttribute[ExceptionsAttribute](m.Attributes)
+	return attr.Exceptions
+}
+
+// Parameters returns the method's MethodParameters, present in classes
+// compiled with -parameters.
+func (m MethodInfo) Parameters() ([]MethodParameter, bool) {
+	attr, ok := FindAttribute[MethodParametersAttribute](m.Attributes)
+	return attr.Parameters, ok
+}
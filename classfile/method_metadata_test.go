@@ -0,0 +1,41 @@
+package classfile
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseMethodMetadata(t *testing.T) {
+	cp := ConstantPool{
+		nil,
+		utf8Info("java/io/IOException"), &ConstantClassInfo{1}, // #1, #2
+		utf8Info("this$0"), // #3
+		utf8Info("value"),  // #4
+	}
+	parse := func(b []byte) ClassFileParser {
+		return ClassFileParser{reader: newClassReader(bytes.NewReader(b)), cp: cp}
+	}
+
+	exceptions, err := parse([]byte{0, 1, 0, 2}).parseExceptionsAttribute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := parse([]byte{3, 0, 3, 0x90, 0x10, 0, 4, 0, 0x10, 0, 0, 0, 0}).parseMethodParametersAttribute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := params.String(), "MethodParameters: [final synthetic mandated this$0, final value, <no name>]"; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	method := MethodInfo{Attributes: []AttributeInfo{exceptions, params, DeprecatedAttribute{}}}
+	if got := method.Exceptions(); len(got) != 1 || got[0] != "java/io/IOException" {
+		t.Errorf("Exceptions: got %v", got)
+	}
+	if got, ok := method.Parameters(); !ok || got[1].Name != "value" {
+		t.Errorf("Parameters: got %v", got)
+	}
+	if !IsDeprecated(method.Attributes) || IsSynthetic(method.Attributes) {
+		t.Errorf("IsDeprecated/IsSynthetic: got %v/%v", IsDeprecated(method.Attributes), IsSynthetic(method.Attributes))
+	}
+}
@@ -0,0 +1,369 @@
+package classfile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.25
+//
+//	Module_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//
+//	    u2 module_name_index;
+//	    u2 module_flags;
+//	    u2 module_version_index;
+//
+//	    u2 requires_count;
+//	    {   u2 requires_index;
+//	        u2 requires_flags;
+//	        u2 requires_version_index;
+//	    } requires[requires_count];
+//
+//	    u2 exports_count;
+//	    {   u2 exports_index;
+//	        u2 exports_flags;
+//	        u2 exports_to_count;
+//	        u2 exports_to_index[exports_to_count];
+//	    } exports[exports_count];
+//
+//	    u2 opens_count;
+//	    {   u2 opens_index;
+//	        u2 opens_flags;
+//	        u2 opens_to_count;
+//	        u2 opens_to_index[opens_to_count];
+//	    } opens[opens_count];
+//
+//	    u2 uses_count;
+//	    u2 uses_index[uses_count];
+//
+//	    u2 provides_count;
+//	    {   u2 provides_index;
+//	        u2 provides_with_count;
+//	        u2 provides_with_index[provides_with_count];
+//	    } provides[provides_count];
+//	}
+//
+// Names are resolved alongside their indices. Module names are dotted
+// ("java.base"); package and class names are in internal form ("java/util").
+type ModuleAttribute struct {
+	AttributeHeader
+	ModuleNameIndex    uint16
+	ModuleName         string
+	ModuleFlags        ModuleFlags
+	ModuleVersionIndex uint16
+	// ModuleVersion is empty when module_version_index is 0.
+	ModuleVersion string
+	Requires      []ModuleRequires
+	Exports       []ModuleExports
+	Opens         []ModuleExports
+	UsesIndex     []uint16
+	Uses          []string
+	Provides      []ModuleProvides
+}
+
+func (self ModuleAttribute) String() string {
+	return fmt.Sprintf("Module: #%d // %s, flags %v, version %q, requires=%d, exports=%d, opens=%d, uses=%d, provides=%d",
+		self.ModuleNameIndex, self.ModuleName, self.ModuleFlags, self.ModuleVersion,
+		len(self.Requires), len(self.Exports), len(self.Opens), len(self.Uses), len(self.Provides))
+}
+
+type ModuleRequires struct {
+	RequiresIndex        uint16
+	Requires             string
+	RequiresFlags        ModuleFlags
+	RequiresVersionIndex uint16
+	RequiresVersion      string
+}
+
+// ModuleExports describes an exports or opens directive. To is empty for an
+// unqualified one.
+type ModuleExports struct {
+	Index   uint16
+	Package string
+	Flags   ModuleFlags
+	ToIndex []uint16
+	To      []string
+}
+
+type ModuleProvides struct {
+	ProvidesIndex     uint16
+	Provides          string
+	ProvidesWithIndex []uint16
+	ProvidesWith      []string
+}
+
+// ModuleFlags are module_flags, requires_flags, exports_flags and opens_flags.
+// Not every flag is meaningful in every position.
+type ModuleFlags uint16
+
+const (
+	ACC_OPEN         = 0x0020 // module_flags
+	ACC_TRANSITIVE   = 0x0020 // requires_flags
+	ACC_STATIC_PHASE = 0x0040 // requires_flags
+	ACC_MANDATED     = 0x8000
+)
+
+func (self ModuleFlags) String() string {
+	return fmt.Sprintf("%#04x", uint16(self))
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.26
+//
+//	ModulePackages_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 package_count;
+//	    u2 package_index[package_count];
+//	}
+type ModulePackagesAttribute struct {
+	AttributeHeader
+	PackageIndex []uint16
+	Packages     []string
+}
+
+func (self ModulePackagesAttribute) String() string {
+	return fmt.Sprintf("ModulePackages: [%s]", strings.Join(self.Packages, ", "))
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.27
+//
+//	ModuleMainClass_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 main_class_index;
+//	}
+type ModuleMainClassAttribute struct {
+	AttributeHeader
+	MainClassIndex uint16
+	MainClass      string
+}
+
+func (self ModuleMainClassAttribute) String() string {
+	return fmt.Sprintf("ModuleMainClass: #%d // %s", self.MainClassIndex, self.MainClass)
+}
+
+// ModuleDescriptor gathers what a module-info.class says about its module.
+type ModuleDescriptor struct {
+	ModuleAttribute
+	// Packages lists every package of the module, from ModulePackages.
+	Packages []string
+	// MainClass is empty without a ModuleMainClass attribute.
+	MainClass string
+}
+
+// Module returns the module descriptor of a module-info.class.
+func (self ClassFile) Module() (*ModuleDescriptor, bool) {
+	module, ok := FindAttribute[ModuleAttribute](self.Attributes)
+	if !ok {
+		return nil, false
+	}
+	md := &ModuleDescriptor{ModuleAttribute: module}
+	if packages, ok := FindAttribute[ModulePackagesAttribute](self.Attributes); ok {
+		md.Packages = packages.Packages
+	}
+	if mainClass, ok := FindAttribute[ModuleMainClassAttribute](self.Attributes); ok {
+		md.MainClass = mainClass.MainClass
+	}
+	return md, true
+}
+
+// String describes the module the way `jar --describe-module` does.
+func (self ModuleDescriptor) String() string {
+	dotted := func(name string) string {
+		return strings.ReplaceAll(name, "/", ".")
+	}
+	lines := []string{}
+	header := self.ModuleName
+	if self.ModuleVersion != "" {
+		header += "@" + self.ModuleVersion
+	}
+	if self.ModuleFlags&ACC_OPEN != 0 {
+		header += " open"
+	}
+
+	exported := map[string]bool{}
+	for _, e := range self.Exports {
+		exported[e.Package] = true
+		if len(e.To) == 0 {
+			lines = append(lines, "exports "+dotted(e.Package))
+		}
+	}
+	for _, r := range self.Requires {
+		line := "requires " + r.Requires
+		if r.RequiresFlags&ACC_MANDATED != 0 {
+			line += " mandated"
+		}
+		if r.RequiresFlags&ACC_STATIC_PHASE != 0 {
+			line += " static"
+		}
+		if r.RequiresFlags&ACC_TRANSITIVE != 0 {
+			line += " transitive"
+		}
+		lines = append(lines, line)
+	}
+	for _, u := range self.Uses {
+		lines = append(lines, "uses "+dotted(u))
+	}
+	for _, p := range self.Provides {
+		impls := []string{}
+		for _, w := range p.ProvidesWith {
+			impls = append(impls, dotted(w))
+		}
+		lines = append(lines, "provides "+dotted(p.Provides)+" with "+strings.Join(impls, " "))
+	}
+	for _, e := range self.Exports {
+		if len(e.To) > 0 {
+			lines = append(lines, "qualified exports "+dotted(e.Package)+" to "+strings.Join(e.To, " "))
+		}
+	}
+	opened := map[string]bool{}
+	for _, o := range self.Opens {
+		opened[o.Package] = true
+		if len(o.To) == 0 {
+			lines = append(lines, "opens "+dotted(o.Package))
+		} else {
+			lines = append(lines, "qualified opens "+dotted(o.Package)+" to "+strings.Join(o.To, " "))
+		}
+	}
+	for _, p := range self.Packages {
+		if !exported[p] && !opened[p] {
+			lines = append(lines, "contains "+dotted(p))
+		}
+	}
+	if self.MainClass != "" {
+		lines = append(lines, "main-class "+dotted(self.MainClass))
+	}
+	sort.Strings(lines)
+	return header + "\n" + strings.Join(lines, "\n") + "\n"
+}
+
+func (self ClassFileParser) parseModuleAttribute() (ModuleAttribute, error) {
+	m := ModuleAttribute{AttributeHeader: self.header}
+	var err error
+	var flags uint16
+	if m.ModuleNameIndex, err = self.reader.ReadU2(); err != nil {
+		return m, within(err, "module_name_index")
+	}
+	if m.ModuleName, err = self.moduleName(m.ModuleNameIndex); err != nil {
+		return m, within(err, "module_name_index")
+	}
+	if flags, err = self.reader.ReadU2(); err != nil {
+		return m, within(err, "module_flags")
+	}
+	m.ModuleFlags = ModuleFlags(flags)
+	if m.ModuleVersionIndex, err = self.reader.ReadU2(); err != nil {
+		return m, within(err, "module_version_index")
+	}
+	if m.ModuleVersion, err = self.optionalUtf8(m.ModuleVersionIndex); err != nil {
+		return m, within(err, "module_version_index")
+	}
+
+	count, err := self.reader.ReadU2()
+	if err != nil {
+		return m, within(err, "requires_count")
+	}
+	m.Requires = make([]ModuleRequires, count)
+	for i := range m.Requires {
+		if m.Requires[i], err = self.parseModuleRequires(); err != nil {
+			return m, within(err, "requires[%d]", i)
+		}
+	}
+
+	if m.Exports, err = self.parseModuleExports("exports"); err != nil {
+		return m, err
+	}
+	if m.Opens, err = self.parseModuleExports("opens"); err != nil {
+		return m, err
+	}
+	if m.UsesIndex, m.Uses, err = self.parseIndexList("uses_count", "uses_index", self.className); err != nil {
+		return m, err
+	}
+
+	if count, err = self.reader.ReadU2(); err != nil {
+		return m, within(err, "provides_count")
+	}
+	m.Provides = make([]ModuleProvides, count)
+	for i := range m.Provides {
+		p := &m.Provides[i]
+		if p.ProvidesIndex, err = self.reader.ReadU2(); err != nil {
+			return m, within(err, "provides[%d]", i)
+		}
+		if p.Provides, err = self.className(p.ProvidesIndex); err != nil {
+			return m, within(err, "provides[%d].provides_index", i)
+		}
+		if p.ProvidesWithIndex, p.ProvidesWith, err = self.parseIndexList("provides_with_count", "provides_with_index", self.className); err != nil {
+			return m, within(err, "provides[%d]", i)
+		}
+	}
+	return m, nil
+}
+
+func (self ClassFileParser) parseModuleRequires() (ModuleRequires, error) {
+	var r ModuleRequires
+	var err error
+	var flags uint16
+	if r.RequiresIndex, err = self.reader.ReadU2(); err != nil {
+		return r, within(err, "requires_index")
+	}
+	if r.Requires, err = self.moduleName(r.RequiresIndex); err != nil {
+		return r, within(err, "requires_index")
+	}
+	if flags, err = self.reader.ReadU2(); err != nil {
+		return r, within(err, "requires_flags")
+	}
+	r.RequiresFlags = ModuleFlags(flags)
+	if r.RequiresVersionIndex, err = self.reader.ReadU2(); err != nil {
+		return r, within(err, "requires_version_index")
+	}
+	if r.RequiresVersion, err = self.optionalUtf8(r.RequiresVersionIndex); err != nil {
+		return r, within(err, "requires_version_index")
+	}
+	return r, nil
+}
+
+// parseModuleExports reads the exports or opens table, which share a layout.
+func (self ClassFileParser) parseModuleExports(name string) ([]ModuleExports, error) {
+	count, err := self.reader.ReadU2()
+	if err != nil {
+		return nil, within(err, "%s_count", name)
+	}
+	directives := make([]ModuleExports, count)
+	for i := range directives {
+		d := &directives[i]
+		var flags uint16
+		if d.Index, err = self.reader.ReadU2(); err != nil {
+			return nil, within(err, "%s[%d]", name, i)
+		}
+		if d.Package, err = self.packageName(d.Index); err != nil {
+			return nil, within(err, "%s[%d].%s_index", name, i, name)
+		}
+		if flags, err = self.reader.ReadU2(); err != nil {
+			return nil, within(err, "%s[%d]", name, i)
+		}
+		d.Flags = ModuleFlags(flags)
+		if d.ToIndex, d.To, err = self.parseIndexList(name+"_to_count", name+"_to_index", self.moduleName); err != nil {
+			return nil, within(err, "%s[%d]", name, i)
+		}
+	}
+	return directives, nil
+}
+
+func (self ClassFileParser) parseModulePackagesAttribute() (ModulePackagesAttribute, error) {
+	indices, packages, err := self.parseIndexList("package_count", "package_index", self.packageName)
+	return ModulePackagesAttribute{self.header, indices, packages}, err
+}
+
+func (self ClassFileParser) parseModuleMainClassAttribute() (ModuleMainClassAttribute, error) {
+	index, err := self.reader.ReadU2()
+	if err != nil {
+		return ModuleMainClassAttribute{}, within(err, "main_class_index")
+	}
+	name, err := self.className(index)
+	if err != nil {
+		return ModuleMainClassAttribute{}, within(err, "main_class_index")
+	}
+	return ModuleMainClassAttribute{self.header, index, name}, nil
+}
@@ -0,0 +1,63 @@
+package classfile
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseModule(t *testing.T) {
+	cp := ConstantPool{
+		nil,
+		utf8Info("com.foo"), &ConstantModuleInfo{1}, // #1, #2
+		utf8Info("1.0"),                               // #3
+		utf8Info("java.base"), &ConstantModuleInfo{4}, // #4, #5
+		utf8Info("com/foo/api"), &ConstantPackageInfo{6}, // #6, #7
+		utf8Info("com/foo/internal"), &ConstantPackageInfo{8}, // #8, #9
+		utf8Info("com.bar"), &ConstantModuleInfo{10}, // #10, #11
+		utf8Info("com/foo/spi/Service"), &ConstantClassInfo{12}, // #12, #13
+		utf8Info("com/foo/impl/Impl"), &ConstantClassInfo{14}, // #14, #15
+		utf8Info("com/foo/impl"), &ConstantPackageInfo{16}, // #16, #17
+		utf8Info("com/foo/Main"), &ConstantClassInfo{18}, // #18, #19
+	}
+	module := []byte{
+		0, 2, 0, 0, 0, 3, // com.foo@1.0
+		0, 1, 0, 5, 0x80, 0x00, 0, 0, // requires java.base mandated
+		0, 2, // exports
+		0, 7, 0, 0, 0, 0, // com.foo.api
+		0, 9, 0, 0, 0, 1, 0, 11, // com.foo.internal to com.bar
+		0, 0, // opens
+		0, 1, 0, 13, // uses
+		0, 1, 0, 13, 0, 1, 0, 15, // provides
+	}
+	packages := []byte{0, 3, 0, 7, 0, 9, 0, 17}
+	mainClass := []byte{0, 19}
+
+	parse := func(b []byte, f func(ClassFileParser) (AttributeInfo, error)) AttributeInfo {
+		attr, err := f(ClassFileParser{reader: newClassReader(bytes.NewReader(b)), cp: cp})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return attr
+	}
+	cf := ClassFile{Attributes: []AttributeInfo{
+		parse(module, func(p ClassFileParser) (AttributeInfo, error) { return p.parseModuleAttribute() }),
+		parse(packages, func(p ClassFileParser) (AttributeInfo, error) { return p.parseModulePackagesAttribute() }),
+		parse(mainClass, func(p ClassFileParser) (AttributeInfo, error) { return p.parseModuleMainClassAttribute() }),
+	}}
+	md, ok := cf.Module()
+	if !ok {
+		t.Fatal("Module() not found")
+	}
+	want := `com.foo@1.0
+contains com.foo.impl
+exports com.foo.api
+main-class com.foo.Main
+provides com.foo.spi.Service with com.foo.impl.Impl
+qualified exports com.foo.internal to com.bar
+requires java.base mandated
+uses com.foo.spi.Service
+`
+	if got := md.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
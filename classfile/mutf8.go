@@ -0,0 +1,78 @@
+package classfile
+
+import (
+	"fmt"
+	"unicode/utf16"
+)
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.4.7
+//
+// CONSTANT_Utf8 strings use Java's "modified UTF-8":
+// - U+0000 is encoded as the two bytes 0xC0 0x80, so no 0x00 byte ever appears
+// - only the 1, 2 and 3 byte forms are used
+// - supplementary characters are encoded as their UTF-16 surrogate pair, each
+//   surrogate taking 3 bytes (6 bytes in total)
+
+// ModifiedUTF8ToUTF16 decodes modified UTF-8 into UTF-16 code units, the
+// representation java.lang.String is defined in terms of.
+func ModifiedUTF8ToUTF16(b []byte) ([]uint16, error) {
+	units := make([]uint16, 0, len(b))
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c == 0:
+			return nil, fmt.Errorf("invalid modified UTF-8: NUL byte at %d", i)
+		case c < 0x80:
+			units = append(units, uint16(c))
+			i++
+		case c&0xE0 == 0xC0:
+			if i+1 >= len(b) || b[i+1]&0xC0 != 0x80 {
+				return nil, fmt.Errorf("invalid modified UTF-8: truncated 2-byte sequence at %d", i)
+			}
+			units = append(units, uint16(c&0x1F)<<6|uint16(b[i+1]&0x3F))
+			i += 2
+		case c&0xF0 == 0xE0:
+			if i+2 >= len(b) || b[i+1]&0xC0 != 0x80 || b[i+2]&0xC0 != 0x80 {
+				return nil, fmt.Errorf("invalid modified UTF-8: truncated 3-byte sequence at %d", i)
+			}
+			units = append(units, uint16(c&0x0F)<<12|uint16(b[i+1]&0x3F)<<6|uint16(b[i+2]&0x3F))
+			i += 3
+		default:
+			return nil, fmt.Errorf("invalid modified UTF-8: unexpected byte %#x at %d", c, i)
+		}
+	}
+	return units, nil
+}
+
+// DecodeModifiedUTF8 decodes modified UTF-8 into a Go string. Surrogate pairs
+// are combined into one rune; an unpaired surrogate becomes U+FFFD.
+func DecodeModifiedUTF8(b []byte) (string, error) {
+	units, err := ModifiedUTF8ToUTF16(b)
+	if err != nil {
+		return "", err
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// UTF16ToModifiedUTF8 is the inverse of ModifiedUTF8ToUTF16. Unpaired
+// surrogates are encoded as they are, like the JDK does.
+func UTF16ToModifiedUTF8(units []uint16) []byte {
+	b := make([]byte, 0, len(units))
+	for _, u := range units {
+		switch {
+		case u != 0 && u < 0x80:
+			b = append(b, byte(u))
+		case u < 0x800:
+			b = append(b, 0xC0|byte(u>>6), 0x80|byte(u&0x3F))
+		default:
+			b = append(b, 0xE0|byte(u>>12), 0x80|byte(u>>6&0x3F), 0x80|byte(u&0x3F))
+		}
+	}
+	return b
+}
+
+// EncodeModifiedUTF8 encodes s as modified UTF-8, e.g. for writing a
+// CONSTANT_Utf8 entry.
+func EncodeModifiedUTF8(s string) []byte {
+	return UTF16ToModifiedUTF8(utf16.Encode([]rune(s)))
+}
@@ -0,0 +1,50 @@
+package classfile
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestModifiedUTF8(t *testing.T) {
+	cases := []struct {
+		s string
+		b []byte
+	}{
+		{"Hello", []byte("Hello")},
+		{"a\x00b", []byte{'a', 0xC0, 0x80, 'b'}},
+		{"é", []byte{0xC3, 0xA9}},
+		{"あ", []byte{0xE3, 0x81, 0x82}},
+		// U+1F600 is the surrogate pair D83D DE00
+		{"😀", []byte{0xED, 0xA0, 0xBD, 0xED, 0xB8, 0x80}},
+	}
+	for _, c := range cases {
+		if got := EncodeModifiedUTF8(c.s); !bytes.Equal(got, c.b) {
+			t.Errorf("Encode(%q): got % x, want % x", c.s, got, c.b)
+		}
+		if got, err := DecodeModifiedUTF8(c.b); err != nil || got != c.s {
+			t.Errorf("Decode(% x): got %q, %v, want %q", c.b, got, err, c.s)
+		}
+	}
+
+	units, err := (ConstantUtf8Info{6, []byte{0xED, 0xA0, 0xBD, 0xED, 0xB8, 0x80}}).UTF16()
+	if err != nil || len(units) != 2 || units[0] != 0xD83D || units[1] != 0xDE00 {
+		t.Errorf("UTF16: got %x, %v", units, err)
+	}
+}
+
+func TestModifiedUTF8Invalid(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x00},
+		{0xC3},
+		{0xE3, 0x81},
+		{0xF0, 0x9F, 0x98, 0x80},
+		{0x80},
+	} {
+		if _, err := DecodeModifiedUTF8(b); err == nil {
+			t.Errorf("Decode(% x): expected an error", b)
+		}
+		if _, err := (ConstantUtf8Info{uint16(len(b)), b}).UTF16(); err == nil {
+			t.Errorf("UTF16(% x): expected an error", b)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package classfile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.6
+//
+//	InnerClasses_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 number_of_classes;
+//	    {   u2 inner_class_info_index;
+//	        u2 outer_class_info_index;
+//	        u2 inner_name_index;
+//	        u2 inner_class_access_flags;
+//	    } classes[number_of_classes];
+//	}
+type InnerClassesAttribute struct {
+	AttributeHeader
+	Classes []InnerClassEntry
+}
+
+// InnerClassEntry describes one class that is not a package member. OuterClass
+// is empty for local and anonymous classes and InnerName is empty for
+// anonymous ones, matching a zero index in the class file.
+type InnerClassEntry struct {
+	InnerClassInfoIndex   uint16
+	InnerClass            string
+	OuterClassInfoIndex   uint16
+	OuterClass            string
+	InnerNameIndex        uint16
+	InnerName             string
+	InnerClassAccessFlags AccessFlags
+}
+
+func (self InnerClassEntry) String() string {
+	s := fmt.Sprintf("%s %s", self.InnerClassAccessFlags, self.InnerClass)
+	if self.InnerName != "" {
+		s += " = " + self.InnerName
+	}
+	if self.OuterClass != "" {
+		s += " of " + self.OuterClass
+	}
+	return s
+}
+
+func (self InnerClassesAttribute) String() string {
+	classes := []string{}
+	for _, c := range self.Classes {
+		classes = append(classes, c.String())
+	}
+	return fmt.Sprintf("InnerClasses: [%s]", strings.Join(classes, ", "))
+}
+
+func (self ClassFileParser) parseInnerClassesAttribute() (InnerClassesAttribute, error) {
+	count, err := self.reader.ReadU2()
+	if err != nil {
+		return InnerClassesAttribute{}, within(err, "number_of_classes")
+	}
+	classes := make([]InnerClassEntry, count)
+	for i := range classes {
+		c := &classes[i]
+		var flags uint16
+		for _, field := range []*uint16{&c.InnerClassInfoIndex, &c.OuterClassInfoIndex, &c.InnerNameIndex, &flags} {
+			if *field, err = self.reader.ReadU2(); err != nil {
+				return InnerClassesAttribute{}, within(err, "classes[%d]", i)
+			}
+		}
+		c.InnerClassAccessFlags = AccessFlags(flags)
+		if c.InnerClass, err = self.className(c.InnerClassInfoIndex); err != nil {
+			return InnerClassesAttribute{}, within(err, "classes[%d].inner_class_info_index", i)
+		}
+		if c.OuterClassInfoIndex != 0 {
+			if c.OuterClass, err = self.className(c.OuterClassInfoIndex); err != nil {
+				return InnerClassesAttribute{}, within(err, "classes[%d].outer_class_info_index", i)
+			}
+		}
+		if c.InnerName, err = self.optionalUtf8(c.InnerNameIndex); err != nil {
+			return InnerClassesAttribute{}, within(err, "classes[%d].inner_name_index", i)
+		}
+	}
+	return InnerClassesAttribute{self.header, classes}, nil
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.7
+//
+//	EnclosingMethod_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 class_index;
+//	    u2 method_index;
+//	}
+//
+// MethodIndex is 0, and MethodName and MethodDescriptor empty, when a local or
+// anonymous class is not enclosed by a method, e.g. it sits in a field initializer.
+type EnclosingMethodAttribute struct {
+	AttributeHeader
+	ClassIndex       uint16
+	Class            string
+	MethodIndex      uint16
+	MethodName       string
+	MethodDescriptor string
+}
+
+func (self EnclosingMethodAttribute) String() string {
+	if self.MethodIndex == 0 {
+		return fmt.Sprintf("EnclosingMethod: #%d.#0 // %s", self.ClassIndex, self.Class)
+	}
+	return fmt.Sprintf("EnclosingMethod: #%d.#%d // %s.%s%s", self.ClassIndex, self.MethodIndex, self.Class, self.MethodName, self.MethodDescriptor)
+}
+
+func (self ClassFileParser) parseEnclosingMethodAttribute() (EnclosingMethodAttribute, error) {
+	e := EnclosingMethodAttribute{AttributeHeader: self.header}
+	var err error
+	if e.ClassIndex, err = self.reader.ReadU2(); err != nil {
+		return e, within(err, "class_index")
+	}
+	if e.Class, err = self.className(e.ClassIndex); err != nil {
+		return e, within(err, "class_index")
+	}
+	if e.MethodIndex, err = self.reader.ReadU2(); err != nil {
+		return e, within(err, "method_index")
+	}
+	if e.MethodIndex == 0 {
+		return e, nil
+	}
+	if e.MethodName, e.MethodDescriptor, err = self.cp.nameAndType(e.MethodIndex); err != nil {
+		return e, within(self.constantError(err), "method_index")
+	}
+	return e, nil
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.28
+//
+//	NestHost_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 host_class_index;
+//	}
+type NestHostAttribute struct {
+	AttributeHeader
+	HostClassIndex uint16
+	HostClass      string
+}
+
+func (self NestHostAttribute) String() string {
+	return fmt.Sprintf("NestHost: #%d // %s", self.HostClassIndex, self.HostClass)
+}
+
+func (self ClassFileParser) parseNestHostAttribute() (NestHostAttribute, error) {
+	index, err := self.reader.ReadU2()
+	if err != nil {
+		return NestHostAttribute{}, within(err, "host_class_index")
+	}
+	name, err := self.className(index)
+	if err != nil {
+		return NestHostAttribute{}, within(err, "host_class_index")
+	}
+	return NestHostAttribute{self.header, index, name}, nil
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.29
+//
+//	NestMembers_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 number_of_classes;
+//	    u2 classes[number_of_classes];
+//	}
+type NestMembersAttribute struct {
+	AttributeHeader
+	ClassIndex []uint16
+	Classes    []string
+}
+
+func (self NestMembersAttribute) String() string {
+	return fmt.Sprintf("NestMembers: [%s]", strings.Join(self.Classes, ", "))
+}
+
+func (self ClassFileParser) parseNestMembersAttribute() (NestMembersAttribute, error) {
+	indices, classes, err := self.parseIndexList("number_of_classes", "classes", self.className)
+	return NestMembersAttribute{self.header, indices, classes}, err
+}
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.31
+//
+//	PermittedSubclasses_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u2 number_of_classes;
+//	    u2 classes[number_of_classes];
+//	}
+type PermittedSubclassesAttribute struct {
+	AttributeHeader
+	ClassIndex []uint16
+	Classes    []string
+}
+
+func (self PermittedSubclassesAttribute) String() string {
+	return fmt.Sprintf("PermittedSubclasses: [%s]", strings.Join(self.Classes, ", "))
+}
+
+func (self ClassFileParser) parsePermittedSubclassesAttribute() (PermittedSubclassesAttribute, error) {
+	indices, classes, err := self.parseIndexList("number_of_classes", "classes", self.className)
+	return PermittedSubclassesAttribute{self.header, indices, classes}, err
+}
+
+// InnerClasses returns the entries of the class's InnerClasses attribute.
+func (self ClassFile) InnerClasses() []InnerClassEntry {
+	attr, _ := FindAttribute[InnerClassesAttribute](self.Attributes)
+	return attr.Classes
+}
+
+// EnclosingMethod returns where a local or anonymous class is declared.
+func (self ClassFile) EnclosingMethod() (EnclosingMethodAttribute, bool) {
+	return FindAttribute[EnclosingMethodAttribute](self.Attributes)
+}
+
+// NestHost returns the host of the nest the class belongs to. A class without
+// NestHost is the host of its own nest, so ok is false for nest hosts.
+func (self ClassFile) NestHost() (string, bool) {
+	attr, ok := FindAttribute[NestHostAttribute](self.Attributes)
+	return attr.HostClass, ok
+}
+
+// NestMembers returns the other members of the nest this class hosts.
+func (self ClassFile) NestMembers() []string {
+	attr, _ := FindAttribute[NestMembersAttribute](self.Attributes)
+	return attr.Classes
+}
+
+// PermittedSubclasses returns the classes allowed to extend or implement a
+// sealed class. ok reports whether the class is sealed.
+func (self ClassFile) PermittedSubclasses() ([]string, bool) {
+	attr, ok := FindAttribute[PermittedSubclassesAttribute](self.Attributes)
+	return attr.Classes, ok
+}
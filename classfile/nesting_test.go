@@ -0,0 +1,100 @@
+package classfile
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseRecord(t *testing.T) {
+	cp := ConstantPool{
+		nil,
+		utf8Info("items"),            // #1
+		utf8Info("Ljava/util/List;"), // #2
+		utf8Info("Signature"),        // #3
+		utf8Info("Ljava/util/List<Ljava/lang/String;>;"), // #4
+		utf8Info("count"), // #5
+		utf8Info("I"),     // #6
+	}
+	b := []byte{
+		0, 2, // components_count
+		0, 1, 0, 2, 0, 1, // items, Ljava/util/List;, 1 attribute
+		0, 3, 0, 0, 0, 2, 0, 4, // Signature
+		0, 5, 0, 6, 0, 0, // count, I
+	}
+	parser := ClassFileParser{reader: newClassReader(bytes.NewReader(b)), cp: cp}
+	attr, err := parser.parseRecordAttribute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := attr.String(), "Record: [Ljava/util/List; items, I count]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if sig, ok := attr.Components[0].Signature(); !ok || sig.Signature != "Ljava/util/List<Ljava/lang/String;>;" {
+		t.Errorf("items signature: got %v", sig)
+	}
+
+	// A component attribute that overruns its length is reported inside the component.
+	b[13] = 3
+	parser = ClassFileParser{reader: newClassReader(bytes.NewReader(b)), cp: cp}
+	if _, err := parser.parseRecordAttribute(); err == nil {
+		t.Errorf("expected the bad component attribute to be rejected")
+	}
+}
+
+func TestParseNesting(t *testing.T) {
+	cp := ConstantPool{
+		nil,
+		utf8Info("com/x/Shape"), &ConstantClassInfo{1}, // #1, #2
+		utf8Info("com/x/Shape$Circle"), &ConstantClassInfo{3}, // #3, #4
+		utf8Info("Circle"),                // #5
+		utf8Info("com/x/Shape$1"),         // #6
+		&ConstantClassInfo{6},             // #7
+		utf8Info("area"), utf8Info("()D"), // #8, #9
+		&ConstantNameAndTypeInfo{8, 9}, // #10
+	}
+	parse := func(b []byte) ClassFileParser {
+		return ClassFileParser{reader: newClassReader(bytes.NewReader(b)), cp: cp}
+	}
+
+	inner, err := parse([]byte{
+		0, 2,
+		0, 4, 0, 2, 0, 5, 0, 0x19, // public static final Circle
+		0, 7, 0, 0, 0, 0, 0, 0, // anonymous
+	}).parseInnerClassesAttribute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "InnerClasses: [[public static final] com/x/Shape$Circle = Circle of com/x/Shape, [] com/x/Shape$1]"
+	if got := inner.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	enclosing, err := parse([]byte{0, 2, 0, 10}).parseEnclosingMethodAttribute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := enclosing.String(), "EnclosingMethod: #2.#10 // com/x/Shape.area()D"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	permitted, err := parse([]byte{0, 2, 0, 4, 0, 7}).parsePermittedSubclassesAttribute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, err := parse([]byte{0, 2}).parseNestHostAttribute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf := ClassFile{Attributes: []AttributeInfo{permitted, host}}
+	if subclasses, ok := cf.PermittedSubclasses(); !ok || len(subclasses) != 2 || subclasses[1] != "com/x/Shape$1" {
+		t.Errorf("PermittedSubclasses: got %v, %v", subclasses, ok)
+	}
+	if name, ok := cf.NestHost(); !ok || name != "com/x/Shape" {
+		t.Errorf("NestHost: got %q, %v", name, ok)
+	}
+
+	// NestMembers must reference CONSTANT_Class entries.
+	if _, err := parse([]byte{0, 1, 0, 1}).parseNestMembersAttribute(); err == nil {
+		t.Errorf("expected a Utf8 entry in classes to be rejected")
+	}
+}
@@ -7,53 +7,99 @@ import (
 
 // Parses a Java class file
 type ClassFileParser struct {
-	reader *ClassReader
-	cp     []ConstantInfo
+	reader   *ClassReader
+	cp       ConstantPool
+	decoders map[attributeKey]AttributeDecoder
+	// header is that of the attribute whose body is being parsed.
+	header AttributeHeader
 }
 
 func NewClassFileParser(reader io.Reader) *ClassFileParser {
-	r := &ClassReader{reader}
-	return &ClassFileParser{r, nil}
+	r := newClassReader(reader)
+	return &ClassFileParser{reader: r}
 }
 
 // Central method to parse a class file
+//
+// Any truncated or malformed input is reported as a *ClassFormatError; Parse
+// never panics on bad data.
 func (self *ClassFileParser) Parse() (*ClassFile, error) {
-	magic := self.reader.ReadU4()
+	magic, err := self.reader.ReadU4()
+	if err != nil {
+		return nil, within(err, "magic")
+	}
 	if !validateMagic(magic) {
-		return nil, fmt.Errorf("java.lang.ClassFormatError: %d", magic)
+		return nil, &ClassFormatError{Offset: 0, Msg: fmt.Sprintf("bad magic %#x", magic), Context: []string{"magic"}}
 	}
 	cf := &ClassFile{}
 
-	cf.MinorVersion = self.reader.ReadU2()
-	cf.MajorVersion = self.reader.ReadU2()
-	cf.ConstantPoolCount = self.reader.ReadU2()
+	if cf.MinorVersion, err = self.reader.ReadU2(); err != nil {
+		return nil, within(err, "minor_version")
+	}
+	if cf.MajorVersion, err = self.reader.ReadU2(); err != nil {
+		return nil, within(err, "major_version")
+	}
+	if cf.ConstantPoolCount, err = self.reader.ReadU2(); err != nil {
+		return nil, within(err, "constant_pool_count")
+	}
 	cp, err := self.parseConstantPool(cf.ConstantPoolCount)
 	if err != nil {
 		return nil, err
 	}
 	cf.ConstantPool = cp
 	self.cp = cp
-	cf.AccessFlags = AccessFlags(self.reader.ReadU2())
-	cf.ThisClass = self.reader.ReadU2()
-	cf.SuperClass = self.reader.ReadU2()
-	cf.InterfacesCount = self.reader.ReadU2()
-	var ifs []uint16
-	for i := 0; i < int(cf.InterfacesCount); i++ {
-		ifs = append(ifs, self.reader.ReadU2())
+	accessFlags, err := self.reader.ReadU2()
+	if err != nil {
+		return nil, within(err, "access_flags")
+	}
+	cf.AccessFlags = AccessFlags(accessFlags)
+	if cf.ThisClass, err = self.reader.ReadU2(); err != nil {
+		return nil, within(err, "this_class")
+	}
+	if cf.SuperClass, err = self.reader.ReadU2(); err != nil {
+		return nil, within(err, "super_class")
+	}
+	if cf.InterfacesCount, err = self.reader.ReadU2(); err != nil {
+		return nil, within(err, "interfaces_count")
+	}
+	ifs := make([]uint16, cf.InterfacesCount)
+	for i := range ifs {
+		if ifs[i], err = self.reader.ReadU2(); err != nil {
+			return nil, within(err, "interfaces[%d]", i)
+		}
 	}
 	cf.Interfaces = ifs
-	cf.FieldCount = self.reader.ReadU2()
+	if cf.FieldCount, err = self.reader.ReadU2(); err != nil {
+		return nil, within(err, "fields_count")
+	}
 	fields, err := self.parseFiledInfo(cf.FieldCount)
 	if err != nil {
 		return nil, err
 	}
 	cf.Fields = fields
-	cf.MethodCount = self.reader.ReadU2()
+	if cf.MethodCount, err = self.reader.ReadU2(); err != nil {
+		return nil, within(err, "methods_count")
+	}
 	methods, err := self.parseMethodInfo(cf.MethodCount)
 	if err != nil {
 		return nil, err
 	}
 	cf.Methods = methods
+	if cf.AttributesCount, err = self.reader.ReadU2(); err != nil {
+		return nil, within(err, "attributes_count")
+	}
+	attributes, err := self.parseAttributeInfo(cf.AttributesCount, ClassContext)
+	if err != nil {
+		return nil, err
+	}
+	cf.Attributes = attributes
+	atEOF, err := self.reader.AtEOF()
+	if err != nil {
+		return nil, within(err, "end of class file")
+	}
+	if !atEOF {
+		return nil, &ClassFormatError{Offset: self.reader.Offset() - 1, Msg: "extra bytes at the end of class file"}
+	}
 
 	return cf, nil
 }
@@ -62,91 +108,221 @@ func validateMagic(magic uint32) bool {
 	return magic == 0xCAFEBABE
 }
 
+// errorf builds a ClassFormatError at the current read position.
+func (self ClassFileParser) errorf(format string, a ...any) error {
+	return &ClassFormatError{Offset: self.reader.Offset(), Msg: fmt.Sprintf(format, a...)}
+}
+
+// constantError reports a failed constant pool lookup as a ClassFormatError at
+// the current read position.
+func (self ClassFileParser) constantError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ClassFormatError{Offset: self.reader.Offset(), Msg: err.Error(), Err: err}
+}
+
+// utf8 looks up a CONSTANT_Utf8 entry.
+func (self ClassFileParser) utf8(index uint16) (string, error) {
+	value, err := self.cp.utf8(index)
+	return value, self.constantError(err)
+}
+
+// optionalUtf8 is utf8 for the fields where index 0 means "absent".
+func (self ClassFileParser) optionalUtf8(index uint16) (string, error) {
+	if index == 0 {
+		return "", nil
+	}
+	return self.utf8(index)
+}
+
+// className looks up a CONSTANT_Class entry and returns the name in internal form.
+func (self ClassFileParser) className(index uint16) (string, error) {
+	name, err := self.cp.className(index)
+	return name, self.constantError(err)
+}
+
+// moduleName looks up a CONSTANT_Module entry.
+func (self ClassFileParser) moduleName(index uint16) (string, error) {
+	info, err := lookup[*ConstantModuleInfo](self.cp, index, "CONSTANT_Module")
+	if err != nil {
+		return "", self.constantError(err)
+	}
+	return self.utf8(info.NameIndex)
+}
+
+// packageName looks up a CONSTANT_Package entry and returns the name in internal form.
+func (self ClassFileParser) packageName(index uint16) (string, error) {
+	info, err := lookup[*ConstantPackageInfo](self.cp, index, "CONSTANT_Package")
+	if err != nil {
+		return "", self.constantError(err)
+	}
+	return self.utf8(info.NameIndex)
+}
+
+// parseIndexList reads a u2 count followed by that many u2 constant pool
+// indices, resolving each index. count and entries are the JVMS names of the
+// two fields for error messages, e.g. "uses_count" and "uses_index".
+func (self ClassFileParser) parseIndexList(count, entries string, resolve func(uint16) (string, error)) ([]uint16, []string, error) {
+	n, err := self.reader.ReadU2()
+	if err != nil {
+		return nil, nil, within(err, "%s", count)
+	}
+	indices := make([]uint16, n)
+	names := make([]string, n)
+	for i := range indices {
+		if indices[i], err = self.reader.ReadU2(); err != nil {
+			return nil, nil, within(err, "%s[%d]", entries, i)
+		}
+		if names[i], err = resolve(indices[i]); err != nil {
+			return nil, nil, within(err, "%s[%d]", entries, i)
+		}
+	}
+	return indices, names, nil
+}
+
 func (self ClassFileParser) parseConstantPool(count uint16) ([]ConstantInfo, error) {
 	constantPool := make([]ConstantInfo, count)
 	for i := 1; i < int(count); i++ {
-		tag := self.reader.ReadU1()
-		switch tag {
-		case ConstantClassTag:
-			nameIndex := self.reader.ReadU2()
-			constantPool[i] = &ConstantClassInfo{nameIndex}
-		case ConstantFieldrefTag:
-			classIndex := self.reader.ReadU2()
-			nameAndTypeIndex := self.reader.ReadU2()
-			constantPool[i] = &ConstantFieldrefInfo{classIndex, nameAndTypeIndex}
-		case ConstantMethodrefTag:
-			classIndex := self.reader.ReadU2()
-			nameAndTypeIndex := self.reader.ReadU2()
-			constantPool[i] = &ConstantMethodrefInfo{classIndex, nameAndTypeIndex}
-		case ConstantInterfaceMethodrefTag:
-			classIndex := self.reader.ReadU2()
-			nameAndTypeIndex := self.reader.ReadU2()
-			constantPool[i] = &ConstantInterfaceMethodrefInfo{classIndex, nameAndTypeIndex}
-		case ConstantStringTag:
-			stringIndex := self.reader.ReadU2()
-			constantPool[i] = &ConstantStringInfo{stringIndex}
-		case ConstantIntegerTag:
-			b := self.reader.Read(4)
-			constantPool[i] = &ConstantIntegerInfo{b}
-		case ConstantFloatTag:
-			b := self.reader.Read(4)
-			constantPool[i] = &ConstantFloatInfo{b}
-		case ConstantLongTag:
-			highBytes := self.reader.ReadU4()
-			lowBytes := self.reader.ReadU4()
-			constantPool[i] = &ConstantLongInfo{highBytes, lowBytes}
-		case ConstantDoubleTag:
-			highBytes := self.reader.ReadU4()
-			lowBytes := self.reader.ReadU4()
-			constantPool[i] = &ConstantDoubleInfo{highBytes, lowBytes}
-		case ConstantNameAndTypeTag:
-			nameIndex := self.reader.ReadU2()
-			descriptorIndex := self.reader.ReadU2()
-			constantPool[i] = &ConstantNameAndTypeInfo{nameIndex, descriptorIndex}
-		case ConstantUtf8Tag:
-			length := self.reader.ReadU2()
-			bytes := self.reader.Read(uint32(length))
-			constantPool[i] = &ConstantUtf8Info{length, bytes}
-		case ConstantMethodHandleTag:
-			referenceKind := self.reader.ReadU1()
-			referenceIndex := self.reader.ReadU2()
-			constantPool[i] = &ConstantMethodHandleInfo{referenceKind, referenceIndex}
-		case ConstantMethodTypeTag:
-			descriptorIndex := self.reader.ReadU2()
-			constantPool[i] = &ConstantMethodTypeInfo{descriptorIndex}
-		case ConstantDynamicTag:
-			bootstrapMethodAttrIndex := self.reader.ReadU2()
-			nameAndTypeIndex := self.reader.ReadU2()
-			constantPool[i] = &ConstantDynamicInfo{bootstrapMethodAttrIndex, nameAndTypeIndex}
-		case ConstantInvokeDynamicTag:
-			bootstrapMethodAttrIndex := self.reader.ReadU2()
-			nameAndTypeIndex := self.reader.ReadU2()
-			constantPool[i] = &ConstantInvokeDynamicInfo{bootstrapMethodAttrIndex, nameAndTypeIndex}
-		case ConstantModuleTag:
-			nameIndex := self.reader.ReadU2()
-			constantPool[i] = &ConstantModuleInfo{nameIndex}
-		case ConstantPackageTag:
-			nameIndex := self.reader.ReadU2()
-			constantPool[i] = &ConstantPackageInfo{nameIndex}
-		default:
-			return nil, fmt.Errorf("java.lang.ClassFormatError: constant pool tag %d", tag)
+		info, err := self.parseConstantInfo()
+		if err != nil {
+			return nil, within(err, "constant_pool[%d]", i)
+		}
+		constantPool[i] = info
+		// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.4.5
+		// 8-byte constants take up two entries; the second one is valid but unusable.
+		switch info.(type) {
+		case *ConstantLongInfo, *ConstantDoubleInfo:
+			i++
+			if i >= int(count) {
+				return nil, within(self.errorf("8-byte constant in the last constant pool slot"), "constant_pool[%d]", i-1)
+			}
+			constantPool[i] = &ConstantUnusableInfo{}
 		}
 	}
 	return constantPool, nil
 }
 
+func (self ClassFileParser) parseConstantInfo() (ConstantInfo, error) {
+	r := self.reader
+	tag, err := r.ReadU1()
+	if err != nil {
+		return nil, err
+	}
+	// Every entry is a fixed sequence of u1/u2/u4 fields; read them all up
+	// front and stop at the first failure.
+	var u1 uint8
+	var u2a, u2b uint16
+	var u4a, u4b uint32
+	var b []byte
+	switch tag {
+	case ConstantClassTag, ConstantStringTag, ConstantMethodTypeTag, ConstantModuleTag, ConstantPackageTag:
+		u2a, err = r.ReadU2()
+	case ConstantFieldrefTag, ConstantMethodrefTag, ConstantInterfaceMethodrefTag, ConstantNameAndTypeTag, ConstantDynamicTag, ConstantInvokeDynamicTag:
+		if u2a, err = r.ReadU2(); err == nil {
+			u2b, err = r.ReadU2()
+		}
+	case ConstantIntegerTag, ConstantFloatTag:
+		b, err = r.Read(4)
+	case ConstantLongTag, ConstantDoubleTag:
+		if u4a, err = r.ReadU4(); err == nil {
+			u4b, err = r.ReadU4()
+		}
+	case ConstantUtf8Tag:
+		if u2a, err = r.ReadU2(); err == nil {
+			b, err = r.Read(uint32(u2a))
+		}
+	case ConstantMethodHandleTag:
+		if u1, err = r.ReadU1(); err == nil {
+			u2a, err = r.ReadU2()
+		}
+	default:
+		return nil, &ClassFormatError{Offset: r.Offset() - 1, Msg: fmt.Sprintf("unknown constant pool tag %d", tag)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case ConstantClassTag:
+		return &ConstantClassInfo{u2a}, nil
+	case ConstantFieldrefTag:
+		return &ConstantFieldrefInfo{u2a, u2b}, nil
+	case ConstantMethodrefTag:
+		return &ConstantMethodrefInfo{u2a, u2b}, nil
+	case ConstantInterfaceMethodrefTag:
+		return &ConstantInterfaceMethodrefInfo{u2a, u2b}, nil
+	case ConstantStringTag:
+		return &ConstantStringInfo{u2a}, nil
+	case ConstantIntegerTag:
+		return &ConstantIntegerInfo{b}, nil
+	case ConstantFloatTag:
+		return &ConstantFloatInfo{b}, nil
+	case ConstantLongTag:
+		return &ConstantLongInfo{u4a, u4b}, nil
+	case ConstantDoubleTag:
+		return &ConstantDoubleInfo{u4a, u4b}, nil
+	case ConstantNameAndTypeTag:
+		return &ConstantNameAndTypeInfo{u2a, u2b}, nil
+	case ConstantUtf8Tag:
+		if _, err := ModifiedUTF8ToUTF16(b); err != nil {
+			return nil, &ClassFormatError{Offset: r.Offset() - int64(len(b)), Msg: err.Error()}
+		}
+		return &ConstantUtf8Info{u2a, b}, nil
+	case ConstantMethodHandleTag:
+		return &ConstantMethodHandleInfo{u1, u2a}, nil
+	case ConstantMethodTypeTag:
+		return &ConstantMethodTypeInfo{u2a}, nil
+	case ConstantDynamicTag:
+		return &ConstantDynamicInfo{u2a, u2b}, nil
+	case ConstantInvokeDynamicTag:
+		return &ConstantInvokeDynamicInfo{u2a, u2b}, nil
+	case ConstantModuleTag:
+		return &ConstantModuleInfo{u2a}, nil
+	default: // ConstantPackageTag
+		return &ConstantPackageInfo{u2a}, nil
+	}
+}
+
+// memberHeader is the part shared by field_info and method_info before the attributes.
+type memberHeader struct {
+	accessFlags     AccessFlags
+	nameIndex       uint16
+	descriptorIndex uint16
+	attributesCount uint16
+}
+
+func (self ClassFileParser) parseMemberHeader() (memberHeader, error) {
+	var h memberHeader
+	flags, err := self.reader.ReadU2()
+	if err != nil {
+		return h, within(err, "access_flags")
+	}
+	h.accessFlags = AccessFlags(flags)
+	if h.nameIndex, err = self.reader.ReadU2(); err != nil {
+		return h, within(err, "name_index")
+	}
+	if h.descriptorIndex, err = self.reader.ReadU2(); err != nil {
+		return h, within(err, "descriptor_index")
+	}
+	if h.attributesCount, err = self.reader.ReadU2(); err != nil {
+		return h, within(err, "attributes_count")
+	}
+	return h, nil
+}
+
 func (self ClassFileParser) parseFiledInfo(size uint16) ([]FieldInfo, error) {
 	fields := make([]FieldInfo, size)
 	for i := 0; i < int(size); i++ {
-		accessFlags := AccessFlags(self.reader.ReadU2())
-		nameIndex := self.reader.ReadU2()
-		descriptorIndex := self.reader.ReadU2()
-		attributesCount := self.reader.ReadU2()
-		attributes, err := self.parseAttributeInfo(attributesCount)
+		h, err := self.parseMemberHeader()
+		if err != nil {
+			return nil, within(err, "field_info[%d]", i)
+		}
+		attributes, err := self.parseAttributeInfo(h.attributesCount, FieldContext)
 		if err != nil {
-			return nil, err
+			return nil, within(err, "field_info[%d]", i)
 		}
-		fields[i] = FieldInfo{accessFlags, nameIndex, descriptorIndex, attributes}
+		fields[i] = FieldInfo{h.accessFlags, h.nameIndex, h.descriptorIndex, attributes}
 	}
 	return fields, nil
 }
@@ -154,14 +330,23 @@ func (self ClassFileParser) parseFiledInfo(size uint16) ([]FieldInfo, error) {
 func (self ClassFileParser) parseMethodInfo(size uint16) ([]MethodInfo, error) {
 	methods := make([]MethodInfo, size)
 	for i := 0; i < int(size); i++ {
-		accessFlags := AccessFlags(self.reader.ReadU2())
-		nameIndex := self.reader.ReadU2()
-		name := self.cp[nameIndex].(*ConstantUtf8Info).Value()
-		descriptorIndex := self.reader.ReadU2()
-		descriptor := self.cp[descriptorIndex].(*ConstantUtf8Info).Value()
-		attributesCount := self.reader.ReadU2()
-		attributes, err := self.parseAttributeInfo(attributesCount)
-		
+		h, err := self.parseMemberHeader()
+		if err != nil {
+			return nil, within(err, "method_info[%d]", i)
+		}
+		name, err := self.utf8(h.nameIndex)
+		if err != nil {
+			return nil, within(err, "method_info[%d].name_index", i)
+		}
+		descriptor, err := self.utf8(h.descriptorIndex)
+		if err != nil {
+			return nil, within(err, "method_info[%d].descriptor_index", i)
+		}
+		attributes, err := self.parseAttributeInfo(h.attributesCount, MethodContext)
+		if err != nil {
+			return nil, within(err, "method_info[%d]", i)
+		}
+
 		code := []byte{}
 		for _, attr := range attributes {
 			switch attr.(type) {
@@ -169,10 +354,7 @@ func (self ClassFileParser) parseMethodInfo(size uint16) ([]MethodInfo, error) {
 				code = attr.(CodeAttribute).Code
 			}
 		}
-		if err != nil {
-			return nil, err
-		}
-		methods[i] = MethodInfo{accessFlags, name, nameIndex, descriptor, descriptorIndex, attributes, code}
+		methods[i] = MethodInfo{h.accessFlags, name, h.nameIndex, descriptor, h.descriptorIndex, attributes, code}
 	}
 	return methods, nil
 }
@@ -0,0 +1,156 @@
+package classfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func readHello(t *testing.T) []byte {
+	t.Helper()
+	b, err := os.ReadFile("../java/Hello.class")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseHello(t *testing.T) {
+	cf, err := NewClassFileParser(bytes.NewReader(readHello(t))).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.MethodCount != 2 || cf.Methods[1].Name != "main" {
+		t.Errorf("unexpected methods: %v", cf.Methods)
+	}
+	if name, ok := cf.SourceFile(); !ok || name != "Hello.java" {
+		t.Errorf("SourceFile: got %q", name)
+	}
+	code := cf.Methods[1].Attributes[0].(CodeAttribute)
+	for pc, want := range map[uint16]int{0: 4, 3: 4, 8: 5} {
+		if line, ok := code.LineNumber(pc); !ok || line != want {
+			t.Errorf("LineNumber(%d): got %d, want %d", pc, line, want)
+		}
+	}
+}
+
+func TestNameBadIndex(t *testing.T) {
+	for _, index := range []uint16{0, 3, 999} {
+		cf := ClassFile{ConstantPool: ConstantPool{nil, &ConstantClassInfo{index}, utf8Info("Foo")}, ThisClass: 1}
+		if name := cf.Name(); name != "" {
+			t.Errorf("name_index #%d: got %q", index, name)
+		}
+	}
+	cf := ClassFile{ConstantPool: ConstantPool{nil, &ConstantClassInfo{2}, utf8Info("Foo")}, ThisClass: 1}
+	if name := cf.Name(); name != "Foo" {
+		t.Errorf("got %q, want Foo", name)
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	b := readHello(t)
+	for n := 0; n < len(b); n++ {
+		_, err := NewClassFileParser(bytes.NewReader(b[:n])).Parse()
+		var cfe *ClassFormatError
+		if !errors.As(err, &cfe) {
+			t.Fatalf("truncated at %d: got %v, want ClassFormatError", n, err)
+		}
+	}
+}
+
+func TestParseErrorContext(t *testing.T) {
+	b := readHello(t)
+	// 0x17c lies inside the Code attribute of main, method_info[1], whose
+	// body starts at 0x170.
+	_, err := NewClassFileParser(bytes.NewReader(b[:0x17c])).Parse()
+	want := "java.lang.ClassFormatError: unexpected EOF at offset 0x170 while reading method_info[1].attributes[0].Code"
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v\nwant %s", err, want)
+	}
+}
+
+func TestParseCodeLengthOverrun(t *testing.T) {
+	b := bytes.Clone(readHello(t))
+	b[0x177] = 0xff // code_length of main no longer fits its Code attribute
+	_, err := NewClassFileParser(bytes.NewReader(b)).Parse()
+	want := "java.lang.ClassFormatError: unexpected EOF at offset 0x178 while reading method_info[1].attributes[0].Code.code"
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v\nwant %s", err, want)
+	}
+}
+
+// classBytes assembles a minimal class file around the given constant pool
+// entries (already encoded) and pool count.
+func classBytes(cpCount uint16, cp ...[]byte) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0xca, 0xfe, 0xba, 0xbe, 0, 0, 0, 61})
+	b.Write([]byte{byte(cpCount >> 8), byte(cpCount)})
+	for _, e := range cp {
+		b.Write(e)
+	}
+	// access_flags, this_class, super_class, interfaces, fields, methods, attributes
+	b.Write([]byte{0, 0x21, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	return b.Bytes()
+}
+
+func TestParseLongTakesTwoSlots(t *testing.T) {
+	b := classBytes(5,
+		[]byte{ConstantLongTag, 0, 0, 0, 0, 0, 0, 0, 123},
+		[]byte{ConstantUtf8Tag, 0, 1, 'x'},
+		[]byte{ConstantDoubleTag, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0},
+	)
+	_, err := NewClassFileParser(bytes.NewReader(b)).Parse()
+	if err == nil || !strings.Contains(err.Error(), "last constant pool slot") {
+		t.Fatalf("expected the double in the last slot to be rejected, got %v", err)
+	}
+
+	b = classBytes(6,
+		[]byte{ConstantLongTag, 0, 0, 0, 0, 0, 0, 0, 123},
+		[]byte{ConstantUtf8Tag, 0, 1, 'x'},
+		[]byte{ConstantDoubleTag, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0},
+	)
+	cf, err := NewClassFileParser(bytes.NewReader(b)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := cf.ConstantPool
+	if _, ok := cp[1].(*ConstantLongInfo); !ok {
+		t.Errorf("#1: got %T", cp[1])
+	}
+	if cp.IsUsable(2) {
+		t.Errorf("#2 should be unusable")
+	}
+	if u, ok := cp[3].(*ConstantUtf8Info); !ok || u.Value() != "x" {
+		t.Errorf("#3: got %v", cp[3])
+	}
+	if _, ok := cp[4].(*ConstantDoubleInfo); !ok || cp.IsUsable(5) {
+		t.Errorf("#4/#5: got %v %v", cp[4], cp[5])
+	}
+}
+
+func TestParseTrailingGarbage(t *testing.T) {
+	b := append(bytes.Clone(readHello(t)), 0)
+	_, err := NewClassFileParser(bytes.NewReader(b)).Parse()
+	want := "java.lang.ClassFormatError: extra bytes at the end of class file at offset 0x19f"
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v\nwant %s", err, want)
+	}
+}
+
+func TestParseReadErrorAtEnd(t *testing.T) {
+	failure := errors.New("read failed")
+	r := io.MultiReader(bytes.NewReader(readHello(t)), iotest.ErrReader(failure))
+	_, err := NewClassFileParser(r).Parse()
+	var cfe *ClassFormatError
+	if !errors.As(err, &cfe) || !errors.Is(err, failure) {
+		t.Fatalf("got %v, want a ClassFormatError wrapping %v", err, failure)
+	}
+	want := "java.lang.ClassFormatError: read failed at offset 0x19f while reading end of class file"
+	if err.Error() != want {
+		t.Errorf("got %v\nwant %s", err, want)
+	}
+}
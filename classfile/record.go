@@ -0,0 +1,97 @@
+package classfile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.30
+//
+//	Record_attribute {
+//	    u2                    attribute_name_index;
+//	    u4                    attribute_length;
+//	    u2                    components_count;
+//	    record_component_info components[components_count];
+//	}
+type RecordAttribute struct {
+	AttributeHeader
+	Components []RecordComponentInfo
+}
+
+func (self RecordAttribute) String() string {
+	components := []string{}
+	for _, c := range self.Components {
+		components = append(components, c.String())
+	}
+	return fmt.Sprintf("Record: [%s]", strings.Join(components, ", "))
+}
+
+//	record_component_info {
+//	    u2             name_index;
+//	    u2             descriptor_index;
+//	    u2             attributes_count;
+//	    attribute_info attributes[attributes_count];
+//	}
+//
+// A component carries its own attributes, typically Signature and annotations.
+type RecordComponentInfo struct {
+	NameIndex       uint16
+	Name            string
+	DescriptorIndex uint16
+	Descriptor      string
+	Attributes      []AttributeInfo
+}
+
+func (self RecordComponentInfo) String() string {
+	return fmt.Sprintf("%s %s", self.Descriptor, self.Name)
+}
+
+// Signature returns the component's generic signature, if it has one.
+func (self RecordComponentInfo) Signature() (SignatureAttribute, bool) {
+	return FindAttribute[SignatureAttribute](self.Attributes)
+}
+
+func (self ClassFileParser) parseRecordAttribute() (RecordAttribute, error) {
+	count, err := self.reader.ReadU2()
+	if err != nil {
+		return RecordAttribute{}, within(err, "components_count")
+	}
+	components := make([]RecordComponentInfo, count)
+	for i := range components {
+		if components[i], err = self.parseRecordComponentInfo(); err != nil {
+			return RecordAttribute{}, within(err, "components[%d]", i)
+		}
+	}
+	return RecordAttribute{self.header, components}, nil
+}
+
+func (self ClassFileParser) parseRecordComponentInfo() (RecordComponentInfo, error) {
+	var c RecordComponentInfo
+	var err error
+	if c.NameIndex, err = self.reader.ReadU2(); err != nil {
+		return c, within(err, "name_index")
+	}
+	if c.Name, err = self.utf8(c.NameIndex); err != nil {
+		return c, within(err, "name_index")
+	}
+	if c.DescriptorIndex, err = self.reader.ReadU2(); err != nil {
+		return c, within(err, "descriptor_index")
+	}
+	if c.Descriptor, err = self.utf8(c.DescriptorIndex); err != nil {
+		return c, within(err, "descriptor_index")
+	}
+	count, err := self.reader.ReadU2()
+	if err != nil {
+		return c, within(err, "attributes_count")
+	}
+	if c.Attributes, err = self.parseAttributeInfo(count, RecordComponentContext); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// Record returns the components of a record class.
+func (self ClassFile) Record() ([]RecordComponentInfo, bool) {
+	attr, ok := FindAttribute[RecordAttribute](self.Attributes)
+	return attr.Components, ok
+}
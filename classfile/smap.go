@@ -0,0 +1,281 @@
+package classfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.11
+//
+//	SourceDebugExtension_attribute {
+//	    u2 attribute_name_index;
+//	    u4 attribute_length;
+//	    u1 debug_extension[attribute_length];
+//	}
+//
+// The JVM attaches no meaning to debug_extension. In practice it holds a JSR-45
+// source map, written by compilers such as kotlinc and Jasper, see SMAP.
+type SourceDebugExtensionAttribute struct {
+	AttributeHeader
+	DebugExtension []byte
+}
+
+func (self SourceDebugExtensionAttribute) String() string {
+	s, err := self.Value()
+	if err != nil {
+		s = string(self.DebugExtension)
+	}
+	return fmt.Sprintf("SourceDebugExtension: %q", s)
+}
+
+// Value decodes debug_extension, which is modified UTF-8 like a CONSTANT_Utf8.
+func (self SourceDebugExtensionAttribute) Value() (string, error) {
+	return DecodeModifiedUTF8(self.DebugExtension)
+}
+
+// SMAP decodes debug_extension as a source map.
+func (self SourceDebugExtensionAttribute) SMAP() (*SMAP, error) {
+	s, err := self.Value()
+	if err != nil {
+		return nil, err
+	}
+	return ParseSMAP(s)
+}
+
+func (self ClassFileParser) parseSourceDebugExtensionAttribute(attrLen uint32) (SourceDebugExtensionAttribute, error) {
+	data, err := self.reader.Read(attrLen)
+	if err != nil {
+		return SourceDebugExtensionAttribute{}, within(err, "debug_extension")
+	}
+	return SourceDebugExtensionAttribute{self.header, data}, nil
+}
+
+// SMAP is a resolved JSR-45 source map: for each stratum, i.e. source language
+// view, which input source lines the lines of the generated Java file came from.
+//
+//	SMAP
+//	Foo.java         output file name
+//	Kotlin           default stratum
+//	*S Kotlin
+//	*F
+//	+ 1 Foo.kt
+//	com/x/Foo.kt
+//	*L
+//	1#1,5:1          InputStartLine#LineFileID,RepeatCount:OutputStartLine,OutputLineIncrement
+//	*E
+type SMAP struct {
+	OutputFileName string
+	DefaultStratum string
+	Strata         []Stratum
+}
+
+type Stratum struct {
+	Name  string
+	Files []SMAPFile
+	Lines []SMAPLineInfo
+}
+
+type SMAPFile struct {
+	ID   int
+	Name string
+	// Path is the optional absolute file name, empty when the entry has none.
+	Path string
+}
+
+// SMAPLineInfo maps RepeatCount input lines, starting at InputStartLine of
+// file LineFileID, to output lines starting at OutputStartLine. Every input
+// line covers OutputLineIncrement output lines.
+type SMAPLineInfo struct {
+	InputStartLine      int
+	LineFileID          int
+	RepeatCount         int
+	OutputStartLine     int
+	OutputLineIncrement int
+}
+
+// Stratum returns the named stratum, or the default one for "".
+func (self SMAP) Stratum(name string) (Stratum, bool) {
+	if name == "" {
+		name = self.DefaultStratum
+	}
+	for _, s := range self.Strata {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Stratum{}, false
+}
+
+// Map finds the input file and line that output line came from.
+func (self Stratum) Map(outputLine int) (SMAPFile, int, bool) {
+	for _, l := range self.Lines {
+		var input int
+		switch {
+		case l.OutputLineIncrement == 0:
+			if outputLine != l.OutputStartLine {
+				continue
+			}
+			input = l.InputStartLine
+		case outputLine >= l.OutputStartLine && outputLine < l.OutputStartLine+l.RepeatCount*l.OutputLineIncrement:
+			input = l.InputStartLine + (outputLine-l.OutputStartLine)/l.OutputLineIncrement
+		default:
+			continue
+		}
+		for _, f := range self.Files {
+			if f.ID == l.LineFileID {
+				return f, input, true
+			}
+		}
+	}
+	return SMAPFile{}, 0, false
+}
+
+// ParseSMAP parses a resolved source map. Embedded source maps (*O and *C
+// sections) only occur in intermediate files and are rejected.
+func ParseSMAP(s string) (*SMAP, error) {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	errorf := func(n int, format string, a ...any) error {
+		return fmt.Errorf("SMAP line %d: %s", n+1, fmt.Sprintf(format, a...))
+	}
+	if len(lines) < 3 || lines[0] != "SMAP" {
+		return nil, fmt.Errorf("SMAP: missing header")
+	}
+	smap := &SMAP{OutputFileName: lines[1], DefaultStratum: lines[2]}
+
+	var stratum *Stratum
+	section := ""
+	fileID := 0
+	ended := false
+	for n := 3; n < len(lines); n++ {
+		line := lines[n]
+		if strings.HasPrefix(line, "*") {
+			section, _, _ = strings.Cut(line[1:], " ")
+			switch section {
+			case "S":
+				smap.Strata = append(smap.Strata, Stratum{Name: strings.TrimSpace(line[2:])})
+				stratum = &smap.Strata[len(smap.Strata)-1]
+				fileID = 0
+			case "E":
+				// kotlinc ends every stratum with *E rather than only the last.
+				ended = true
+			case "O", "C":
+				return nil, errorf(n, "embedded source maps are not supported")
+			case "F", "L":
+				if stratum == nil {
+					return nil, errorf(n, "*%s section outside a stratum", section)
+				}
+			}
+			continue
+		}
+		switch section {
+		case "F":
+			file, err := parseSMAPFile(line)
+			if err != nil {
+				return nil, errorf(n, "%v", err)
+			}
+			if strings.HasPrefix(line, "+") {
+				n++
+				if n == len(lines) {
+					return nil, errorf(n-1, "missing path of file %d", file.ID)
+				}
+				file.Path = lines[n]
+			}
+			stratum.Files = append(stratum.Files, file)
+		case "L":
+			info, err := parseSMAPLineInfo(line, fileID)
+			if err != nil {
+				return nil, errorf(n, "%v", err)
+			}
+			// LineFileID carries over to later lines that leave it out.
+			fileID = info.LineFileID
+			stratum.Lines = append(stratum.Lines, info)
+		}
+		// Vendor (*V) and unknown sections are skipped.
+	}
+	if !ended {
+		return nil, fmt.Errorf("SMAP: missing *E")
+	}
+	return smap, nil
+}
+
+// parseSMAPFile parses "[+ ]FileID FileName".
+func parseSMAPFile(line string) (SMAPFile, error) {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "+"))
+	id, name, ok := strings.Cut(line, " ")
+	if !ok {
+		return SMAPFile{}, fmt.Errorf("bad file info %q", line)
+	}
+	fileID, err := strconv.Atoi(id)
+	if err != nil {
+		return SMAPFile{}, fmt.Errorf("bad file id %q", id)
+	}
+	return SMAPFile{ID: fileID, Name: strings.TrimSpace(name)}, nil
+}
+
+// parseSMAPLineInfo parses
+// "InputStartLine[#LineFileID][,RepeatCount]:OutputStartLine[,OutputLineIncrement]".
+func parseSMAPLineInfo(line string, fileID int) (SMAPLineInfo, error) {
+	info := SMAPLineInfo{LineFileID: fileID, RepeatCount: 1, OutputLineIncrement: 1}
+	input, output, ok := strings.Cut(strings.TrimSpace(line), ":")
+	if !ok {
+		return info, fmt.Errorf("bad line info %q", line)
+	}
+	number := func(s string) (int, error) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("bad line info %q", line)
+		}
+		return n, nil
+	}
+	var err error
+	if start, repeat, ok := strings.Cut(input, ","); ok {
+		if info.RepeatCount, err = number(repeat); err != nil {
+			return info, err
+		}
+		input = start
+	}
+	if start, id, ok := strings.Cut(input, "#"); ok {
+		if info.LineFileID, err = number(id); err != nil {
+			return info, err
+		}
+		input = start
+	}
+	if info.InputStartLine, err = number(input); err != nil {
+		return info, err
+	}
+	if start, increment, ok := strings.Cut(output, ","); ok {
+		if info.OutputLineIncrement, err = number(increment); err != nil {
+			return info, err
+		}
+		output = start
+	}
+	if info.OutputStartLine, err = number(output); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// SourcePosition returns the source file and line of the instruction at pc in
+// code, a Code attribute of this class. When the class has a source map whose
+// default stratum is not Java, the line is translated through it, so code
+// inlined by kotlinc or generated from a JSP points back at its real source.
+func (self ClassFile) SourcePosition(code CodeAttribute, pc uint16) (string, int, bool) {
+	line, ok := code.LineNumber(pc)
+	if !ok {
+		return "", 0, false
+	}
+	file, _ := self.SourceFile()
+	if ext, ok := FindAttribute[SourceDebugExtensionAttribute](self.Attributes); ok {
+		if smap, err := ext.SMAP(); err == nil && smap.DefaultStratum != "Java" {
+			if stratum, ok := smap.Stratum(""); ok {
+				if f, input, ok := stratum.Map(line); ok {
+					return f.Name, input, true
+				}
+			}
+		}
+	}
+	return file, line, true
+}
@@ -0,0 +1,78 @@
+package classfile
+
+import "testing"
+
+const kotlinSMAP = `SMAP
+Main.kt
+Kotlin
+*S Kotlin
+*F
++ 1 Main.kt
+MainKt
++ 2 Util.kt
+com/x/UtilKt
+*L
+1#1,10:1
+5#2,2:20,3
+*E
+*S KotlinDebug
+*F
++ 1 Main.kt
+MainKt
+*L
+4#1:20
+*E
+`
+
+func TestParseSMAP(t *testing.T) {
+	smap, err := ParseSMAP(kotlinSMAP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if smap.OutputFileName != "Main.kt" || len(smap.Strata) != 2 {
+		t.Fatalf("got %+v", smap)
+	}
+	stratum, ok := smap.Stratum("")
+	if !ok || stratum.Name != "Kotlin" {
+		t.Fatalf("default stratum: got %+v", stratum)
+	}
+	if stratum.Files[1].Path != "com/x/UtilKt" {
+		t.Errorf("path: got %q", stratum.Files[1].Path)
+	}
+	tests := []struct {
+		output int
+		file   string
+		line   int
+		ok     bool
+	}{
+		{1, "Main.kt", 1, true},
+		{10, "Main.kt", 10, true},
+		{11, "", 0, false},
+		{20, "Util.kt", 5, true},
+		{25, "Util.kt", 6, true},
+		{26, "", 0, false},
+	}
+	for _, tt := range tests {
+		file, line, ok := stratum.Map(tt.output)
+		if file.Name != tt.file || line != tt.line || ok != tt.ok {
+			t.Errorf("Map(%d) = %q, %d, %v; want %q, %d, %v", tt.output, file.Name, line, ok, tt.file, tt.line, tt.ok)
+		}
+	}
+
+	code := CodeAttribute{Attributes: []AttributeInfo{
+		LineNumberTableAttribute{AttributeHeader{}, []LineNumberTableEntry{{0, 3}, {4, 21}}},
+	}}
+	cf := ClassFile{Attributes: []AttributeInfo{
+		SourceFileAttribute{AttributeHeader{}, 0, "Main.kt"},
+		SourceDebugExtensionAttribute{AttributeHeader{}, []byte(kotlinSMAP)},
+	}}
+	if file, line, ok := cf.SourcePosition(code, 5); file != "Util.kt" || line != 5 || !ok {
+		t.Errorf("SourcePosition(5) = %q, %d, %v", file, line, ok)
+	}
+
+	for _, bad := range []string{"", "SMAP\nA.java\nJava\n*L\n1:1\n*E", "SMAP\nA.java\nJSP\n*S JSP\n*L\n1,x:1\n*E", "SMAP\nA.java\nJava\n"} {
+		if _, err := ParseSMAP(bad); err == nil {
+			t.Errorf("ParseSMAP(%q): expected an error", bad)
+		}
+	}
+}
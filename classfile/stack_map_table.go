@@ -0,0 +1,343 @@
+package classfile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.4
+//
+//	StackMapTable_attribute {
+//	    u2              attribute_name_index;
+//	    u4              attribute_length;
+//	    u2              number_of_entries;
+//	    stack_map_frame entries[number_of_entries];
+//	}
+type StackMapTableAttribute struct {
+	AttributeHeader
+	NumberOfEntries uint16
+	Entries         []StackMapFrame
+}
+
+func (self StackMapTableAttribute) String() string {
+	frames := []string{}
+	for _, frame := range self.Entries {
+		frames = append(frames, frame.String())
+	}
+	s := fmt.Sprintf("StackMapTable: number_of_entries=%d", self.NumberOfEntries)
+	if len(frames) > 0 {
+		s = s + "\n    " + strings.Join(frames, "\n    ")
+	}
+	return s
+}
+
+// FrameAt returns the frame that applies at exactly bytecode offset pc, if any.
+func (self StackMapTableAttribute) FrameAt(pc uint16) (StackMapFrame, bool) {
+	for _, frame := range self.Entries {
+		if frame.Header().Offset == pc {
+			return frame, true
+		}
+	}
+	return nil, false
+}
+
+//	union stack_map_frame {
+//	    same_frame;
+//	    same_locals_1_stack_item_frame;
+//	    same_locals_1_stack_item_frame_extended;
+//	    chop_frame;
+//	    same_frame_extended;
+//	    append_frame;
+//	    full_frame;
+//	}
+//
+// The extended forms share a type with their short form and differ only in
+// FrameType, which is kept so the frame can be written back as it was read.
+type StackMapFrame interface {
+	Header() FrameHeader
+	String() string
+}
+
+type FrameHeader struct {
+	FrameType   uint8
+	OffsetDelta uint16
+	// Offset is the absolute bytecode offset the frame applies to: the first
+	// frame is at offset_delta, each later one at previous + offset_delta + 1.
+	Offset uint16
+}
+
+func (self FrameHeader) Header() FrameHeader {
+	return self
+}
+
+// Frame type ranges
+const (
+	FrameTypeSameMax                      = 63
+	FrameTypeSameLocals1StackItemMax      = 127
+	FrameTypeSameLocals1StackItemExtended = 247
+	FrameTypeChopMin                      = 248
+	FrameTypeChopMax                      = 250
+	FrameTypeSameExtended                 = 251
+	FrameTypeAppendMin                    = 252
+	FrameTypeAppendMax                    = 254
+	FrameTypeFull                         = 255
+)
+
+//	same_frame {
+//	    u1 frame_type = SAME; /* 0-63 */
+//	}
+//
+//	same_frame_extended {
+//	    u1 frame_type = SAME_FRAME_EXTENDED; /* 251 */
+//	    u2 offset_delta;
+//	}
+type SameFrame struct {
+	FrameHeader
+}
+
+func (self SameFrame) String() string {
+	return fmt.Sprintf("frame_type=%d /* same */ offset=%d", self.FrameType, self.Offset)
+}
+
+//	same_locals_1_stack_item_frame {
+//	    u1 frame_type = SAME_LOCALS_1_STACK_ITEM; /* 64-127 */
+//	    verification_type_info stack[1];
+//	}
+//
+//	same_locals_1_stack_item_frame_extended {
+//	    u1 frame_type = SAME_LOCALS_1_STACK_ITEM_EXTENDED; /* 247 */
+//	    u2 offset_delta;
+//	    verification_type_info stack[1];
+//	}
+type SameLocals1StackItemFrame struct {
+	FrameHeader
+	Stack VerificationTypeInfo
+}
+
+func (self SameLocals1StackItemFrame) String() string {
+	return fmt.Sprintf("frame_type=%d /* same_locals_1_stack_item */ offset=%d, stack=[ %s ]", self.FrameType, self.Offset, self.Stack)
+}
+
+//	chop_frame {
+//	    u1 frame_type = CHOP; /* 248-250 */
+//	    u2 offset_delta;
+//	}
+type ChopFrame struct {
+	FrameHeader
+}
+
+// Chopped is the number of last locals that are absent, 1 to 3.
+func (self ChopFrame) Chopped() int {
+	return 251 - int(self.FrameType)
+}
+
+func (self ChopFrame) String() string {
+	return fmt.Sprintf("frame_type=%d /* chop */ offset=%d", self.FrameType, self.Offset)
+}
+
+//	append_frame {
+//	    u1 frame_type = APPEND; /* 252-254 */
+//	    u2 offset_delta;
+//	    verification_type_info locals[frame_type - 251];
+//	}
+type AppendFrame struct {
+	FrameHeader
+	Locals []VerificationTypeInfo
+}
+
+func (self AppendFrame) String() string {
+	return fmt.Sprintf("frame_type=%d /* append */ offset=%d, locals=%s", self.FrameType, self.Offset, verificationTypes(self.Locals))
+}
+
+//	full_frame {
+//	    u1 frame_type = FULL_FRAME; /* 255 */
+//	    u2 offset_delta;
+//	    u2 number_of_locals;
+//	    verification_type_info locals[number_of_locals];
+//	    u2 number_of_stack_items;
+//	    verification_type_info stack[number_of_stack_items];
+//	}
+type FullFrame struct {
+	FrameHeader
+	Locals []VerificationTypeInfo
+	Stack  []VerificationTypeInfo
+}
+
+func (self FullFrame) String() string {
+	return fmt.Sprintf("frame_type=%d /* full_frame */ offset=%d, locals=%s, stack=%s", self.FrameType, self.Offset, verificationTypes(self.Locals), verificationTypes(self.Stack))
+}
+
+// verification_type_info tags
+const (
+	ItemTop               = 0
+	ItemInteger           = 1
+	ItemFloat             = 2
+	ItemDouble            = 3
+	ItemLong              = 4
+	ItemNull              = 5
+	ItemUninitializedThis = 6
+	ItemObject            = 7
+	ItemUninitialized     = 8
+)
+
+//	union verification_type_info {
+//	    Top_variable_info;
+//	    Integer_variable_info;
+//	    Float_variable_info;
+//	    Long_variable_info;
+//	    Double_variable_info;
+//	    Null_variable_info;
+//	    UninitializedThis_variable_info;
+//	    Object_variable_info;
+//	    Uninitialized_variable_info;
+//	}
+type VerificationTypeInfo struct {
+	Tag uint8
+	// CpoolIndex is the CONSTANT_Class of an ItemObject.
+	CpoolIndex uint16
+	// Offset is the offset of the `new` instruction of an ItemUninitialized.
+	Offset uint16
+}
+
+func (self VerificationTypeInfo) String() string {
+	switch self.Tag {
+	case ItemTop:
+		return "top"
+	case ItemInteger:
+		return "int"
+	case ItemFloat:
+		return "float"
+	case ItemDouble:
+		return "double"
+	case ItemLong:
+		return "long"
+	case ItemNull:
+		return "null"
+	case ItemUninitializedThis:
+		return "uninitialized_this"
+	case ItemObject:
+		return fmt.Sprintf("class #%d", self.CpoolIndex)
+	default:
+		return fmt.Sprintf("uninitialized %d", self.Offset)
+	}
+}
+
+func verificationTypes(types []VerificationTypeInfo) string {
+	s := []string{}
+	for _, t := range types {
+		s = append(s, t.String())
+	}
+	return fmt.Sprintf("[ %s ]", strings.Join(s, ", "))
+}
+
+func (self ClassFileParser) parseStackMapTableAttribute() (StackMapTableAttribute, error) {
+	count, err := self.reader.ReadU2()
+	if err != nil {
+		return StackMapTableAttribute{}, within(err, "number_of_entries")
+	}
+	entries := make([]StackMapFrame, count)
+	offset := -1
+	for i := range entries {
+		frame, err := self.parseStackMapFrame(offset)
+		if err != nil {
+			return StackMapTableAttribute{}, within(err, "entries[%d]", i)
+		}
+		entries[i] = frame
+		offset = int(frame.Header().Offset)
+	}
+	return StackMapTableAttribute{self.header, count, entries}, nil
+}
+
+// parseStackMapFrame reads one frame; previous is the offset of the preceding
+// frame, or -1 for the first one.
+func (self ClassFileParser) parseStackMapFrame(previous int) (StackMapFrame, error) {
+	frameType, err := self.reader.ReadU1()
+	if err != nil {
+		return nil, within(err, "frame_type")
+	}
+	header := FrameHeader{FrameType: frameType}
+	switch {
+	case frameType <= FrameTypeSameMax:
+		header.OffsetDelta = uint16(frameType)
+	case frameType <= FrameTypeSameLocals1StackItemMax:
+		header.OffsetDelta = uint16(frameType - 64)
+	case frameType < FrameTypeSameLocals1StackItemExtended:
+		return nil, &ClassFormatError{Offset: self.reader.Offset() - 1, Msg: fmt.Sprintf("reserved frame_type %d", frameType)}
+	default:
+		if header.OffsetDelta, err = self.reader.ReadU2(); err != nil {
+			return nil, within(err, "offset_delta")
+		}
+	}
+	offset := previous + int(header.OffsetDelta) + 1
+	if offset > 0xFFFF {
+		return nil, self.errorf("frame offset %d out of range", offset)
+	}
+	header.Offset = uint16(offset)
+
+	switch {
+	case frameType <= FrameTypeSameMax, frameType == FrameTypeSameExtended:
+		return SameFrame{header}, nil
+	case frameType <= FrameTypeSameLocals1StackItemMax, frameType == FrameTypeSameLocals1StackItemExtended:
+		stack, err := self.parseVerificationTypeInfo()
+		if err != nil {
+			return nil, within(err, "stack[0]")
+		}
+		return SameLocals1StackItemFrame{header, stack}, nil
+	case frameType <= FrameTypeChopMax:
+		return ChopFrame{header}, nil
+	case frameType <= FrameTypeAppendMax:
+		locals, err := self.parseVerificationTypeInfos(int(frameType)-251, "locals")
+		if err != nil {
+			return nil, err
+		}
+		return AppendFrame{header, locals}, nil
+	default:
+		numLocals, err := self.reader.ReadU2()
+		if err != nil {
+			return nil, within(err, "number_of_locals")
+		}
+		locals, err := self.parseVerificationTypeInfos(int(numLocals), "locals")
+		if err != nil {
+			return nil, err
+		}
+		numStack, err := self.reader.ReadU2()
+		if err != nil {
+			return nil, within(err, "number_of_stack_items")
+		}
+		stack, err := self.parseVerificationTypeInfos(int(numStack), "stack")
+		if err != nil {
+			return nil, err
+		}
+		return FullFrame{header, locals, stack}, nil
+	}
+}
+
+func (self ClassFileParser) parseVerificationTypeInfos(n int, name string) ([]VerificationTypeInfo, error) {
+	types := make([]VerificationTypeInfo, n)
+	for i := range types {
+		t, err := self.parseVerificationTypeInfo()
+		if err != nil {
+			return nil, within(err, "%s[%d]", name, i)
+		}
+		types[i] = t
+	}
+	return types, nil
+}
+
+func (self ClassFileParser) parseVerificationTypeInfo() (VerificationTypeInfo, error) {
+	tag, err := self.reader.ReadU1()
+	if err != nil {
+		return VerificationTypeInfo{}, err
+	}
+	info := VerificationTypeInfo{Tag: tag}
+	switch tag {
+	case ItemTop, ItemInteger, ItemFloat, ItemDouble, ItemLong, ItemNull, ItemUninitializedThis:
+	case ItemObject:
+		info.CpoolIndex, err = self.reader.ReadU2()
+	case ItemUninitialized:
+		info.Offset, err = self.reader.ReadU2()
+	default:
+		return info, &ClassFormatError{Offset: self.reader.Offset() - 1, Msg: fmt.Sprintf("unknown verification_type_info tag %d", tag)}
+	}
+	return info, err
+}
@@ -0,0 +1,53 @@
+package classfile
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseStackMapTable(t *testing.T) {
+	b := []byte{
+		0, 7, // number_of_entries
+		5,         // same_frame, delta 5
+		64 + 2, 1, // same_locals_1_stack_item_frame, delta 2, int
+		247, 0, 10, 7, 0, 3, // same_locals_1_stack_item_frame_extended, delta 10, class #3
+		249, 0, 0, // chop 2
+		251, 1, 0, // same_frame_extended, delta 256
+		253, 0, 1, 4, 8, 0, 12, // append long, uninitialized 12
+		255, 0, 3, 0, 2, 6, 2, 0, 1, 5, // full_frame: [uninitialized_this, float], [null]
+	}
+	parser := ClassFileParser{reader: newClassReader(bytes.NewReader(b))}
+	attr, err := parser.parseStackMapTableAttribute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOffsets := []uint16{5, 8, 19, 20, 277, 279, 283}
+	for i, frame := range attr.Entries {
+		if got := frame.Header().Offset; got != wantOffsets[i] {
+			t.Errorf("entries[%d]: offset %d, want %d", i, got, wantOffsets[i])
+		}
+	}
+	if f, ok := attr.Entries[2].(SameLocals1StackItemFrame); !ok || f.Stack.Tag != ItemObject || f.Stack.CpoolIndex != 3 {
+		t.Errorf("entries[2]: got %v", attr.Entries[2])
+	}
+	if f, ok := attr.Entries[3].(ChopFrame); !ok || f.Chopped() != 2 {
+		t.Errorf("entries[3]: got %v", attr.Entries[3])
+	}
+	if f, ok := attr.Entries[5].(AppendFrame); !ok || len(f.Locals) != 2 || f.Locals[1].Offset != 12 {
+		t.Errorf("entries[5]: got %v", attr.Entries[5])
+	}
+	want := "frame_type=255 /* full_frame */ offset=283, locals=[ uninitialized_this, float ], stack=[ null ]"
+	if got := attr.Entries[6].String(); got != want {
+		t.Errorf("entries[6]: got %q", got)
+	}
+	if _, ok := attr.FrameAt(277); !ok {
+		t.Errorf("FrameAt(277) not found")
+	}
+}
+
+func TestParseStackMapTableReservedFrameType(t *testing.T) {
+	parser := ClassFileParser{reader: newClassReader(bytes.NewReader([]byte{0, 1, 128}))}
+	if _, err := parser.parseStackMapTableAttribute(); err == nil {
+		t.Errorf("expected frame_type 128 to be rejected")
+	}
+}
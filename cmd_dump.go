@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"gjvm/classfile"
+	"gjvm/javap"
+)
+
+// runDump implements `gjvm dump [--format=text|json] <files>`. The text format
+// is javap -v -p; json is the stable form of classfile.ClassFile.MarshalJSON.
+func runDump(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: gjvm dump [--format=text|json] <files>")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "text", "output format, text or json")
+	if err := flags.Parse(optionsFirst(flags, args)); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 || (*format != "text" && *format != "json") {
+		flags.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		if err := dumpClass(path, *format, stdout); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func dumpClass(path, format string, w io.Writer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	cf, err := classfile.NewClassFileParser(file).Parse()
+	if err != nil {
+		return err
+	}
+	if format == "text" {
+		return javap.Print(w, cf)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cf)
+}
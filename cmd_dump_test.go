@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func runDumpArgs(args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	status := runDump(args, &stdout, &stderr)
+	return stdout.String(), stderr.String(), status
+}
+
+func TestRunDump(t *testing.T) {
+	out, errs, status := runDumpArgs("java/Hello.class")
+	if status != 0 || !strings.Contains(out, "  major version: 61\n") {
+		t.Errorf("text: status %d, got %q%s", status, out, errs)
+	}
+
+	for _, args := range [][]string{
+		{"--format=json", "java/Hello.class"},
+		{"java/Hello.class", "-format", "json"},
+	} {
+		out, errs, status := runDumpArgs(args...)
+		var cf struct{ MajorVersion int }
+		if status != 0 {
+			t.Errorf("%q: status %d, %s", args, status, errs)
+		} else if err := json.Unmarshal([]byte(out), &cf); err != nil || cf.MajorVersion != 61 {
+			t.Errorf("%q: got %+v, %v", args, cf, err)
+		}
+	}
+}
+
+func TestRunDumpErrors(t *testing.T) {
+	if _, errs, status := runDumpArgs("nonexist.class"); status != 1 || !strings.HasPrefix(errs, "nonexist.class: open nonexist.class:") {
+		t.Errorf("missing file: status %d, stderr %q", status, errs)
+	}
+	if _, errs, status := runDumpArgs("--format=xml", "java/Hello.class"); status != 2 || !strings.HasPrefix(errs, "usage: gjvm dump") {
+		t.Errorf("bad format: status %d, stderr %q", status, errs)
+	}
+}
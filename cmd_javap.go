@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gjvm/classfile"
+	"gjvm/javap"
+)
+
+// runJavap implements `gjvm javap [options] <classes>`, which accepts the
+// usual javap flags and prints each class as javap would.
+func runJavap(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("javap", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: gjvm javap [options] <classes>")
+		flags.PrintDefaults()
+	}
+	var opts javap.Options
+	flags.BoolVar(&opts.Code, "c", false, "disassemble the code")
+	flags.BoolVar(&opts.Verbose, "v", false, "print additional information")
+	flags.BoolVar(&opts.Verbose, "verbose", false, "print additional information")
+	flags.BoolVar(&opts.Private, "p", false, "show all classes and members")
+	flags.BoolVar(&opts.Private, "private", false, "show all classes and members")
+	flags.BoolVar(&opts.Signatures, "s", false, "print internal type signatures")
+	flags.BoolVar(&opts.Lines, "l", false, "print line number and local variable tables")
+	flags.BoolVar(&opts.Constants, "constants", false, "show final constants")
+	classpath := "."
+	flags.StringVar(&classpath, "cp", classpath, "where to find classes given by name")
+	flags.StringVar(&classpath, "classpath", classpath, "where to find classes given by name")
+	if err := flags.Parse(optionsFirst(flags, args)); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, class := range flags.Args() {
+		if err := javapClass(class, classpath, opts, stdout); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// optionsFirst moves the options in args in front of the operands, which
+// flag.Parse requires but javap does not: `javap Foo.class -c` is valid.
+// Options that take a value keep the argument after them, and "--" still
+// ends the options.
+func optionsFirst(flags *flag.FlagSet, args []string) []string {
+	var options, operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			operands = append(operands, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			operands = append(operands, arg)
+			continue
+		}
+		options = append(options, arg)
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := flags.Lookup(name); f != nil && i+1 < len(args) {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				i++
+				options = append(options, args[i])
+			}
+		}
+	}
+	return append(append(options, "--"), operands...)
+}
+
+func javapClass(class, classpath string, opts javap.Options, w io.Writer) error {
+	path, err := findClassFile(class, classpath)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	cf, err := classfile.NewClassFileParser(bytes.NewReader(data)).Parse()
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if opts.Verbose {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		opts.Classfile = javap.NewClassfile(path, stat.ModTime(), data)
+	}
+	return opts.Print(w, cf)
+}
+
+// findClassFile resolves a javap argument, either a path to a class file or
+// a class name such as java.lang.Object, which is looked up on the
+// colon-separated classpath.
+func findClassFile(class, classpath string) (string, error) {
+	if strings.HasSuffix(class, ".class") {
+		return class, nil
+	}
+	name := strings.ReplaceAll(class, ".", "/") + ".class"
+	for _, dir := range filepath.SplitList(classpath) {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("class not found: %s", class)
+}
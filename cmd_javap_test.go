@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func runJavapArgs(args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	status := runJavap(args, &stdout, &stderr)
+	return stdout.String(), stderr.String(), status
+}
+
+func TestRunJavap(t *testing.T) {
+	want, _, status := runJavapArgs("-c", "java/Hello.class")
+	if status != 0 || !strings.Contains(want, "       0: aload_0\n") {
+		t.Fatalf("status %d, got %q", status, want)
+	}
+	// javap takes options before, after and between the classes
+	for _, args := range [][]string{
+		{"java/Hello.class", "-c"},
+		{"-cp", "java", "Hello", "-c"},
+		{"Hello", "-classpath", "java", "-c"},
+		{"-classpath=java", "-c", "Hello"},
+	} {
+		if out, errs, status := runJavapArgs(args...); status != 0 || out != want {
+			t.Errorf("%q: status %d, got %q%s", args, status, out, errs)
+		}
+	}
+}
+
+func TestRunJavapErrors(t *testing.T) {
+	out, errs, status := runJavapArgs("java/Hello.class", "-cp", "java", "Nope")
+	if status != 1 || !strings.HasPrefix(out, "Compiled from") || errs != "Error: class not found: Nope\n" {
+		t.Errorf("missing class: status %d, stdout %q, stderr %q", status, out, errs)
+	}
+	if _, errs, status := runJavapArgs("-c"); status != 2 || !strings.HasPrefix(errs, "usage: gjvm javap") {
+		t.Errorf("no classes: status %d, stderr %q", status, errs)
+	}
+	if _, _, status := runJavapArgs("-x", "java/Hello.class"); status != 2 {
+		t.Errorf("unknown option: status %d", status)
+	}
+}
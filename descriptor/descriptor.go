@@ -0,0 +1,252 @@
+// Package descriptor parses field and method descriptors.
+//
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.3
+//
+//	FieldDescriptor:  FieldType
+//	FieldType:        BaseType | ObjectType | ArrayType
+//	BaseType:         B | C | D | F | I | J | S | Z
+//	ObjectType:       L ClassName ;
+//	ArrayType:        [ ComponentType
+//	MethodDescriptor: ( {ParameterDescriptor} ) ReturnDescriptor
+//	ReturnDescriptor: FieldType | V
+package descriptor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldType is one of BaseType, ObjectType or ArrayType. Void is a BaseType as
+// well, but only appears as the return type of a method.
+type FieldType interface {
+	// Descriptor renders the type back into descriptor form, e.g. "[Ljava/lang/String;".
+	Descriptor() string
+	// String renders the type as Java source would, e.g. "java.lang.String[]".
+	String() string
+	// Slots is the number of local variable / operand stack slots a value of
+	// this type takes: 2 for long and double, 0 for void, 1 otherwise.
+	Slots() int
+}
+
+type BaseType byte
+
+const (
+	Byte    BaseType = 'B'
+	Char    BaseType = 'C'
+	Double  BaseType = 'D'
+	Float   BaseType = 'F'
+	Int     BaseType = 'I'
+	Long    BaseType = 'J'
+	Short   BaseType = 'S'
+	Boolean BaseType = 'Z'
+	Void    BaseType = 'V'
+)
+
+var baseTypeNames = map[BaseType]string{
+	Byte:    "byte",
+	Char:    "char",
+	Double:  "double",
+	Float:   "float",
+	Int:     "int",
+	Long:    "long",
+	Short:   "short",
+	Boolean: "boolean",
+	Void:    "void",
+}
+
+func (self BaseType) Descriptor() string {
+	return string(rune(self))
+}
+func (self BaseType) String() string {
+	return baseTypeNames[self]
+}
+func (self BaseType) Slots() int {
+	switch self {
+	case Long, Double:
+		return 2
+	case Void:
+		return 0
+	}
+	return 1
+}
+
+// ObjectType is a class or interface type. ClassName is in internal form,
+// e.g. "java/lang/String".
+type ObjectType struct {
+	ClassName string
+}
+
+func (self ObjectType) Descriptor() string {
+	return "L" + self.ClassName + ";"
+}
+func (self ObjectType) String() string {
+	return strings.ReplaceAll(self.ClassName, "/", ".")
+}
+func (self ObjectType) Slots() int {
+	return 1
+}
+
+type ArrayType struct {
+	ComponentType FieldType
+}
+
+func (self ArrayType) Descriptor() string {
+	return "[" + self.ComponentType.Descriptor()
+}
+func (self ArrayType) String() string {
+	return self.ComponentType.String() + "[]"
+}
+func (self ArrayType) Slots() int {
+	return 1
+}
+
+// Dimensions returns the number of array dimensions, e.g. 2 for "[[I".
+func (self ArrayType) Dimensions() int {
+	n := 1
+	for t, ok := self.ComponentType.(ArrayType); ok; t, ok = t.ComponentType.(ArrayType) {
+		n++
+	}
+	return n
+}
+
+type MethodDescriptor struct {
+	Parameters []FieldType
+	ReturnType FieldType
+}
+
+func (self MethodDescriptor) Descriptor() string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, p := range self.Parameters {
+		b.WriteString(p.Descriptor())
+	}
+	b.WriteByte(')')
+	b.WriteString(self.ReturnType.Descriptor())
+	return b.String()
+}
+
+// String renders the descriptor like javap does, e.g. "void (int, java.lang.String)".
+func (self MethodDescriptor) String() string {
+	params := make([]string, len(self.Parameters))
+	for i, p := range self.Parameters {
+		params[i] = p.String()
+	}
+	return fmt.Sprintf("%s (%s)", self.ReturnType, strings.Join(params, ", "))
+}
+
+// ParameterSlots is the number of local variable slots the parameters take,
+// not counting `this` for instance methods.
+func (self MethodDescriptor) ParameterSlots() int {
+	n := 0
+	for _, p := range self.Parameters {
+		n += p.Slots()
+	}
+	return n
+}
+
+// ParseFieldDescriptor parses a descriptor such as "I" or "[Ljava/lang/Object;".
+func ParseFieldDescriptor(s string) (FieldType, error) {
+	p := parser{s, 0}
+	t, err := p.fieldType()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(s) {
+		return nil, p.errorf("trailing characters")
+	}
+	return t, nil
+}
+
+// ParseMethodDescriptor parses a descriptor such as "(IJ[Ljava/lang/String;)V".
+func ParseMethodDescriptor(s string) (MethodDescriptor, error) {
+	p := parser{s, 0}
+	var md MethodDescriptor
+	if !p.consume('(') {
+		return md, p.errorf("expected '('")
+	}
+	md.Parameters = []FieldType{}
+	for !p.consume(')') {
+		t, err := p.fieldType()
+		if err != nil {
+			return md, err
+		}
+		md.Parameters = append(md.Parameters, t)
+	}
+	if p.consume('V') {
+		md.ReturnType = Void
+	} else {
+		t, err := p.fieldType()
+		if err != nil {
+			return md, err
+		}
+		md.ReturnType = t
+	}
+	if p.pos != len(s) {
+		return md, p.errorf("trailing characters")
+	}
+	// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.3.3
+	if md.ParameterSlots() > 255 {
+		return md, fmt.Errorf("invalid descriptor %q: more than 255 parameter slots", s)
+	}
+	return md, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (self *parser) errorf(format string, a ...any) error {
+	return fmt.Errorf("invalid descriptor %q at %d: %s", self.s, self.pos, fmt.Sprintf(format, a...))
+}
+
+func (self *parser) consume(c byte) bool {
+	if self.pos < len(self.s) && self.s[self.pos] == c {
+		self.pos++
+		return true
+	}
+	return false
+}
+
+func (self *parser) fieldType() (FieldType, error) {
+	if self.pos >= len(self.s) {
+		return nil, self.errorf("unexpected end")
+	}
+	c := self.s[self.pos]
+	switch BaseType(c) {
+	case Byte, Char, Double, Float, Int, Long, Short, Boolean:
+		self.pos++
+		return BaseType(c), nil
+	}
+	switch c {
+	case 'L':
+		end := strings.IndexByte(self.s[self.pos:], ';')
+		if end < 0 {
+			return nil, self.errorf("unterminated class name")
+		}
+		name := self.s[self.pos+1 : self.pos+end]
+		if name == "" || strings.ContainsAny(name, ".[") || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.Contains(name, "//") {
+			return nil, self.errorf("invalid class name %q", name)
+		}
+		self.pos += end + 1
+		return ObjectType{name}, nil
+	case '[':
+		dims := 0
+		for self.consume('[') {
+			dims++
+		}
+		// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.3.2
+		if dims > 255 {
+			return nil, self.errorf("more than 255 array dimensions")
+		}
+		t, err := self.fieldType()
+		if err != nil {
+			return nil, err
+		}
+		for ; dims > 0; dims-- {
+			t = ArrayType{t}
+		}
+		return t, nil
+	}
+	return nil, self.errorf("unexpected %q", c)
+}
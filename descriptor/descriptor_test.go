@@ -0,0 +1,65 @@
+package descriptor
+
+import (
+	"testing"
+)
+
+func TestParseMethodDescriptor(t *testing.T) {
+	cases := []struct {
+		desc   string
+		str    string
+		slots  int
+		result FieldType
+	}{
+		{"()V", "void ()", 0, Void},
+		{"(I)V", "void (int)", 1, Void},
+		{"(Ljava/lang/String;)V", "void (java.lang.String)", 1, Void},
+		{"(IDJ[[Ljava/lang/Object;Z)[I", "int[] (int, double, long, java.lang.Object[][], boolean)", 7, ArrayType{Int}},
+		{"([Ljava/lang/String;)Ljava/lang/String;", "java.lang.String (java.lang.String[])", 1, ObjectType{"java/lang/String"}},
+	}
+	for _, c := range cases {
+		md, err := ParseMethodDescriptor(c.desc)
+		if err != nil {
+			t.Errorf("%s: %v", c.desc, err)
+			continue
+		}
+		if md.String() != c.str {
+			t.Errorf("%s: String() got %q, want %q", c.desc, md.String(), c.str)
+		}
+		if md.ParameterSlots() != c.slots {
+			t.Errorf("%s: ParameterSlots() got %d, want %d", c.desc, md.ParameterSlots(), c.slots)
+		}
+		if md.ReturnType != c.result {
+			t.Errorf("%s: ReturnType got %v, want %v", c.desc, md.ReturnType, c.result)
+		}
+		if md.Descriptor() != c.desc {
+			t.Errorf("%s: Descriptor() got %q", c.desc, md.Descriptor())
+		}
+	}
+}
+
+func TestParseFieldDescriptor(t *testing.T) {
+	ft, err := ParseFieldDescriptor("[[J")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ft.String() != "long[][]" || ft.(ArrayType).Dimensions() != 2 || ft.Slots() != 1 {
+		t.Errorf("got %v", ft)
+	}
+	if ft, _ := ParseFieldDescriptor("D"); ft.Slots() != 2 {
+		t.Errorf("double should take 2 slots")
+	}
+}
+
+func TestParseInvalidDescriptors(t *testing.T) {
+	for _, desc := range []string{"", "V", "Q", "L;", "Ljava/lang/String", "Ljava.lang.String;", "[", "II", "[V"} {
+		if _, err := ParseFieldDescriptor(desc); err == nil {
+			t.Errorf("field %q: expected an error", desc)
+		}
+	}
+	for _, desc := range []string{"", "V", "()", "(V)V", "(I", "(I)VV", "I)V", "()[V"} {
+		if _, err := ParseMethodDescriptor(desc); err == nil {
+			t.Errorf("method %q: expected an error", desc)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package javap
+
+import (
+	"fmt"
+
+	"gjvm/bytecode"
+)
+
+// instructions writes the disassembly of code, one instruction per line:
+//
+//	0: getstatic     #7                  // Field java/lang/System.out:Ljava/io/PrintStream;
+func (self *printer) instructions(indent int, code []byte) error {
+	insns, err := bytecode.DecodeAll(code)
+	for _, insn := range insns {
+		self.instruction(indent, insn)
+	}
+	return err
+}
+
+func (self *printer) instruction(indent int, insn bytecode.Instruction) {
+	name := insn.Opcode.String()
+	if insn.Wide {
+		name += "_w"
+	}
+	head := fmt.Sprintf("%4d: ", insn.PC)
+	operands := func(operands, comment string) {
+		self.line(indent, "%s", withComment(fmt.Sprintf("%s%-13s %s", head, name, operands), comment))
+	}
+	constant := func() string { return self.constant(uint16(insn.Index)) }
+
+	switch op := insn.Opcode; {
+	case op == bytecode.BIPUSH, op == bytecode.SIPUSH:
+		operands(fmt.Sprint(insn.Value), "")
+	case op == bytecode.LDC, op == bytecode.LDC_W, op == bytecode.LDC2_W,
+		op >= bytecode.GETSTATIC && op <= bytecode.INVOKESTATIC,
+		op == bytecode.NEW, op == bytecode.ANEWARRAY,
+		op == bytecode.CHECKCAST, op == bytecode.INSTANCEOF:
+		operands(fmt.Sprintf("#%d", insn.Index), constant())
+	case op >= bytecode.ILOAD && op <= bytecode.ALOAD,
+		op >= bytecode.ISTORE && op <= bytecode.ASTORE, op == bytecode.RET:
+		operands(fmt.Sprint(insn.Index), "")
+	case op == bytecode.IINC:
+		operands(fmt.Sprintf("%d, %d", insn.Index, insn.Value), "")
+	case op == bytecode.TABLESWITCH:
+		self.line(indent, "%s%-13s { // %d to %d", head, name, insn.Low, insn.High)
+		self.switchCases(indent, insn)
+	case op == bytecode.LOOKUPSWITCH:
+		self.line(indent, "%s%-13s { // %d", head, name, len(insn.Keys))
+		self.switchCases(indent, insn)
+	case insn.IsBranch():
+		operands(fmt.Sprint(insn.Target), "")
+	case op == bytecode.INVOKEINTERFACE, op == bytecode.MULTIANEWARRAY:
+		operands(fmt.Sprintf("#%d,  %d", insn.Index, insn.Value), constant())
+	case op == bytecode.INVOKEDYNAMIC:
+		operands(fmt.Sprintf("#%d,  0", insn.Index), constant())
+	case op == bytecode.NEWARRAY:
+		t, ok := bytecode.ArrayTypeName(insn.Value)
+		if !ok {
+			t = fmt.Sprintf("<unknown type %d>", insn.Value)
+		}
+		operands(t, "")
+	default:
+		self.line(indent, "%s%s", head, name)
+	}
+}
+
+func (self *printer) switchCases(indent int, insn bytecode.Instruction) {
+	for i, target := range insn.Targets {
+		self.line(indent, "%18d: %d", insn.Key(i), target)
+	}
+	self.line(indent, "%18s: %d", "default", insn.Target)
+	self.line(indent, "      }")
+}
@@ -0,0 +1,222 @@
+package javap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gjvm/classfile"
+)
+
+// The helpers below never fail: a reference to a missing or mistyped entry is
+// rendered as "<invalid #n>" so that a damaged class can still be inspected.
+
+func (self *printer) invalid(index uint16) string {
+	return fmt.Sprintf("<invalid #%d>", index)
+}
+
+func (self *printer) utf8(index uint16) string {
+	if self.cf.ConstantPool.IsUsable(index) {
+		if info, ok := self.cf.ConstantPool[index].(*classfile.ConstantUtf8Info); ok {
+			return info.Value()
+		}
+	}
+	return self.invalid(index)
+}
+
+// className returns the name of a CONSTANT_Class, quoted when it is an array
+// descriptor as javap does: "[Ljava/lang/String;" with the quotes.
+func (self *printer) className(index uint16) string {
+	if self.cf.ConstantPool.IsUsable(index) {
+		if info, ok := self.cf.ConstantPool[index].(*classfile.ConstantClassInfo); ok {
+			return quoteClassName(self.utf8(info.NameIndex))
+		}
+	}
+	return self.invalid(index)
+}
+
+func quoteClassName(name string) string {
+	if strings.HasPrefix(name, "[") {
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+// quoteName quotes the special method names "<init>" and "<clinit>".
+func quoteName(name string) string {
+	if strings.HasPrefix(name, "<") {
+		return `"` + name + `"`
+	}
+	return name
+}
+
+// nameAndType renders a CONSTANT_NameAndType as "name:descriptor".
+func (self *printer) nameAndType(index uint16) string {
+	if self.cf.ConstantPool.IsUsable(index) {
+		if info, ok := self.cf.ConstantPool[index].(*classfile.ConstantNameAndTypeInfo); ok {
+			return quoteName(self.utf8(info.NameIndex)) + ":" + self.utf8(info.DescriptorIndex)
+		}
+	}
+	return self.invalid(index)
+}
+
+// memberRef renders a Fieldref, Methodref or InterfaceMethodref as
+// "class.name:descriptor". The class is left out when omitThis is set and it
+// is the class being printed, as javap does in bytecode comments.
+func (self *printer) memberRef(classIndex, nameAndTypeIndex uint16, omitThis bool) string {
+	if omitThis && classIndex == self.cf.ThisClass {
+		return self.nameAndType(nameAndTypeIndex)
+	}
+	return self.className(classIndex) + "." + self.nameAndType(nameAndTypeIndex)
+}
+
+func (self *printer) methodHandle(info *classfile.ConstantMethodHandleInfo, omitThis bool) string {
+	return info.ReferenceKindName() + " " + self.reference(info.ReferenceIndex, omitThis)
+}
+
+// reference renders the member a method handle points at.
+func (self *printer) reference(index uint16, omitThis bool) string {
+	if self.cf.ConstantPool.IsUsable(index) {
+		switch info := self.cf.ConstantPool[index].(type) {
+		case *classfile.ConstantFieldrefInfo:
+			return self.memberRef(info.ClassIndex, info.NameAndTypeIndex, omitThis)
+		case *classfile.ConstantMethodrefInfo:
+			return self.memberRef(info.ClassIndex, info.NameAndTypeIndex, omitThis)
+		case *classfile.ConstantInterfaceMethodrefInfo:
+			return self.memberRef(info.ClassIndex, info.NameAndTypeIndex, omitThis)
+		}
+	}
+	return self.invalid(index)
+}
+
+// constantEntry describes constant pool entry index as the three columns of
+// javap's "Constant pool:" listing, e.g. "Methodref", "#2.#3" and
+// `java/lang/Object."<init>":()V`.
+func (self *printer) constantEntry(index uint16) (kind, args, comment string) {
+	switch info := self.cf.ConstantPool[index].(type) {
+	case *classfile.ConstantClassInfo:
+		return "Class", fmt.Sprintf("#%d", info.NameIndex), quoteClassName(self.utf8(info.NameIndex))
+	case *classfile.ConstantFieldrefInfo:
+		return "Fieldref", fmt.Sprintf("#%d.#%d", info.ClassIndex, info.NameAndTypeIndex), self.memberRef(info.ClassIndex, info.NameAndTypeIndex, false)
+	case *classfile.ConstantMethodrefInfo:
+		return "Methodref", fmt.Sprintf("#%d.#%d", info.ClassIndex, info.NameAndTypeIndex), self.memberRef(info.ClassIndex, info.NameAndTypeIndex, false)
+	case *classfile.ConstantInterfaceMethodrefInfo:
+		return "InterfaceMethodref", fmt.Sprintf("#%d.#%d", info.ClassIndex, info.NameAndTypeIndex), self.memberRef(info.ClassIndex, info.NameAndTypeIndex, false)
+	case *classfile.ConstantStringInfo:
+		return "String", fmt.Sprintf("#%d", info.StringIndex), escape(self.utf8(info.StringIndex))
+	case *classfile.ConstantIntegerInfo:
+		return "Integer", strconv.Itoa(int(info.Value())), ""
+	case *classfile.ConstantFloatInfo:
+		return "Float", classfile.FormatJavaFloat(float64(info.Value()), 32) + "f", ""
+	case *classfile.ConstantLongInfo:
+		return "Long", strconv.FormatInt(info.Value(), 10) + "l", ""
+	case *classfile.ConstantDoubleInfo:
+		return "Double", classfile.FormatJavaFloat(info.Value(), 64) + "d", ""
+	case *classfile.ConstantNameAndTypeInfo:
+		return "NameAndType", fmt.Sprintf("#%d:#%d", info.NameIndex, info.DescriptorIndex), self.nameAndType(index)
+	case *classfile.ConstantUtf8Info:
+		return "Utf8", escape(info.Value()), ""
+	case *classfile.ConstantMethodHandleInfo:
+		return "MethodHandle", fmt.Sprintf("%d:#%d", info.ReferenceKind, info.ReferenceIndex), self.methodHandle(info, false)
+	case *classfile.ConstantMethodTypeInfo:
+		return "MethodType", fmt.Sprintf("#%d", info.DescriptorIndex), self.utf8(info.DescriptorIndex)
+	case *classfile.ConstantDynamicInfo:
+		return "Dynamic", fmt.Sprintf("#%d:#%d", info.BootstrapMethodAttrIndex, info.NameAndTypeIndex), fmt.Sprintf("#%d:%s", info.BootstrapMethodAttrIndex, self.nameAndType(info.NameAndTypeIndex))
+	case *classfile.ConstantInvokeDynamicInfo:
+		return "InvokeDynamic", fmt.Sprintf("#%d:#%d", info.BootstrapMethodAttrIndex, info.NameAndTypeIndex), fmt.Sprintf("#%d:%s", info.BootstrapMethodAttrIndex, self.nameAndType(info.NameAndTypeIndex))
+	case *classfile.ConstantModuleInfo:
+		return "Module", fmt.Sprintf("#%d", info.NameIndex), self.utf8(info.NameIndex)
+	case *classfile.ConstantPackageInfo:
+		return "Package", fmt.Sprintf("#%d", info.NameIndex), self.utf8(info.NameIndex)
+	}
+	return "Unknown", "", ""
+}
+
+// constant describes the entry an instruction or attribute refers to, as in
+// the comment of `getstatic #7 // Field java/lang/System.out:Ljava/io/PrintStream;`.
+func (self *printer) constant(index uint16) string {
+	if !self.cf.ConstantPool.IsUsable(index) {
+		return self.invalid(index)
+	}
+	switch info := self.cf.ConstantPool[index].(type) {
+	case *classfile.ConstantClassInfo:
+		return "class " + self.className(index)
+	case *classfile.ConstantFieldrefInfo:
+		return "Field " + self.memberRef(info.ClassIndex, info.NameAndTypeIndex, true)
+	case *classfile.ConstantMethodrefInfo:
+		return "Method " + self.memberRef(info.ClassIndex, info.NameAndTypeIndex, true)
+	case *classfile.ConstantInterfaceMethodrefInfo:
+		return "InterfaceMethod " + self.memberRef(info.ClassIndex, info.NameAndTypeIndex, true)
+	case *classfile.ConstantMethodHandleInfo:
+		return "MethodHandle " + self.methodHandle(info, true)
+	case *classfile.ConstantIntegerInfo:
+		return "int " + strconv.Itoa(int(info.Value()))
+	case *classfile.ConstantFloatInfo:
+		return "float " + classfile.FormatJavaFloat(float64(info.Value()), 32) + "f"
+	case *classfile.ConstantLongInfo:
+		return "long " + strconv.FormatInt(info.Value(), 10) + "l"
+	case *classfile.ConstantDoubleInfo:
+		return "double " + classfile.FormatJavaFloat(info.Value(), 64) + "d"
+	}
+	kind, _, comment := self.constantEntry(index)
+	return kind + " " + comment
+}
+
+// constantValue renders the ConstantValue of a field with descriptor desc as
+// javap -constants does: a Java literal of the field's type.
+func (self *printer) constantValue(desc string, index uint16) string {
+	if !self.cf.ConstantPool.IsUsable(index) {
+		return self.invalid(index)
+	}
+	switch info := self.cf.ConstantPool[index].(type) {
+	case *classfile.ConstantIntegerInfo:
+		switch v := info.Value(); desc {
+		case "Z":
+			return strconv.FormatBool(v != 0)
+		case "C":
+			switch r := rune(v); r {
+			case '\'':
+				return `'\''`
+			case '"':
+				return `'"'`
+			default:
+				return "'" + escape(string(r)) + "'"
+			}
+		}
+	case *classfile.ConstantStringInfo:
+		return `"` + escape(self.utf8(info.StringIndex)) + `"`
+	}
+	_, args, _ := self.constantEntry(index)
+	return args
+}
+
+// escape renders s with the escapes javap uses for Utf8 and String entries.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			if unicode.IsPrint(r) {
+				b.WriteRune(r)
+			} else {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,31 @@
+package javap
+
+import (
+	"fmt"
+	"strings"
+
+	"gjvm/classfile"
+)
+
+// flagNames renders flags the way javap's "flags:" line does:
+// "(0x0021) ACC_PUBLIC, ACC_SUPER".
+func flagNames(flags classfile.AccessFlags, table []classfile.AccessFlag) string {
+	names := []string{}
+	for _, f := range table {
+		if uint16(flags)&f.Mask != 0 {
+			names = append(names, f.Name)
+		}
+	}
+	return fmt.Sprintf("(0x%04x) %s", uint16(flags), strings.Join(names, ", "))
+}
+
+// modifiers returns the Java modifiers of flags, each followed by a space.
+func modifiers(flags classfile.AccessFlags, table []classfile.AccessFlag) string {
+	s := ""
+	for _, f := range table {
+		if uint16(flags)&f.Mask != 0 && f.Modifier != "" {
+			s += f.Modifier + " "
+		}
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package javap
+
+import (
+	"gjvm/classfile"
+)
+
+// moduleDirectives prints the body of a module declaration the way javap
+// does without -v, one directive per line:
+//
+//	requires java.base;
+//	exports com.foo.internal to
+//	    com.bar;
+func (self *printer) moduleDirectives(module *classfile.ModuleDescriptor) {
+	for _, r := range module.Requires {
+		modifiers := ""
+		if r.RequiresFlags&classfile.ACC_TRANSITIVE != 0 {
+			modifiers += "transitive "
+		}
+		if r.RequiresFlags&classfile.ACC_STATIC_PHASE != 0 {
+			modifiers += "static "
+		}
+		self.line(2, "requires %s%s;", modifiers, r.Requires)
+	}
+	for _, e := range module.Exports {
+		self.directive("exports "+javaName(e.Package), "to", e.To)
+	}
+	for _, o := range module.Opens {
+		self.directive("opens "+javaName(o.Package), "to", o.To)
+	}
+	for _, u := range module.Uses {
+		self.line(2, "uses %s;", javaName(u))
+	}
+	for _, p := range module.Provides {
+		self.directive("provides "+javaName(p.Provides), "with", mapNames(p.ProvidesWith, javaName))
+	}
+}
+
+// directive prints "head;", or "head keyword" followed by names on lines of
+// their own when there are any.
+func (self *printer) directive(head, keyword string, names []string) {
+	if len(names) == 0 {
+		self.line(2, "%s;", head)
+		return
+	}
+	self.line(2, "%s %s", head, keyword)
+	for i, name := range names {
+		sep := ","
+		if i == len(names)-1 {
+			sep = ";"
+		}
+		self.line(6, "%s%s", name, sep)
+	}
+}
@@ -0,0 +1,607 @@
+// Package javap renders parsed class files the way the JDK's javap tool does,
+// resolving constant pool references to names, descriptors and values.
+package javap
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gjvm/classfile"
+	"gjvm/descriptor"
+	"gjvm/signature"
+)
+
+// Options selects what Print shows. The fields follow the javap flags of the
+// same meaning.
+type Options struct {
+	// Code (-c) disassembles the code of each method.
+	Code bool
+	// Verbose (-v) adds the constant pool, flags, sizes and every attribute.
+	Verbose bool
+	// Private (-p) shows private members, which are hidden otherwise.
+	Private bool
+	// Signatures (-s) prints the descriptor of each member.
+	Signatures bool
+	// Lines (-l) prints the line number and local variable tables.
+	Lines bool
+	// Constants (-constants) shows the value of static final constants.
+	Constants bool
+	// Classfile describes where the class was read from. It is printed above
+	// the class by -v when set.
+	Classfile *Classfile
+}
+
+// Classfile is the file a class was read from.
+type Classfile struct {
+	Path     string
+	Modified time.Time
+	Size     int
+	SHA256   [sha256.Size]byte
+}
+
+// NewClassfile describes the class file at path with contents data.
+func NewClassfile(path string, modified time.Time, data []byte) *Classfile {
+	return &Classfile{Path: path, Modified: modified, Size: len(data), SHA256: sha256.Sum256(data)}
+}
+
+type printer struct {
+	cf   *classfile.ClassFile
+	opts Options
+	b    strings.Builder
+}
+
+// Print writes cf to w in the format of `javap -v -p`.
+func Print(w io.Writer, cf *classfile.ClassFile) error {
+	return Options{Verbose: true, Private: true}.Print(w, cf)
+}
+
+// Print writes cf to w in the format javap uses with the selected options.
+func (self Options) Print(w io.Writer, cf *classfile.ClassFile) error {
+	p := &printer{cf: cf, opts: self}
+	p.class()
+	_, err := io.WriteString(w, p.b.String())
+	return err
+}
+
+func (self *printer) line(indent int, format string, a ...any) {
+	self.b.WriteString(strings.Repeat(" ", indent))
+	fmt.Fprintf(&self.b, format, a...)
+	self.b.WriteByte('\n')
+}
+
+// withComment appends "// comment" at javap's comment column.
+func withComment(text, comment string) string {
+	if comment == "" {
+		return text
+	}
+	if len(text) < 40 {
+		text += strings.Repeat(" ", 40-len(text))
+	} else {
+		text += " "
+	}
+	return text + "// " + comment
+}
+
+func (self *printer) thisClass() string {
+	return self.className(self.cf.ThisClass)
+}
+
+// javaName turns an internal class name into the dotted form.
+func javaName(name string) string {
+	return strings.ReplaceAll(name, "/", ".")
+}
+
+func (self *printer) class() {
+	cf := self.cf
+	indent := 0
+	if info := self.opts.Classfile; self.opts.Verbose && info != nil {
+		self.line(0, "Classfile %s", info.Path)
+		self.line(2, "Last modified %s; size %d bytes", info.Modified.Format("Jan 2, 2006"), info.Size)
+		self.line(2, "SHA-256 checksum %x", info.SHA256)
+		indent = 2
+	}
+	if sf, ok := cf.SourceFile(); ok {
+		self.line(indent, "Compiled from %q", sf)
+	}
+	if !self.opts.Verbose {
+		self.line(0, "%s {", self.classDeclaration())
+		if module, ok := cf.Module(); ok {
+			self.moduleDirectives(module)
+		}
+		self.members()
+		self.line(0, "}")
+		return
+	}
+
+	self.line(0, "%s", self.classDeclaration())
+	self.line(2, "minor version: %d", cf.MinorVersion)
+	self.line(2, "major version: %d", cf.MajorVersion)
+	self.line(2, "flags: %s", flagNames(cf.AccessFlags, classfile.ClassFlagTable))
+	self.line(2, "%s", withComment(fmt.Sprintf("this_class: #%d", cf.ThisClass), self.thisClass()))
+	if cf.SuperClass == 0 {
+		self.line(2, "super_class: #0")
+	} else {
+		self.line(2, "%s", withComment(fmt.Sprintf("super_class: #%d", cf.SuperClass), self.className(cf.SuperClass)))
+	}
+	self.line(2, "interfaces: %d, fields: %d, methods: %d, attributes: %d", len(cf.Interfaces), len(cf.Fields), len(cf.Methods), len(cf.Attributes))
+
+	self.line(0, "Constant pool:")
+	for i := 1; i < len(cf.ConstantPool); i++ {
+		if !cf.ConstantPool.IsUsable(uint16(i)) {
+			continue
+		}
+		kind, args, comment := self.constantEntry(uint16(i))
+		if comment == "" {
+			self.line(0, "%5s = %-18s %s", fmt.Sprintf("#%d", i), kind, args)
+		} else {
+			self.line(0, "%5s = %-18s %-14s // %s", fmt.Sprintf("#%d", i), kind, args, comment)
+		}
+	}
+
+	self.line(0, "{")
+	self.members()
+	self.line(0, "}")
+	self.attributes(0, cf.Attributes)
+}
+
+// members prints the fields and methods the options make visible. Like javap,
+// it separates them with blank lines once they span several lines each.
+func (self *printer) members() {
+	separate := self.opts.Verbose || self.opts.Code || self.opts.Signatures || self.opts.Lines
+	first := true
+	next := func(flags classfile.AccessFlags) bool {
+		if flags.IsPrivate() && !self.opts.Private {
+			return false
+		}
+		if !first && separate {
+			self.line(0, "")
+		}
+		first = false
+		return true
+	}
+	for _, field := range self.cf.Fields {
+		if next(field.AccessFlags) {
+			self.field(field)
+		}
+	}
+	for _, method := range self.cf.Methods {
+		if next(method.AccessFlags) {
+			self.method(method)
+		}
+	}
+}
+
+// classDeclaration renders e.g. "public class Hello extends Base implements java.lang.Runnable".
+func (self *printer) classDeclaration() string {
+	cf := self.cf
+	name := javaName(self.thisClass())
+	if cf.AccessFlags.IsModule() {
+		if module, ok := cf.Module(); ok {
+			decl := "module " + module.ModuleName
+			if module.ModuleFlags&classfile.ACC_OPEN != 0 {
+				decl = "open " + decl
+			}
+			if module.ModuleVersion != "" {
+				decl += "@" + module.ModuleVersion
+			}
+			return decl
+		}
+		return name
+	}
+	flags := cf.AccessFlags
+	kind := "class "
+	if flags.IsInterface() {
+		// interfaces are implicitly abstract
+		flags &^= classfile.ACC_ABSTRACT
+		kind = "interface "
+	}
+	decl := modifiers(flags, classfile.ClassFlagTable) + kind
+
+	if sig, ok := cf.Signature(); ok {
+		if cs, err := sig.ClassSignature(); err == nil {
+			return decl + signature.Printer{}.Class(self.utf8ThisName(), cs)
+		}
+	}
+	decl += name
+	interfaces := make([]string, len(cf.Interfaces))
+	for i, index := range cf.Interfaces {
+		interfaces[i] = javaName(self.className(index))
+	}
+	if cf.AccessFlags.IsInterface() {
+		if len(interfaces) > 0 {
+			decl += " extends " + strings.Join(interfaces, ", ")
+		}
+		return decl
+	}
+	if super := javaName(self.className(cf.SuperClass)); cf.SuperClass != 0 && super != "java.lang.Object" {
+		decl += " extends " + super
+	}
+	if len(interfaces) > 0 {
+		decl += " implements " + strings.Join(interfaces, ", ")
+	}
+	return decl
+}
+
+// utf8ThisName is this_class in internal form, unquoted.
+func (self *printer) utf8ThisName() string {
+	return strings.Trim(self.thisClass(), `"`)
+}
+
+func (self *printer) field(field classfile.FieldInfo) {
+	desc := self.utf8(field.DescriptorIndex)
+	typ := desc
+	if t, err := descriptor.ParseFieldDescriptor(desc); err == nil {
+		typ = t.String()
+	}
+	if sig, ok := classfile.FindAttribute[classfile.SignatureAttribute](field.Attributes); ok {
+		if fs, err := sig.FieldSignature(); err == nil {
+			typ = signature.Printer{}.Type(fs)
+		}
+	}
+	decl := modifiers(field.AccessFlags, classfile.FieldFlagTable) + typ + " " + self.utf8(field.NameIndex)
+	if self.opts.Constants {
+		if cv, ok := classfile.FindAttribute[classfile.ConstantValueAttribute](field.Attributes); ok {
+			decl += " = " + self.constantValue(desc, cv.ConstantValueIndex)
+		}
+	}
+	self.line(2, "%s;", decl)
+	if self.opts.Verbose || self.opts.Signatures {
+		self.line(4, "descriptor: %s", desc)
+	}
+	if self.opts.Verbose {
+		self.line(4, "flags: %s", flagNames(field.AccessFlags, classfile.FieldFlagTable))
+		self.attributes(4, field.Attributes)
+	}
+}
+
+func (self *printer) method(method classfile.MethodInfo) {
+	self.line(2, "%s;", self.methodDeclaration(method))
+	if self.opts.Verbose || self.opts.Signatures {
+		self.line(4, "descriptor: %s", method.Descriptor)
+	}
+	if self.opts.Verbose {
+		self.line(4, "flags: %s", flagNames(method.AccessFlags, classfile.MethodFlagTable))
+		self.methodAttributes(method)
+		return
+	}
+	code, ok := classfile.FindAttribute[classfile.CodeAttribute](method.Attributes)
+	if !ok {
+		return
+	}
+	if self.opts.Code {
+		self.line(4, "Code:")
+		if err := self.instructions(4, code.Code); err != nil {
+			self.line(4, "<error: %v>", err)
+		}
+		self.exceptionTable(4, code)
+	}
+	if self.opts.Lines {
+		for _, attr := range code.Attributes {
+			switch attr := attr.(type) {
+			case classfile.LineNumberTableAttribute:
+				self.lineNumberTable(4, attr)
+			case classfile.LocalVariableTableAttribute:
+				self.localVariableTable(4, attr)
+			}
+		}
+	}
+}
+
+// methodDeclaration renders e.g. "public static void main(java.lang.String[])".
+func (self *printer) methodDeclaration(method classfile.MethodInfo) string {
+	flags := method.AccessFlags
+	decl := ""
+	if self.cf.AccessFlags.IsInterface() && flags&(classfile.ACC_STATIC|classfile.ACC_PRIVATE|classfile.ACC_ABSTRACT) == 0 {
+		decl = "default "
+	}
+	if self.cf.AccessFlags.IsInterface() {
+		flags &^= classfile.ACC_ABSTRACT
+	}
+	decl = modifiers(flags, classfile.MethodFlagTable) + decl
+	if method.Name == "<clinit>" {
+		return "static {}"
+	}
+
+	var typeParams, result string
+	var params, throws []string
+	if md, err := descriptor.ParseMethodDescriptor(method.Descriptor); err == nil {
+		result = md.ReturnType.String()
+		for _, p := range md.Parameters {
+			params = append(params, p.String())
+		}
+	}
+	for _, e := range method.Exceptions() {
+		throws = append(throws, javaName(e))
+	}
+	if sig, ok := classfile.FindAttribute[classfile.SignatureAttribute](method.Attributes); ok {
+		if ms, err := sig.MethodSignature(); err == nil {
+			printer := signature.Printer{}
+			typeParams = printer.TypeParameters(ms.TypeParameters)
+			result = printer.Type(ms.Result)
+			params = params[:0]
+			for _, p := range ms.Parameters {
+				params = append(params, printer.Type(p))
+			}
+			if len(ms.Throws) > 0 {
+				throws = throws[:0]
+				for _, t := range ms.Throws {
+					throws = append(throws, printer.Type(t))
+				}
+			}
+		}
+	}
+	if method.AccessFlags&classfile.ACC_VARARGS != 0 && len(params) > 0 {
+		last := params[len(params)-1]
+		params[len(params)-1] = strings.TrimSuffix(last, "[]") + "..."
+	}
+
+	if typeParams != "" {
+		decl += typeParams + " "
+	}
+	if method.Name == "<init>" {
+		decl += javaName(self.utf8ThisName())
+	} else {
+		decl += result + " " + method.Name
+	}
+	decl += "(" + strings.Join(params, ", ") + ")"
+	if len(throws) > 0 {
+		decl += " throws " + strings.Join(throws, ", ")
+	}
+	return decl
+}
+
+func (self *printer) methodAttributes(method classfile.MethodInfo) {
+	for _, attr := range method.Attributes {
+		switch attr := attr.(type) {
+		case classfile.CodeAttribute:
+			self.code(method, attr)
+		case classfile.ExceptionsAttribute:
+			self.line(4, "Exceptions:")
+			self.line(6, "throws %s", strings.Join(mapNames(attr.Exceptions, javaName), ", "))
+		case classfile.MethodParametersAttribute:
+			self.line(4, "MethodParameters:")
+			self.line(6, "%-30s %s", "Name", "Flags")
+			for _, p := range attr.Parameters {
+				name := p.Name
+				if name == "" {
+					name = "<no name>"
+				}
+				flags := []string{}
+				if p.AccessFlags.IsFinal() {
+					flags = append(flags, "final")
+				}
+				if p.AccessFlags.IsSynthetic() {
+					flags = append(flags, "synthetic")
+				}
+				if p.IsMandated() {
+					flags = append(flags, "mandated")
+				}
+				self.line(6, "%-30s %s", name, strings.Join(flags, " "))
+			}
+		default:
+			self.attribute(4, attr)
+		}
+	}
+}
+
+func mapNames(names []string, f func(string) string) []string {
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = f(n)
+	}
+	return out
+}
+
+func (self *printer) code(method classfile.MethodInfo, code classfile.CodeAttribute) {
+	argsSize := 0
+	if md, err := descriptor.ParseMethodDescriptor(method.Descriptor); err == nil {
+		argsSize = md.ParameterSlots()
+	}
+	if !method.AccessFlags.IsStatic() {
+		argsSize++
+	}
+	self.line(4, "Code:")
+	self.line(6, "stack=%d, locals=%d, args_size=%d", code.MaxStack, code.MaxLocals, argsSize)
+	if err := self.instructions(6, code.Code); err != nil {
+		self.line(6, "<error: %v>", err)
+	}
+	self.exceptionTable(6, code)
+	for _, attr := range code.Attributes {
+		switch attr := attr.(type) {
+		case classfile.LineNumberTableAttribute:
+			self.lineNumberTable(6, attr)
+		case classfile.LocalVariableTableAttribute:
+			self.localVariableTable(6, attr)
+		case classfile.LocalVariableTypeTableAttribute:
+			self.line(6, "LocalVariableTypeTable:")
+			self.line(8, "Start  Length  Slot  Name   Signature")
+			for _, e := range attr.LocalVariableTypeTable {
+				self.line(8, "%5d %7d %5d %5s   %s", e.StartPc, e.Length, e.Index, e.Name, e.Signature)
+			}
+		case classfile.StackMapTableAttribute:
+			self.stackMapTable(attr)
+		default:
+			self.attribute(6, attr)
+		}
+	}
+}
+
+func (self *printer) exceptionTable(indent int, code classfile.CodeAttribute) {
+	if len(code.ExceptionTable) == 0 {
+		return
+	}
+	self.line(indent, "Exception table:")
+	self.line(indent+3, "from    to  target type")
+	for _, e := range code.ExceptionTable {
+		catchType := "any"
+		if e.CatchType != 0 {
+			catchType = "Class " + self.className(e.CatchType)
+		}
+		self.line(indent+2, "%6d%6d%6d   %s", e.StartPc, e.EndPc, e.HandlerPc, catchType)
+	}
+}
+
+func (self *printer) lineNumberTable(indent int, attr classfile.LineNumberTableAttribute) {
+	self.line(indent, "LineNumberTable:")
+	for _, e := range attr.LineNumberTable {
+		self.line(indent+2, "line %d: %d", e.LineNumber, e.StartPc)
+	}
+}
+
+func (self *printer) localVariableTable(indent int, attr classfile.LocalVariableTableAttribute) {
+	self.line(indent, "LocalVariableTable:")
+	self.line(indent+2, "Start  Length  Slot  Name   Signature")
+	for _, e := range attr.LocalVariableTable {
+		self.line(indent+2, "%5d %7d %5d %5s   %s", e.StartPc, e.Length, e.Index, e.Name, e.Descriptor)
+	}
+}
+
+func (self *printer) stackMapTable(attr classfile.StackMapTableAttribute) {
+	self.line(6, "StackMapTable: number_of_entries = %d", len(attr.Entries))
+	for _, frame := range attr.Entries {
+		h := frame.Header()
+		switch f := frame.(type) {
+		case classfile.SameFrame:
+			if h.FrameType == classfile.FrameTypeSameExtended {
+				self.line(8, "frame_type = %d /* same_frame_extended */", h.FrameType)
+				self.line(10, "offset_delta = %d", h.OffsetDelta)
+			} else {
+				self.line(8, "frame_type = %d /* same */", h.FrameType)
+			}
+		case classfile.SameLocals1StackItemFrame:
+			if h.FrameType == classfile.FrameTypeSameLocals1StackItemExtended {
+				self.line(8, "frame_type = %d /* same_locals_1_stack_item_frame_extended */", h.FrameType)
+				self.line(10, "offset_delta = %d", h.OffsetDelta)
+			} else {
+				self.line(8, "frame_type = %d /* same_locals_1_stack_item */", h.FrameType)
+			}
+			self.line(10, "stack = %s", self.verificationTypes([]classfile.VerificationTypeInfo{f.Stack}))
+		case classfile.ChopFrame:
+			self.line(8, "frame_type = %d /* chop */", h.FrameType)
+			self.line(10, "offset_delta = %d", h.OffsetDelta)
+		case classfile.AppendFrame:
+			self.line(8, "frame_type = %d /* append */", h.FrameType)
+			self.line(10, "offset_delta = %d", h.OffsetDelta)
+			self.line(10, "locals = %s", self.verificationTypes(f.Locals))
+		case classfile.FullFrame:
+			self.line(8, "frame_type = %d /* full_frame */", h.FrameType)
+			self.line(10, "offset_delta = %d", h.OffsetDelta)
+			self.line(10, "locals = %s", self.verificationTypes(f.Locals))
+			self.line(10, "stack = %s", self.verificationTypes(f.Stack))
+		}
+	}
+}
+
+func (self *printer) verificationTypes(types []classfile.VerificationTypeInfo) string {
+	s := make([]string, len(types))
+	for i, t := range types {
+		switch t.Tag {
+		case classfile.ItemObject:
+			s[i] = "class " + self.className(t.CpoolIndex)
+		case classfile.ItemUninitializedThis:
+			s[i] = "this"
+		default:
+			s[i] = t.String()
+		}
+	}
+	return "[ " + strings.Join(s, ", ") + " ]"
+}
+
+// attribute prints the attributes javap shows outside of Code and the member
+// headers. Those it has no special layout for fall back to their String.
+func (self *printer) attributes(indent int, attrs []classfile.AttributeInfo) {
+	for _, attr := range attrs {
+		self.attribute(indent, attr)
+	}
+}
+
+func (self *printer) attribute(indent int, attr classfile.AttributeInfo) {
+	switch attr := attr.(type) {
+	case classfile.SourceFileAttribute:
+		self.line(indent, "SourceFile: %q", attr.SourceFile)
+	case classfile.SignatureAttribute:
+		self.line(indent, "%s", withComment(fmt.Sprintf("Signature: #%d", attr.SignatureIndex), attr.Signature))
+	case classfile.ConstantValueAttribute:
+		self.line(indent, "ConstantValue: %s", self.constant(attr.ConstantValueIndex))
+	case classfile.DeprecatedAttribute:
+		self.line(indent, "Deprecated: true")
+	case classfile.InnerClassesAttribute:
+		self.line(indent, "InnerClasses:")
+		for _, c := range attr.Classes {
+			text := modifiers(c.InnerClassAccessFlags, classfile.InnerClassFlagTable) + fmt.Sprintf("#%d", c.InnerClassInfoIndex)
+			comment := "class " + c.InnerClass
+			if c.InnerNameIndex != 0 {
+				text += fmt.Sprintf("= #%d", c.InnerNameIndex)
+				comment = c.InnerName + "=" + comment
+			}
+			if c.OuterClassInfoIndex != 0 {
+				text += fmt.Sprintf(" of #%d", c.OuterClassInfoIndex)
+				comment += " of class " + c.OuterClass
+			}
+			self.line(indent+2, "%s", withComment(text+";", comment))
+		}
+	case classfile.EnclosingMethodAttribute:
+		comment := attr.Class
+		if attr.MethodIndex != 0 {
+			comment += "." + attr.MethodName
+		}
+		self.line(indent, "%s", withComment(fmt.Sprintf("EnclosingMethod: #%d.#%d", attr.ClassIndex, attr.MethodIndex), comment))
+	case classfile.NestHostAttribute:
+		self.line(indent, "NestHost: class %s", attr.HostClass)
+	case classfile.NestMembersAttribute:
+		self.line(indent, "NestMembers:")
+		for _, c := range attr.Classes {
+			self.line(indent+2, "%s", c)
+		}
+	case classfile.PermittedSubclassesAttribute:
+		self.line(indent, "PermittedSubclasses:")
+		for _, c := range attr.Classes {
+			self.line(indent+2, "%s", c)
+		}
+	case classfile.BootstrapMethodsAttribute:
+		self.line(indent, "BootstrapMethods:")
+		for i, m := range attr.BootstrapMethods {
+			self.line(indent+2, "%d: #%d %s", i, m.BootstrapMethodRef, self.bootstrapArgument(m.BootstrapMethodRef))
+			self.line(indent+4, "Method arguments:")
+			for _, arg := range m.BootstrapArguments {
+				self.line(indent+6, "#%d %s", arg, self.bootstrapArgument(arg))
+			}
+		}
+	case classfile.AnnotationsAttribute:
+		name := "RuntimeInvisibleAnnotations"
+		if attr.Visible {
+			name = "RuntimeVisibleAnnotations"
+		}
+		self.line(indent, "%s:", name)
+		for i, a := range attr.Annotations {
+			self.line(indent+2, "%d: #%d()", i, a.TypeIndex)
+			self.line(indent+4, "%s", a)
+		}
+	default:
+		for _, line := range strings.Split(attr.String(), "\n") {
+			self.line(indent, "%s", line)
+		}
+	}
+}
+
+// bootstrapArgument renders a loadable constant without its kind, as javap
+// does in BootstrapMethods.
+func (self *printer) bootstrapArgument(index uint16) string {
+	if !self.cf.ConstantPool.IsUsable(index) {
+		return self.invalid(index)
+	}
+	switch info := self.cf.ConstantPool[index].(type) {
+	case *classfile.ConstantMethodHandleInfo:
+		return self.methodHandle(info, false)
+	case *classfile.ConstantClassInfo:
+		return self.className(index)
+	}
+	_, args, comment := self.constantEntry(index)
+	if comment == "" {
+		return args
+	}
+	return comment
+}
@@ -0,0 +1,173 @@
+package javap
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gjvm/classfile"
+)
+
+func TestPrintHello(t *testing.T) {
+	f, err := os.Open("../java/Hello.class")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cf, err := classfile.NewClassFileParser(f).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := Print(&b, cf); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"  this_class: #21                         // Hello\n",
+		"   #1 = Methodref          #2.#3          // java/lang/Object.\"<init>\":()V\n",
+		"  #14 = Utf8               Hello world\n",
+		"  public static void main(java.lang.String[]);\n    descriptor: ([Ljava/lang/String;)V\n    flags: (0x0009) ACC_PUBLIC, ACC_STATIC\n",
+		"      stack=2, locals=1, args_size=1\n" +
+			"         0: getstatic     #7                  // Field java/lang/System.out:Ljava/io/PrintStream;\n" +
+			"         3: ldc           #13                 // String Hello world\n" +
+			"         5: invokevirtual #15                 // Method java/io/PrintStream.println:(Ljava/lang/String;)V\n" +
+			"         8: return\n",
+		"}\nSourceFile: \"Hello.java\"\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks\n%s\ngot\n%s", want, got)
+		}
+	}
+}
+
+func TestPrintOptions(t *testing.T) {
+	f, err := os.Open("../java/Hello.class")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cf, err := classfile.NewClassFileParser(f).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{}, `Compiled from "Hello.java"
+public class Hello {
+  public Hello();
+  public static void main(java.lang.String[]);
+}
+`},
+		{Options{Code: true}, `Compiled from "Hello.java"
+public class Hello {
+  public Hello();
+    Code:
+       0: aload_0
+       1: invokespecial #1                  // Method java/lang/Object."<init>":()V
+       4: return
+
+  public static void main(java.lang.String[]);
+    Code:
+       0: getstatic     #7                  // Field java/lang/System.out:Ljava/io/PrintStream;
+       3: ldc           #13                 // String Hello world
+       5: invokevirtual #15                 // Method java/io/PrintStream.println:(Ljava/lang/String;)V
+       8: return
+}
+`},
+		{Options{Signatures: true, Lines: true}, `Compiled from "Hello.java"
+public class Hello {
+  public Hello();
+    descriptor: ()V
+    LineNumberTable:
+      line 2: 0
+
+  public static void main(java.lang.String[]);
+    descriptor: ([Ljava/lang/String;)V
+    LineNumberTable:
+      line 4: 0
+      line 5: 8
+}
+`},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := tt.opts.Print(&b, cf); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%+v: got\n%s\nwant\n%s", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestConstantValue(t *testing.T) {
+	cf := &classfile.ClassFile{ConstantPool: classfile.ConstantPool{
+		nil,
+		&classfile.ConstantIntegerInfo{Bytes: []byte{0, 0, 0, 'a'}},
+		&classfile.ConstantIntegerInfo{Bytes: []byte{0, 0, 0, 1}},
+		&classfile.ConstantLongInfo{HighBytes: 0, LowBytes: 5},
+		&classfile.ConstantUnusableInfo{},
+	}}
+	p := &printer{cf: cf}
+	for _, tt := range []struct {
+		desc  string
+		index uint16
+		want  string
+	}{
+		{"C", 1, "'a'"},
+		{"Z", 2, "true"},
+		{"I", 2, "1"},
+		{"J", 3, "5l"},
+	} {
+		if got := p.constantValue(tt.desc, tt.index); got != tt.want {
+			t.Errorf("constantValue(%q, %d) = %q, want %q", tt.desc, tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestInstructions(t *testing.T) {
+	cf := &classfile.ClassFile{ConstantPool: classfile.ConstantPool{nil}}
+	code := []byte{
+		0x1b,       // 0: iload_1
+		0xaa, 0, 0, // 1: tableswitch, 2 bytes of padding
+		0, 0, 0, 30, 0, 0, 0, 1, 0, 0, 0, 2, // default 31, low 1, high 2
+		0, 0, 0, 27, 0, 0, 0, 28, // 28, 29
+		0xab, 0, 0, 0, // 24: lookupswitch, 3 bytes of padding
+		0, 0, 0, 7, 0, 0, 0, 1, // default 31, npairs 1
+		0xff, 0xff, 0xff, 0xff, 0, 0, 0, 7, // -1: 31
+		0xc4, 0x84, 0x01, 0x00, 0x03, 0xe8, // 44: wide iinc 256, 1000
+		0xbc, 10, // 50: newarray int
+		0xa7, 0xff, 0xce, // 52: goto 2
+		0xb1, // 55: return
+	}
+	p := &printer{cf: cf}
+	if err := p.instructions(6, code); err != nil {
+		t.Fatal(err)
+	}
+	want := `         0: iload_1
+         1: tableswitch   { // 1 to 2
+                       1: 28
+                       2: 29
+                 default: 31
+            }
+        24: lookupswitch  { // 1
+                      -1: 31
+                 default: 31
+            }
+        44: iinc_w        256, 1000
+        50: newarray      int
+        52: goto          2
+        55: return
+`
+	if got := p.b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	p = &printer{cf: cf}
+	if err := p.instructions(6, []byte{0x11, 0}); err == nil {
+		t.Errorf("expected a truncated sipush to be reported")
+	}
+}
@@ -1,83 +1,71 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"gjvm/classfile"
-		"gjvm/runtime"
+	"gjvm/runtime"
 )
 
 func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: gjvm <file.class> | gjvm javap [options] <classes> | gjvm dump [--format=json] <files>")
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "javap":
+		os.Exit(runJavap(os.Args[2:], os.Stdout, os.Stderr))
+	case "dump":
+		os.Exit(runDump(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	f := os.Args[1]
 	file, err := os.Open(f)
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
 	defer file.Close()
 
 	parser := classfile.NewClassFileParser(file)
 	class, err := parser.Parse()
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "%s: %v\n", f, err)
+		os.Exit(1)
 	}
 
-	fmt.Printf("minorVersion: %04d\n", class.MinorVersion)
-	fmt.Printf("majorVersion: %04d\n", class.MajorVersion)
-	fmt.Printf("constantPoolCount: %d\n", class.ConstantPoolCount)
-	for i := 1; i < int(class.ConstantPoolCount); i++ {
-		fmt.Printf("#%d: %s\n", i, class.ConstantPool[i])
-	}
-	fmt.Printf("accessFlags: %s\n", class.AccessFlags)
-	
-	fmt.Printf("thisClass: #%d\n", class.ThisClass)
-	fmt.Printf("superClass: #%d\n", class.SuperClass)
-	fmt.Printf("interfacesCount: %d\n", class.InterfacesCount)
-	for i := 0; i < int(class.InterfacesCount); i++ {
-		fmt.Printf("interface[%d]: #%d\n", i, class.Interfaces[i])
-	}
-	fmt.Printf("fieldCount: %d\n", class.FieldCount)
-	for i := 0; i < int(class.FieldCount); i++ {
-		fmt.Printf("field[%d]: %s\n", i, class.Fields[i])
-	}
-	fmt.Printf("methodCount: %d\n", class.MethodCount)
-	for i := 0; i < int(class.MethodCount); i++ {
-		fmt.Printf("method[%d]: %s\n", i, class.Methods[i])
-	}
-
-	var mainMethod classfile.MethodInfo
-	for i := 0; i < int(class.MethodCount); i++ {
-		method := class.Methods[i]
-		methodName := class.ConstantPool[method.NameIndex]
-		fmt.Printf("methodName: %s\n", methodName)
-		if methodName == "main" {
-			mainMethod = method
-			break
-		}
-	}
-
-	for m := range mainMethod.Attributes {
-		fmt.Printf("mainMethodAttribute[%d]: %s\n", m, mainMethod.Attributes[m])
+	// module-info.class has no code to run; describe the module instead
+	if module, ok := class.Module(); ok {
+		fmt.Print(module)
+		return
 	}
 
 	main, err := findMain(class)
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "%s: %v\n", f, err)
+		os.Exit(1)
 	}
 
-
-	fmt.Println("=================================================================")
-	cs := ""
-	for i := 0; i < len(main.Code); i++ {
-		cs += fmt.Sprintf("%#x ", main.Code[i])
+	// the class path root is the directory the class's package starts in
+	root := filepath.Dir(f)
+	for range strings.Count(class.Name(), "/") {
+		root = filepath.Dir(root)
+	}
+	thread := runtime.NewThread(os.Stdout)
+	thread.Loader = runtime.DirLoader(root)
+	if _, err := thread.Invoke(class, main); err != nil {
+		var e *runtime.Throwable
+		if errors.As(err, &e) {
+			fmt.Fprintf(os.Stderr, "Exception in thread \"main\" %s\n", e.Trace())
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
 	}
-	fmt.Printf("main code: [ %v]\n", cs)
-	fmt.Println("=================================================================")
-
-		stack := runtime.NewOperandStack()
-		runtime.Interpret(main.Code, stack, class.ConstantPool)
-
-	fmt.Println()
 }
 
 func findMain(class *classfile.ClassFile) (classfile.MethodInfo, error) {
@@ -0,0 +1,65 @@
+package runtime
+
+import (
+	"gjvm/bytecode"
+)
+
+// returnAddress is the value jsr pushes and ret jumps to.
+type returnAddress int
+
+// branches reports whether a conditional branch is taken, popping its
+// operands: one int for ifeq to ifle, two ints for if_icmpeq to if_icmple,
+// two references for if_acmpeq and if_acmpne, one for ifnull and ifnonnull.
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-6.html#jvms-6.5.if_cond
+func branches(op bytecode.Opcode, stack *OperandStack) bool {
+	switch op {
+	case bytecode.IFEQ, bytecode.IFNE, bytecode.IFLT, bytecode.IFGE, bytecode.IFGT, bytecode.IFLE:
+		return holds(op-bytecode.IFEQ, compare(stack.PopInt(), 0))
+	case bytecode.IF_ICMPEQ, bytecode.IF_ICMPNE, bytecode.IF_ICMPLT,
+		bytecode.IF_ICMPGE, bytecode.IF_ICMPGT, bytecode.IF_ICMPLE:
+		b, a := stack.PopInt(), stack.PopInt()
+		return holds(op-bytecode.IF_ICMPEQ, compare(a, b))
+	case bytecode.IF_ACMPEQ, bytecode.IF_ACMPNE:
+		b, a := stack.Pop(), stack.Pop()
+		return (a == b) == (op == bytecode.IF_ACMPEQ)
+	case bytecode.IFNULL:
+		return stack.Pop() == nil
+	case bytecode.IFNONNULL:
+		return stack.Pop() != nil
+	}
+	panic("not a conditional branch: " + op.String())
+}
+
+// holds reports whether the i-th condition of eq, ne, lt, ge, gt, le, the
+// order of both if<cond> and if_icmp<cond>, holds for a comparison result.
+func holds(i bytecode.Opcode, c int32) bool {
+	switch i {
+	case 0:
+		return c == 0
+	case 1:
+		return c != 0
+	case 2:
+		return c < 0
+	case 3:
+		return c >= 0
+	case 4:
+		return c > 0
+	}
+	return c <= 0
+}
+
+// switchTarget is where a tableswitch or lookupswitch jumps for key.
+func switchTarget(insn bytecode.Instruction, key int32) int {
+	if insn.Opcode == bytecode.TABLESWITCH {
+		if key < insn.Low || key > insn.High {
+			return insn.Target
+		}
+		return insn.Targets[key-insn.Low]
+	}
+	for i, k := range insn.Keys {
+		if k == key {
+			return insn.Targets[i]
+		}
+	}
+	return insn.Target
+}
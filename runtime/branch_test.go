@@ -0,0 +1,126 @@
+package runtime
+
+import (
+	"fmt"
+	"testing"
+
+	"gjvm/builder"
+	"gjvm/bytecode"
+)
+
+func TestConditionalBranches(t *testing.T) {
+	for _, tc := range []struct {
+		op    bytecode.Opcode
+		desc  string
+		args  []Value
+		taken bool
+	}{
+		{bytecode.IFEQ, "(I)V", []Value{int32(0)}, true},
+		{bytecode.IFNE, "(I)V", []Value{int32(0)}, false},
+		{bytecode.IFLT, "(I)V", []Value{int32(-1)}, true},
+		{bytecode.IFGE, "(I)V", []Value{int32(-1)}, false},
+		{bytecode.IFGT, "(I)V", []Value{int32(0)}, false},
+		{bytecode.IFLE, "(I)V", []Value{int32(0)}, true},
+		{bytecode.IF_ICMPEQ, "(II)V", []Value{int32(3), int32(3)}, true},
+		{bytecode.IF_ICMPNE, "(II)V", []Value{int32(3), int32(3)}, false},
+		{bytecode.IF_ICMPLT, "(II)V", []Value{int32(-5), int32(3)}, true},
+		{bytecode.IF_ICMPGE, "(II)V", []Value{int32(-5), int32(3)}, false},
+		{bytecode.IF_ICMPGT, "(II)V", []Value{int32(4), int32(3)}, true},
+		{bytecode.IF_ICMPLE, "(II)V", []Value{int32(4), int32(3)}, false},
+		{bytecode.IF_ACMPEQ, "(Ljava/lang/Object;Ljava/lang/Object;)V", []Value{"a", nil}, false},
+		{bytecode.IF_ACMPNE, "(Ljava/lang/Object;Ljava/lang/Object;)V", []Value{"a", nil}, true},
+		{bytecode.IFNULL, "(Ljava/lang/Object;)V", []Value{nil}, true},
+		{bytecode.IFNONNULL, "(Ljava/lang/Object;)V", []Value{nil}, false},
+	} {
+		_, out, err := run(t, tc.desc, func(c *builder.CodeBuilder) {
+			taken := c.NewLabel()
+			load := bytecode.ILOAD
+			if tc.desc[1] == 'L' {
+				load = bytecode.ALOAD
+			}
+			for i := range tc.args {
+				c.Local(load, i)
+			}
+			c.Jump(tc.op, taken).Push(0)
+			printTop(c, "Z").Op(bytecode.RETURN)
+			c.Mark(taken).Push(1)
+			printTop(c, "Z").Op(bytecode.RETURN)
+		}, tc.args...)
+		if err != nil {
+			t.Errorf("%s %v: %v", tc.op, tc.args, err)
+		} else if want := fmt.Sprintln(tc.taken); out != want {
+			t.Errorf("%s %v: got %q, want %q", tc.op, tc.args, out, want)
+		}
+	}
+}
+
+func TestLoop(t *testing.T) {
+	_, out, err := run(t, "()V", func(c *builder.CodeBuilder) {
+		loop, end := c.NewLabel(), c.NewLabel()
+		c.Push(0).Local(bytecode.ISTORE, 0).Push(1).Local(bytecode.ISTORE, 1)
+		c.Mark(loop).Local(bytecode.ILOAD, 1).Push(10).Jump(bytecode.IF_ICMPGT, end)
+		c.Local(bytecode.ILOAD, 0).Local(bytecode.ILOAD, 1).Op(bytecode.IADD).Local(bytecode.ISTORE, 0)
+		c.Iinc(1, 1).Jump(bytecode.GOTO, loop)
+		c.Mark(end).Local(bytecode.ILOAD, 0)
+		printTop(c, "I").Op(bytecode.RETURN)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "55\n" {
+		t.Errorf("got %q, want 55", out)
+	}
+}
+
+// TestSwitches runs each switch at every padding, which depends on its pc.
+func TestSwitches(t *testing.T) {
+	for _, op := range []bytecode.Opcode{bytecode.TABLESWITCH, bytecode.LOOKUPSWITCH} {
+		for nops := range 4 {
+			for _, tc := range []struct {
+				key  int32
+				want string
+			}{{-1, "default"}, {0, "zero"}, {1, "one"}, {2, "two"}, {3, "default"}, {1 << 20, "default"}} {
+				_, out, err := run(t, "(I)V", func(c *builder.CodeBuilder) {
+					for range nops {
+						c.Op(bytecode.NOP)
+					}
+					dflt, zero, one, two := c.NewLabel(), c.NewLabel(), c.NewLabel(), c.NewLabel()
+					c.Local(bytecode.ILOAD, 0)
+					if op == bytecode.TABLESWITCH {
+						c.TableSwitch(0, dflt, zero, one, two)
+					} else {
+						c.LookupSwitch(dflt, map[int32]*builder.Label{0: zero, 1: one, 2: two})
+					}
+					for _, l := range []struct {
+						label *builder.Label
+						name  string
+					}{{dflt, "default"}, {zero, "zero"}, {one, "one"}, {two, "two"}} {
+						c.Mark(l.label).Ldc(l.name)
+						printTop(c, "Ljava/lang/String;").Op(bytecode.RETURN)
+					}
+				}, tc.key)
+				if err != nil {
+					t.Errorf("%s after %d nops, key %d: %v", op, nops, tc.key, err)
+				} else if out != tc.want+"\n" {
+					t.Errorf("%s after %d nops, key %d: got %q, want %s", op, nops, tc.key, out, tc.want)
+				}
+			}
+		}
+	}
+}
+
+func TestSubroutine(t *testing.T) {
+	_, out, err := run(t, "()V", func(c *builder.CodeBuilder) {
+		sub := c.NewLabel()
+		c.Jump(bytecode.JSR, sub).Ldc("after")
+		printTop(c, "Ljava/lang/String;").Op(bytecode.RETURN)
+		c.Mark(sub).Local(bytecode.ASTORE, 0).Ldc("sub")
+		printTop(c, "Ljava/lang/String;").Local(bytecode.RET, 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "sub\nafter\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"math"
+
+	"gjvm/bytecode"
+)
+
+// float and double arithmetic is IEEE 754 with round to nearest, which Go's
+// float32 and float64 are. The explicit conversions keep the compiler from
+// fusing operations or computing a float in more precision than float32.
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-2.html#jvms-2.8
+
+// floatBinary applies fadd, fsub, fmul, fdiv or frem.
+func floatBinary(op bytecode.Opcode, a, b float32) float32 {
+	switch op {
+	case bytecode.FADD:
+		return float32(a + b)
+	case bytecode.FSUB:
+		return float32(a - b)
+	case bytecode.FMUL:
+		return float32(a * b)
+	case bytecode.FDIV:
+		return float32(a / b)
+	case bytecode.FREM:
+		// the remainder of truncating division, like C's fmod; it is exact,
+		// so computing it in float64 loses nothing
+		return float32(math.Mod(float64(a), float64(b)))
+	}
+	panic("not a float instruction: " + op.String())
+}
+
+// doubleBinary applies dadd, dsub, dmul, ddiv or drem.
+func doubleBinary(op bytecode.Opcode, a, b float64) float64 {
+	switch op {
+	case bytecode.DADD:
+		return float64(a + b)
+	case bytecode.DSUB:
+		return float64(a - b)
+	case bytecode.DMUL:
+		return float64(a * b)
+	case bytecode.DDIV:
+		return float64(a / b)
+	case bytecode.DREM:
+		return math.Mod(a, b)
+	}
+	panic("not a double instruction: " + op.String())
+}
+
+// floatCompare implements fcmpl, fcmpg, dcmpl and dcmpg, which differ only in
+// whether a NaN operand gives -1 (the l forms) or 1 (the g forms).
+func floatCompare[T float32 | float64](a, b T, nan int32) int32 {
+	if math.IsNaN(float64(a)) || math.IsNaN(float64(b)) {
+		return nan
+	}
+	return compare(a, b)
+}
+
+// toInt converts like f2i and d2i: NaN becomes 0, values beyond the int range
+// saturate, and the rest are truncated toward zero.
+func toInt(v float64) int32 {
+	switch {
+	case math.IsNaN(v):
+		return 0
+	case v >= math.MaxInt32:
+		return math.MaxInt32
+	case v <= math.MinInt32:
+		return math.MinInt32
+	}
+	return int32(v)
+}
+
+// toLong converts like f2l and d2l.
+func toLong(v float64) int64 {
+	switch {
+	case math.IsNaN(v):
+		return 0
+	case v >= math.MaxInt64:
+		return math.MaxInt64
+	case v <= math.MinInt64:
+		return math.MinInt64
+	}
+	return int64(v)
+}
+
+// nanComparison is what fcmpl, fcmpg, dcmpl and dcmpg push for NaN.
+func nanComparison(op bytecode.Opcode) int32 {
+	if op == bytecode.FCMPL || op == bytecode.DCMPL {
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,111 @@
+package runtime
+
+import (
+	"math"
+	"testing"
+
+	"gjvm/builder"
+	"gjvm/bytecode"
+)
+
+func TestFloatInstructions(t *testing.T) {
+	nan := float32(math.NaN())
+	for _, tc := range []struct {
+		a, b float32
+		op   bytecode.Opcode
+		want string
+	}{
+		{16777216, 1, bytecode.FADD, "1.6777216E7"},
+		{1e38, 10, bytecode.FMUL, "Infinity"},
+		{1, 0, bytecode.FDIV, "Infinity"},
+		{0, 0, bytecode.FDIV, "NaN"},
+		{-5.5, 2, bytecode.FREM, "-1.5"},
+		{1, 3, bytecode.FSUB, "-2.0"},
+		{1, 2, bytecode.FCMPL, "-1"},
+		{nan, 2, bytecode.FCMPL, "-1"},
+		{nan, 2, bytecode.FCMPG, "1"},
+		{2, 2, bytecode.FCMPG, "0"},
+	} {
+		want := "F"
+		if tc.op == bytecode.FCMPL || tc.op == bytecode.FCMPG {
+			want = "I"
+		}
+		_, out, err := run(t, "(FF)V", func(c *builder.CodeBuilder) {
+			c.Local(bytecode.FLOAD, 0).Local(bytecode.FLOAD, 1).Op(tc.op)
+			printTop(c, want).Op(bytecode.RETURN)
+		}, tc.a, tc.b)
+		if err != nil {
+			t.Errorf("%v %s %v: %v", tc.a, tc.op, tc.b, err)
+		} else if out != tc.want+"\n" {
+			t.Errorf("%v %s %v: got %q, want %s", tc.a, tc.op, tc.b, out, tc.want)
+		}
+	}
+}
+
+func TestDoubleInstructions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b float64
+		op   bytecode.Opcode
+		want string
+	}{
+		{0.1, 0.2, bytecode.DADD, "0.30000000000000004"},
+		{-7, 2, bytecode.DREM, "-1.0"},
+		{7, -2, bytecode.DREM, "1.0"},
+		{1, math.Inf(1), bytecode.DREM, "1.0"},
+		{-1, 0, bytecode.DDIV, "-Infinity"},
+		{3, 1.5, bytecode.DMUL, "4.5"},
+		{math.NaN(), 0, bytecode.DCMPL, "-1"},
+		{math.NaN(), 0, bytecode.DCMPG, "1"},
+		{3, 2, bytecode.DCMPL, "1"},
+	} {
+		want := "D"
+		if tc.op == bytecode.DCMPL || tc.op == bytecode.DCMPG {
+			want = "I"
+		}
+		_, out, err := run(t, "(DD)V", func(c *builder.CodeBuilder) {
+			c.Local(bytecode.DLOAD, 0).Local(bytecode.DLOAD, 2).Op(tc.op)
+			printTop(c, want).Op(bytecode.RETURN)
+		}, tc.a, Top, tc.b, Top)
+		if err != nil {
+			t.Errorf("%v %s %v: %v", tc.a, tc.op, tc.b, err)
+		} else if out != tc.want+"\n" {
+			t.Errorf("%v %s %v: got %q, want %s", tc.a, tc.op, tc.b, out, tc.want)
+		}
+	}
+}
+
+func TestFloatConversions(t *testing.T) {
+	_, out, err := run(t, "()V", func(c *builder.CodeBuilder) {
+		c.Ldc(float32(1e10)).Op(bytecode.F2I)
+		printTop(c, "I")
+		c.Ldc(float32(-2.7)).Op(bytecode.F2I)
+		printTop(c, "I")
+		c.Ldc(float32(-1e30)).Op(bytecode.F2L)
+		printTop(c, "J")
+		c.Op(bytecode.FCONST_0).Op(bytecode.FCONST_0).Op(bytecode.FDIV).Op(bytecode.F2D).Op(bytecode.D2I)
+		printTop(c, "I")
+		c.Ldc(1e300).Op(bytecode.D2L)
+		printTop(c, "J")
+		c.Ldc(0.1).Op(bytecode.D2F)
+		printTop(c, "F")
+		c.Ldc(1e300).Op(bytecode.D2F)
+		printTop(c, "F")
+		c.Ldc(float32(0.1)).Op(bytecode.F2D)
+		printTop(c, "D")
+		c.Op(bytecode.DCONST_0).Op(bytecode.DNEG)
+		printTop(c, "D")
+		c.Op(bytecode.FCONST_2).Local(bytecode.FSTORE, 0).Op(bytecode.DCONST_1).Local(bytecode.DSTORE, 1)
+		c.Local(bytecode.FLOAD, 0).Op(bytecode.FNEG)
+		printTop(c, "F")
+		c.Local(bytecode.DLOAD, 1)
+		printTop(c, "D")
+		c.Op(bytecode.RETURN)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2147483647\n-2\n-9223372036854775808\n0\n9223372036854775807\n0.1\nInfinity\n0.10000000149011612\n-0.0\n-2.0\n1.0\n"
+	if out != want {
+		t.Errorf("got  %q\nwant %q", out, want)
+	}
+}
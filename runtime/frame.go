@@ -0,0 +1,158 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+
+	"gjvm/classfile"
+)
+
+// Value is what a local variable or operand stack slot holds: int32 for
+// boolean, byte, char, short and int, float32, int64, float64, or a reference,
+// which is nil for null, a string for a java.lang.String, or an object.
+//
+// A long or double takes two slots, like in the JVM: the value, then Top.
+type Value any
+
+// top fills the second slot of a long or double.
+type top struct{}
+
+func (top) String() string { return "top" }
+
+var Top Value = top{}
+
+// Frame is the state of one method invocation: the code it runs, the pc of
+// the current instruction, its local variables and its operand stack.
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-2.html#jvms-2.6
+type Frame struct {
+	Class  *classfile.ClassFile
+	Method classfile.MethodInfo
+	Code   []byte
+	PC     int
+	Locals []Value
+	Stack  *OperandStack
+
+	// returned is set by a return instruction, along with the result.
+	returned bool
+	result   Value
+}
+
+// NewFrame sets up a frame for method, with locals and stack sized from its
+// Code attribute.
+func NewFrame(class *classfile.ClassFile, method classfile.MethodInfo) (*Frame, error) {
+	code, ok := classfile.FindAttribute[classfile.CodeAttribute](method.Attributes)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s%s has no code", className(class), method.Name, method.Descriptor)
+	}
+	return &Frame{
+		Class:  class,
+		Method: method,
+		Code:   code.Code,
+		Locals: make([]Value, code.MaxLocals),
+		Stack:  NewOperandStack(int(code.MaxStack)),
+	}, nil
+}
+
+func (f *Frame) String() string {
+	return fmt.Sprintf("%s.%s%s pc %d", className(f.Class), f.Method.Name, f.Method.Descriptor, f.PC)
+}
+
+func (f *Frame) local(index int) Value {
+	if index >= len(f.Locals) {
+		panic(fmt.Errorf("local variable %d out of range, max_locals is %d", index, len(f.Locals)))
+	}
+	return f.Locals[index]
+}
+
+func (f *Frame) setLocal(index int, v Value) {
+	if index >= len(f.Locals) {
+		panic(fmt.Errorf("local variable %d out of range, max_locals is %d", index, len(f.Locals)))
+	}
+	f.Locals[index] = v
+}
+
+// setLocal2 stores a long or double in index and index+1.
+func (f *Frame) setLocal2(index int, v Value) {
+	f.setLocal(index+1, Top)
+	f.setLocal(index, v)
+}
+
+// className returns the binary name of class, e.g. "com.x.Foo".
+func className(class *classfile.ClassFile) string {
+	return strings.ReplaceAll(class.Name(), "/", ".")
+}
+
+// OperandStack holds at most the max_stack slots it was created with.
+// Pushing more or popping from an empty stack, which the verifier rules
+// out, panics with an error that the interpreter reports.
+type OperandStack struct {
+	slots []Value
+}
+
+func NewOperandStack(max int) *OperandStack {
+	return &OperandStack{make([]Value, 0, max)}
+}
+
+func (s *OperandStack) Len() int {
+	return len(s.slots)
+}
+
+func (s *OperandStack) Push(v Value) {
+	if len(s.slots) == cap(s.slots) {
+		panic(fmt.Errorf("operand stack overflow, max_stack is %d", cap(s.slots)))
+	}
+	s.slots = append(s.slots, v)
+}
+
+func (s *OperandStack) Pop() Value {
+	if len(s.slots) == 0 {
+		panic(fmt.Errorf("operand stack underflow"))
+	}
+	v := s.slots[len(s.slots)-1]
+	s.slots = s.slots[:len(s.slots)-1]
+	return v
+}
+
+// Peek returns the slot n below the top without popping it.
+func (s *OperandStack) Peek(n int) Value {
+	if n >= len(s.slots) {
+		panic(fmt.Errorf("operand stack underflow"))
+	}
+	return s.slots[len(s.slots)-1-n]
+}
+
+func (s *OperandStack) PushInt(v int32) {
+	s.Push(v)
+}
+
+func (s *OperandStack) PopInt() int32 {
+	return s.Pop().(int32)
+}
+
+func (s *OperandStack) PushLong(v int64) {
+	s.Push(v)
+	s.Push(Top)
+}
+
+func (s *OperandStack) PopLong() int64 {
+	s.Pop()
+	return s.Pop().(int64)
+}
+
+func (s *OperandStack) PushFloat(v float32) {
+	s.Push(v)
+}
+
+func (s *OperandStack) PopFloat() float32 {
+	return s.Pop().(float32)
+}
+
+func (s *OperandStack) PushDouble(v float64) {
+	s.Push(v)
+	s.Push(Top)
+}
+
+func (s *OperandStack) PopDouble() float64 {
+	s.Pop()
+	return s.Pop().(float64)
+}
@@ -0,0 +1,100 @@
+package runtime
+
+import "gjvm/bytecode"
+
+// Go's int32 and int64 arithmetic wraps around in two's complement like
+// Java's, and defines MinInt / -1 as MinInt and MinInt % -1 as 0 as the JVMS
+// does, so only division by zero and the shift counts need handling.
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-6.html#jvms-6.5.idiv
+
+func divisionByZero() *Throwable {
+	return throw("java.lang.ArithmeticException", "/ by zero")
+}
+
+// intBinary applies one of the int instructions from iadd to ixor.
+func intBinary(op bytecode.Opcode, a, b int32) (int32, error) {
+	switch op {
+	case bytecode.IADD:
+		return a + b, nil
+	case bytecode.ISUB:
+		return a - b, nil
+	case bytecode.IMUL:
+		return a * b, nil
+	case bytecode.IDIV:
+		if b == 0 {
+			return 0, divisionByZero()
+		}
+		return a / b, nil
+	case bytecode.IREM:
+		if b == 0 {
+			return 0, divisionByZero()
+		}
+		return a % b, nil
+	case bytecode.ISHL:
+		return a << (b & 0x1f), nil
+	case bytecode.ISHR:
+		return a >> (b & 0x1f), nil
+	case bytecode.IUSHR:
+		return int32(uint32(a) >> (b & 0x1f)), nil
+	case bytecode.IAND:
+		return a & b, nil
+	case bytecode.IOR:
+		return a | b, nil
+	case bytecode.IXOR:
+		return a ^ b, nil
+	}
+	panic("not an int instruction: " + op.String())
+}
+
+// longBinary applies one of the long instructions from ladd to lxor, but
+// not the shifts, whose count is an int.
+func longBinary(op bytecode.Opcode, a, b int64) (int64, error) {
+	switch op {
+	case bytecode.LADD:
+		return a + b, nil
+	case bytecode.LSUB:
+		return a - b, nil
+	case bytecode.LMUL:
+		return a * b, nil
+	case bytecode.LDIV:
+		if b == 0 {
+			return 0, divisionByZero()
+		}
+		return a / b, nil
+	case bytecode.LREM:
+		if b == 0 {
+			return 0, divisionByZero()
+		}
+		return a % b, nil
+	case bytecode.LAND:
+		return a & b, nil
+	case bytecode.LOR:
+		return a | b, nil
+	case bytecode.LXOR:
+		return a ^ b, nil
+	}
+	panic("not a long instruction: " + op.String())
+}
+
+// longShift applies lshl, lshr or lushr, which use the low 6 bits of s.
+func longShift(op bytecode.Opcode, a int64, s int32) int64 {
+	s &= 0x3f
+	switch op {
+	case bytecode.LSHL:
+		return a << s
+	case bytecode.LSHR:
+		return a >> s
+	}
+	return int64(uint64(a) >> s)
+}
+
+// compare returns -1, 0 or 1 as a is less than, equal to or greater than b.
+func compare[T int32 | int64 | float32 | float64](a, b T) int32 {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package runtime
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"gjvm/builder"
+	"gjvm/bytecode"
+)
+
+func TestIntInstructions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b int32
+		op   bytecode.Opcode
+		want string
+	}{
+		{math.MaxInt32, 1, bytecode.IADD, "-2147483648"},
+		{math.MinInt32, 1, bytecode.ISUB, "2147483647"},
+		{0x10000, 0x10000, bytecode.IMUL, "0"},
+		{-7, 2, bytecode.IDIV, "-3"},
+		{math.MinInt32, -1, bytecode.IDIV, "-2147483648"},
+		{-7, 2, bytecode.IREM, "-1"},
+		{math.MinInt32, -1, bytecode.IREM, "0"},
+		{1, 33, bytecode.ISHL, "2"},
+		{-16, 2, bytecode.ISHR, "-4"},
+		{-16, 28, bytecode.IUSHR, "15"},
+		{-16, -4, bytecode.IUSHR, "15"},
+		{12, 10, bytecode.IAND, "8"},
+		{12, 10, bytecode.IOR, "14"},
+		{12, 10, bytecode.IXOR, "6"},
+	} {
+		_, out, err := run(t, "(II)V", func(c *builder.CodeBuilder) {
+			c.Local(bytecode.ILOAD, 0).Local(bytecode.ILOAD, 1).Op(tc.op)
+			printTop(c, "I").Op(bytecode.RETURN)
+		}, tc.a, tc.b)
+		if err != nil {
+			t.Errorf("%d %s %d: %v", tc.a, tc.op, tc.b, err)
+		} else if out != tc.want+"\n" {
+			t.Errorf("%d %s %d: got %q, want %s", tc.a, tc.op, tc.b, out, tc.want)
+		}
+	}
+}
+
+func TestLongInstructions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b int64
+		op   bytecode.Opcode
+		want string
+	}{
+		{math.MaxInt64, 1, bytecode.LADD, "-9223372036854775808"},
+		{1 << 40, 1 << 30, bytecode.LSUB, "1098437885952"},
+		{1 << 32, 1 << 32, bytecode.LMUL, "0"},
+		{math.MinInt64, -1, bytecode.LDIV, "-9223372036854775808"},
+		{-7, 2, bytecode.LREM, "-1"},
+		{12, 10, bytecode.LAND, "8"},
+		{12, 10, bytecode.LOR, "14"},
+		{12, 10, bytecode.LXOR, "6"},
+	} {
+		_, out, err := run(t, "(JJ)V", func(c *builder.CodeBuilder) {
+			c.Local(bytecode.LLOAD, 0).Local(bytecode.LLOAD, 2).Op(tc.op)
+			printTop(c, "J").Op(bytecode.RETURN)
+		}, tc.a, Top, tc.b, Top)
+		if err != nil {
+			t.Errorf("%d %s %d: %v", tc.a, tc.op, tc.b, err)
+		} else if out != tc.want+"\n" {
+			t.Errorf("%d %s %d: got %q, want %s", tc.a, tc.op, tc.b, out, tc.want)
+		}
+	}
+}
+
+func TestIntegerShiftsAndConversions(t *testing.T) {
+	_, out, err := run(t, "()V", func(c *builder.CodeBuilder) {
+		c.Op(bytecode.LCONST_1).Push(65).Op(bytecode.LSHL) // 1L << (65 & 63)
+		printTop(c, "J")
+		c.Ldc(int64(-16)).Push(60).Op(bytecode.LUSHR)
+		printTop(c, "J")
+		c.Ldc(int64(-16)).Push(2).Op(bytecode.LSHR)
+		printTop(c, "J")
+		c.Ldc(int64(1)<<32 + 5).Op(bytecode.L2I)
+		printTop(c, "I")
+		c.Push(-1).Op(bytecode.I2L)
+		printTop(c, "J")
+		c.Push(200).Op(bytecode.I2B)
+		printTop(c, "I")
+		c.Push(-1).Op(bytecode.I2C)
+		printTop(c, "I")
+		c.Push(65).Op(bytecode.I2C)
+		printTop(c, "C")
+		c.Push(40000).Op(bytecode.I2S)
+		printTop(c, "I")
+		c.Push(16777217).Op(bytecode.I2F)
+		printTop(c, "F")
+		c.Ldc(int64(1)<<53 + 1).Op(bytecode.L2D)
+		printTop(c, "D")
+		c.Ldc(int64(5)).Op(bytecode.LNEG).Op(bytecode.LCONST_0).Op(bytecode.LCMP)
+		printTop(c, "I")
+		c.Push(7).Local(bytecode.ISTORE, 0).Iinc(0, -10).Local(bytecode.ILOAD, 0).Op(bytecode.INEG)
+		printTop(c, "I")
+		c.Op(bytecode.RETURN)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2\n15\n-4\n5\n-1\n-56\n65535\nA\n-25536\n1.6777216E7\n9.007199254740992E15\n-1\n3\n"
+	if out != want {
+		t.Errorf("got  %q\nwant %q", out, want)
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	for _, op := range []bytecode.Opcode{bytecode.IDIV, bytecode.IREM, bytecode.LDIV, bytecode.LREM} {
+		_, _, err := run(t, "()V", func(c *builder.CodeBuilder) {
+			if op == bytecode.IDIV || op == bytecode.IREM {
+				c.Push(1).Push(0).Op(op).Op(bytecode.POP)
+			} else {
+				c.Op(bytecode.LCONST_1).Op(bytecode.LCONST_0).Op(op).Op(bytecode.POP2)
+			}
+			c.Op(bytecode.RETURN)
+		})
+		var e *Throwable
+		if !errors.As(err, &e) || e.Error() != "java.lang.ArithmeticException: / by zero" {
+			t.Errorf("%s: got %v", op, err)
+			continue
+		}
+		if want := "java.lang.ArithmeticException: / by zero\n\tat Test.f()V pc 2"; e.Trace() != want {
+			t.Errorf("%s: got %q, want %q", op, e.Trace(), want)
+		}
+	}
+}
@@ -1,162 +1,396 @@
 package runtime
 
 import (
-	"bytes"
-	"encoding/binary"
 	"fmt"
+	"io"
+	"os"
 
+	"gjvm/bytecode"
 	"gjvm/classfile"
+	"gjvm/descriptor"
 )
 
-func Interpret(code []byte, stack *OperandStack, cp classfile.ConstantPool) {
-	r := newReader(code)
-	sys := NewSystem()
+// Thread runs methods, keeping a Frame for every invocation in progress.
+type Thread struct {
+	// MaxDepth is the most frames the thread may hold; a call beyond it
+	// throws StackOverflowError.
+	MaxDepth int
+	// Loader finds the classes that invoked methods belong to, other than
+	// those passed to Invoke. A nil Loader finds none.
+	Loader ClassLoader
 
-	frames := make([]interface{}, 0)
-
-	for r.hasNext() {
-		frame, err := r.readFrame(cp)
-		if err != nil {
-			fmt.Println(err)
-			break
-		}
-		fmt.Printf("- %v\n", frame)
-		frames = append(frames, frame)
-	}
-
-	fmt.Println("=================================================================")
-	fmt.Println("Exec result")
-	fmt.Println("=================================================================")
-	for _, frame := range frames {
-		switch frame.(type) {
-		case *GetStatic:
-			stack.Push(frame)
-		case *Ldc:
-			stack.Push(frame)
-		case *InvokeVirtual:
-			frame := frame.(*InvokeVirtual)
-			args := make([]interface{}, len(frame.ArgTypes))
-			for i := range frame.ArgTypes {
-				frame := stack.Pop()
-				switch frame.(type) {
-				case *Ldc:
-					args[i] = frame.(*Ldc).value
-				}
-			}
-			// static := stack.Pop().(*GetStatic)
-			method := fmt.Sprintf("%s.%s", frame.Class, frame.Name)
-			sys.Call(method, args...)
-		}
-	}
+	frames  []*Frame
+	classes map[string]*classfile.ClassFile
+	sys     *System
 }
 
-type reader struct {
-	r *bytes.Reader
-}
-
-func newReader(code []byte) *reader {
-	return &reader{bytes.NewReader(code)}
+// NewThread returns a thread whose System.out writes to out.
+func NewThread(out io.Writer) *Thread {
+	return &Thread{
+		MaxDepth: DefaultMaxDepth,
+		classes:  map[string]*classfile.ClassFile{},
+		sys:      NewSystem(out),
+	}
 }
 
-func (r *reader) hasNext() bool {
-	return r.r.Len() > 0
+// Interpret runs method on a new thread that prints to standard output.
+func Interpret(class *classfile.ClassFile, method classfile.MethodInfo, args ...Value) (Value, error) {
+	return NewThread(os.Stdout).Invoke(class, method, args...)
 }
 
-func (r *reader) readFrame(cp classfile.ConstantPool) (Frame, error) {
-	opcode, err := r.r.ReadByte()
+// Invoke runs method with args as its first local variables, a long or double
+// taking two, and returns its result, or nil for a void method.
+func (t *Thread) Invoke(class *classfile.ClassFile, method classfile.MethodInfo, args ...Value) (Value, error) {
+	f, err := NewFrame(class, method)
 	if err != nil {
 		return nil, err
 	}
+	if len(args) > len(f.Locals) {
+		return nil, fmt.Errorf("%s: %d arguments for %d local variables", f, len(args), len(f.Locals))
+	}
+	copy(f.Locals, args)
+	t.classes[className(class)] = class
+	return t.run(f)
+}
 
-	switch opcode {
-	case 0xb2: // getstatic
-		index := make([]byte, 2)
-		r.r.Read(index)
-		i := binary.BigEndian.Uint16(index)
-		constant := cp[i].(*classfile.ConstantFieldrefInfo)
-		filedRef := constant.Resolve(cp)
-		return &GetStatic{filedRef.Class, filedRef.Name, filedRef.Descriptor}, nil
-	case 0x12: // ldc
-		index := make([]byte, 1)
-		r.r.Read(index)
-		i := uint16(index[0])
-		constantInfo := cp[i]
-		switch constantInfo.(type) {
-		case *classfile.ConstantStringInfo:
-			str := constantInfo.(*classfile.ConstantStringInfo).Resolve(cp)
-			return &Ldc{str}, nil
-		default:
-			return nil, fmt.Errorf("unsupported: %T", constantInfo)
-		}
-	case 0xb6: // invokevirtual
-		index := make([]byte, 2)
-		r.r.Read(index)
-		i := binary.BigEndian.Uint16(index)
-		method := cp[i].(*classfile.ConstantMethodrefInfo).Resolve(cp)
-		return &InvokeVirtual{method.Class, method.Name, method.ArgTypes}, nil
-	case 0xb1: // return
-		return &Return{}, nil
+// run is the fetch-decode-execute loop over one frame.
+func (t *Thread) run(f *Frame) (result Value, err error) {
+	if len(t.frames) >= t.MaxDepth {
+		return nil, throw("java.lang.StackOverflowError", "")
 	}
+	t.frames = append(t.frames, f)
+	defer func() {
+		t.frames = t.frames[:len(t.frames)-1]
+	}()
+	defer func() {
+		// stack and local variable faults panic with an error
+		if r := recover(); r != nil {
+			e, ok := r.(error)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("%s: %w", f, e)
+		}
+	}()
 
-	return nil, fmt.Errorf("unknown opcode: %d", opcode)
+	for !f.returned {
+		insn, err := bytecode.Decode(f.Code, f.PC)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		next, err := t.execute(f, insn)
+		if e, ok := err.(*Throwable); ok {
+			e.StackTrace = append(e.StackTrace, f.String())
+			return nil, e
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		f.PC = next
+	}
+	return f.result, nil
 }
 
-type Frame interface {
-	String() string
-}
+// execute runs one instruction and returns the pc of the next.
+func (t *Thread) execute(f *Frame, insn bytecode.Instruction) (int, error) {
+	next := insn.PC + insn.Len()
+	stack := f.Stack
+	switch op := insn.Opcode; op {
+	case bytecode.NOP:
+	case bytecode.ACONST_NULL:
+		stack.Push(nil)
+	case bytecode.LDC, bytecode.LDC_W, bytecode.LDC2_W:
+		v, err := constant(f.Class.ConstantPool, uint16(insn.Index))
+		if err != nil {
+			return 0, err
+		}
+		stack.Push(v)
+		if op == bytecode.LDC2_W {
+			stack.Push(Top)
+		}
 
-type GetStatic struct {
-	Class      string
-	Name       string
-	Descriptor string
-}
+	case bytecode.ICONST_M1, bytecode.ICONST_0, bytecode.ICONST_1, bytecode.ICONST_2,
+		bytecode.ICONST_3, bytecode.ICONST_4, bytecode.ICONST_5:
+		stack.PushInt(int32(op) - int32(bytecode.ICONST_0))
+	case bytecode.LCONST_0, bytecode.LCONST_1:
+		stack.PushLong(int64(op - bytecode.LCONST_0))
+	case bytecode.FCONST_0, bytecode.FCONST_1, bytecode.FCONST_2:
+		stack.PushFloat(float32(op - bytecode.FCONST_0))
+	case bytecode.DCONST_0, bytecode.DCONST_1:
+		stack.PushDouble(float64(op - bytecode.DCONST_0))
+	case bytecode.BIPUSH, bytecode.SIPUSH:
+		stack.PushInt(int32(insn.Value))
 
-func (gs *GetStatic) String() string {
-	return fmt.Sprintf("0xb2 getstatic: %s.%s %s", gs.Class, gs.Name, gs.Descriptor)
-}
+	case bytecode.ILOAD, bytecode.FLOAD, bytecode.ALOAD:
+		stack.Push(f.local(insn.Index))
+	case bytecode.LLOAD, bytecode.DLOAD:
+		stack.Push(f.local(insn.Index))
+		stack.Push(Top)
+	case bytecode.ILOAD_0, bytecode.ILOAD_1, bytecode.ILOAD_2, bytecode.ILOAD_3:
+		stack.Push(f.local(int(op - bytecode.ILOAD_0)))
+	case bytecode.LLOAD_0, bytecode.LLOAD_1, bytecode.LLOAD_2, bytecode.LLOAD_3:
+		stack.Push(f.local(int(op - bytecode.LLOAD_0)))
+		stack.Push(Top)
+	case bytecode.FLOAD_0, bytecode.FLOAD_1, bytecode.FLOAD_2, bytecode.FLOAD_3:
+		stack.Push(f.local(int(op - bytecode.FLOAD_0)))
+	case bytecode.DLOAD_0, bytecode.DLOAD_1, bytecode.DLOAD_2, bytecode.DLOAD_3:
+		stack.Push(f.local(int(op - bytecode.DLOAD_0)))
+		stack.Push(Top)
+	case bytecode.ALOAD_0, bytecode.ALOAD_1, bytecode.ALOAD_2, bytecode.ALOAD_3:
+		stack.Push(f.local(int(op - bytecode.ALOAD_0)))
+	case bytecode.ISTORE:
+		f.setLocal(insn.Index, stack.PopInt())
+	case bytecode.LSTORE:
+		f.setLocal2(insn.Index, stack.PopLong())
+	case bytecode.FSTORE:
+		f.setLocal(insn.Index, stack.PopFloat())
+	case bytecode.DSTORE:
+		f.setLocal2(insn.Index, stack.PopDouble())
+	case bytecode.ASTORE:
+		f.setLocal(insn.Index, stack.Pop())
+	case bytecode.ISTORE_0, bytecode.ISTORE_1, bytecode.ISTORE_2, bytecode.ISTORE_3:
+		f.setLocal(int(op-bytecode.ISTORE_0), stack.PopInt())
+	case bytecode.LSTORE_0, bytecode.LSTORE_1, bytecode.LSTORE_2, bytecode.LSTORE_3:
+		f.setLocal2(int(op-bytecode.LSTORE_0), stack.PopLong())
+	case bytecode.FSTORE_0, bytecode.FSTORE_1, bytecode.FSTORE_2, bytecode.FSTORE_3:
+		f.setLocal(int(op-bytecode.FSTORE_0), stack.PopFloat())
+	case bytecode.DSTORE_0, bytecode.DSTORE_1, bytecode.DSTORE_2, bytecode.DSTORE_3:
+		f.setLocal2(int(op-bytecode.DSTORE_0), stack.PopDouble())
+	case bytecode.ASTORE_0, bytecode.ASTORE_1, bytecode.ASTORE_2, bytecode.ASTORE_3:
+		f.setLocal(int(op-bytecode.ASTORE_0), stack.Pop())
+	case bytecode.IINC:
+		f.setLocal(insn.Index, f.local(insn.Index).(int32)+int32(insn.Value))
 
-type Ldc struct {
-	value any
-}
+	// The stack instructions move slots regardless of type; a long or double
+	// being two slots makes each of them do what the JVMS says for every form.
+	case bytecode.POP:
+		stack.Pop()
+	case bytecode.POP2:
+		stack.Pop()
+		stack.Pop()
+	case bytecode.DUP:
+		stack.Push(stack.Peek(0))
+	case bytecode.DUP_X1:
+		v1, v2 := stack.Pop(), stack.Pop()
+		pushAll(stack, v1, v2, v1)
+	case bytecode.DUP_X2:
+		v1, v2, v3 := stack.Pop(), stack.Pop(), stack.Pop()
+		pushAll(stack, v1, v3, v2, v1)
+	case bytecode.DUP2:
+		v1, v2 := stack.Peek(0), stack.Peek(1)
+		pushAll(stack, v2, v1)
+	case bytecode.DUP2_X1:
+		v1, v2, v3 := stack.Pop(), stack.Pop(), stack.Pop()
+		pushAll(stack, v2, v1, v3, v2, v1)
+	case bytecode.DUP2_X2:
+		v1, v2, v3, v4 := stack.Pop(), stack.Pop(), stack.Pop(), stack.Pop()
+		pushAll(stack, v2, v1, v4, v3, v2, v1)
+	case bytecode.SWAP:
+		v1, v2 := stack.Pop(), stack.Pop()
+		pushAll(stack, v1, v2)
 
-func (ldc *Ldc) String() string {
-	return fmt.Sprintf("0x12 ldc: %v", ldc.value)
-}
+	case bytecode.IADD, bytecode.ISUB, bytecode.IMUL, bytecode.IDIV, bytecode.IREM,
+		bytecode.ISHL, bytecode.ISHR, bytecode.IUSHR, bytecode.IAND, bytecode.IOR, bytecode.IXOR:
+		b, a := stack.PopInt(), stack.PopInt()
+		v, err := intBinary(op, a, b)
+		if err != nil {
+			return 0, err
+		}
+		stack.PushInt(v)
+	case bytecode.LADD, bytecode.LSUB, bytecode.LMUL, bytecode.LDIV, bytecode.LREM,
+		bytecode.LAND, bytecode.LOR, bytecode.LXOR:
+		b, a := stack.PopLong(), stack.PopLong()
+		v, err := longBinary(op, a, b)
+		if err != nil {
+			return 0, err
+		}
+		stack.PushLong(v)
+	case bytecode.LSHL, bytecode.LSHR, bytecode.LUSHR:
+		s := stack.PopInt()
+		stack.PushLong(longShift(op, stack.PopLong(), s))
+	case bytecode.INEG:
+		stack.PushInt(-stack.PopInt())
+	case bytecode.LNEG:
+		stack.PushLong(-stack.PopLong())
+	case bytecode.LCMP:
+		b, a := stack.PopLong(), stack.PopLong()
+		stack.PushInt(compare(a, b))
 
-type InvokeVirtual struct {
-	Class    string
-	Name     string
-	ArgTypes []string
-}
+	case bytecode.FADD, bytecode.FSUB, bytecode.FMUL, bytecode.FDIV, bytecode.FREM:
+		b, a := stack.PopFloat(), stack.PopFloat()
+		stack.PushFloat(floatBinary(op, a, b))
+	case bytecode.DADD, bytecode.DSUB, bytecode.DMUL, bytecode.DDIV, bytecode.DREM:
+		b, a := stack.PopDouble(), stack.PopDouble()
+		stack.PushDouble(doubleBinary(op, a, b))
+	case bytecode.FNEG:
+		stack.PushFloat(-stack.PopFloat())
+	case bytecode.DNEG:
+		stack.PushDouble(-stack.PopDouble())
+	case bytecode.FCMPL, bytecode.FCMPG:
+		b, a := stack.PopFloat(), stack.PopFloat()
+		stack.PushInt(floatCompare(a, b, nanComparison(op)))
+	case bytecode.DCMPL, bytecode.DCMPG:
+		b, a := stack.PopDouble(), stack.PopDouble()
+		stack.PushInt(floatCompare(a, b, nanComparison(op)))
 
-func (iv *InvokeVirtual) String() string {
-	return fmt.Sprintf("0xb6 invokevirtual: %s.%s %v", iv.Class, iv.Name, iv.ArgTypes)
-}
+	case bytecode.I2L:
+		stack.PushLong(int64(stack.PopInt()))
+	case bytecode.I2F:
+		stack.PushFloat(float32(stack.PopInt()))
+	case bytecode.I2D:
+		stack.PushDouble(float64(stack.PopInt()))
+	case bytecode.L2I:
+		stack.PushInt(int32(stack.PopLong()))
+	case bytecode.L2F:
+		stack.PushFloat(float32(stack.PopLong()))
+	case bytecode.L2D:
+		stack.PushDouble(float64(stack.PopLong()))
+	case bytecode.F2I:
+		stack.PushInt(toInt(float64(stack.PopFloat())))
+	case bytecode.F2L:
+		stack.PushLong(toLong(float64(stack.PopFloat())))
+	case bytecode.F2D:
+		stack.PushDouble(float64(stack.PopFloat()))
+	case bytecode.D2I:
+		stack.PushInt(toInt(stack.PopDouble()))
+	case bytecode.D2L:
+		stack.PushLong(toLong(stack.PopDouble()))
+	case bytecode.D2F:
+		stack.PushFloat(float32(stack.PopDouble()))
+	case bytecode.I2B:
+		stack.PushInt(int32(int8(stack.PopInt())))
+	case bytecode.I2C:
+		stack.PushInt(int32(uint16(stack.PopInt())))
+	case bytecode.I2S:
+		stack.PushInt(int32(int16(stack.PopInt())))
 
-type Return struct{}
+	case bytecode.IFEQ, bytecode.IFNE, bytecode.IFLT, bytecode.IFGE, bytecode.IFGT, bytecode.IFLE,
+		bytecode.IF_ICMPEQ, bytecode.IF_ICMPNE, bytecode.IF_ICMPLT,
+		bytecode.IF_ICMPGE, bytecode.IF_ICMPGT, bytecode.IF_ICMPLE,
+		bytecode.IF_ACMPEQ, bytecode.IF_ACMPNE, bytecode.IFNULL, bytecode.IFNONNULL:
+		if branches(op, stack) {
+			next = insn.Target
+		}
+	case bytecode.GOTO, bytecode.GOTO_W:
+		next = insn.Target
+	case bytecode.JSR, bytecode.JSR_W:
+		stack.Push(returnAddress(next))
+		next = insn.Target
+	case bytecode.RET:
+		addr, ok := f.local(insn.Index).(returnAddress)
+		if !ok {
+			return 0, fmt.Errorf("ret of local %d, which is not a return address", insn.Index)
+		}
+		next = int(addr)
+	case bytecode.TABLESWITCH, bytecode.LOOKUPSWITCH:
+		next = switchTarget(insn, stack.PopInt())
 
-func (r *Return) String() string {
-	return fmt.Sprintf("0xb1 return")
-}
+	case bytecode.GETSTATIC:
+		cp := f.Class.ConstantPool
+		ref, ok := entry(cp, insn.Index).(*classfile.ConstantFieldrefInfo)
+		if !ok {
+			return 0, fmt.Errorf("getstatic #%d is not a field", insn.Index)
+		}
+		field, err := ref.Resolve(cp)
+		if err != nil {
+			return 0, err
+		}
+		if field.Class != "java.lang.System" || field.Name != "out" {
+			return 0, fmt.Errorf("unsupported static field %s.%s", field.Class, field.Name)
+		}
+		stack.Push(t.sys.Out)
+	case bytecode.INVOKEVIRTUAL:
+		cp := f.Class.ConstantPool
+		ref, ok := entry(cp, insn.Index).(*classfile.ConstantMethodrefInfo)
+		if !ok {
+			return 0, fmt.Errorf("invokevirtual #%d is not a method", insn.Index)
+		}
+		method, err := ref.Resolve(cp)
+		if err != nil {
+			return 0, err
+		}
+		args := popArgs(stack, method.Descriptor)
+		if _, ok := stack.Pop().(*PrintStream); !ok {
+			return 0, fmt.Errorf("unsupported method %s.%s", method.Class, method.Name)
+		}
+		result, err := t.sys.Call(method.Class+"."+method.Name, method.Descriptor, args...)
+		if err != nil {
+			return 0, err
+		}
+		pushResult(stack, result, method.Descriptor.ReturnType)
+
+	case bytecode.INVOKESTATIC, bytecode.INVOKESPECIAL:
+		if err := t.invoke(f, insn); err != nil {
+			return 0, err
+		}
 
-type Unsupported struct {
-	opcode byte
+	case bytecode.IRETURN, bytecode.FRETURN, bytecode.ARETURN:
+		f.result, f.returned = stack.Pop(), true
+	case bytecode.LRETURN, bytecode.DRETURN:
+		stack.Pop()
+		f.result, f.returned = stack.Pop(), true
+	case bytecode.RETURN:
+		f.returned = true
+	default:
+		return 0, fmt.Errorf("unsupported opcode %s", op)
+	}
+	return next, nil
 }
 
-func (u *Unsupported) String() string {
-	return fmt.Sprintf("unsupported opcode: %d", u.opcode)
+func pushAll(stack *OperandStack, values ...Value) {
+	for _, v := range values {
+		stack.Push(v)
+	}
 }
 
-type OperandStack []interface{}
+// popArgs pops the arguments of a method, last first, dropping the second
+// slot of longs and doubles.
+func popArgs(stack *OperandStack, md descriptor.MethodDescriptor) []Value {
+	args := make([]Value, len(md.Parameters))
+	for i := len(args) - 1; i >= 0; i-- {
+		if md.Parameters[i].Slots() == 2 {
+			stack.Pop()
+		}
+		args[i] = stack.Pop()
+	}
+	return args
+}
 
-func (s *OperandStack) Push(value interface{}) {
-	*s = append(*s, value)
+// pushResult pushes what a method of return type t returned.
+func pushResult(stack *OperandStack, v Value, t descriptor.FieldType) {
+	switch t.Slots() {
+	case 1:
+		stack.Push(v)
+	case 2:
+		stack.Push(v)
+		stack.Push(Top)
+	}
 }
-func (s *OperandStack) Pop() interface{} {
-	v := (*s)[len(*s)-1]
-	*s = (*s)[:len(*s)-1]
-	return v
+
+// constant is the value ldc, ldc_w or ldc2_w pushes for a constant pool entry.
+func constant(cp classfile.ConstantPool, index uint16) (Value, error) {
+	if !cp.IsUsable(index) {
+		return nil, fmt.Errorf("ldc of unusable constant #%d", index)
+	}
+	switch info := cp[index].(type) {
+	case *classfile.ConstantIntegerInfo:
+		return info.Value(), nil
+	case *classfile.ConstantFloatInfo:
+		return info.Value(), nil
+	case *classfile.ConstantLongInfo:
+		return info.Value(), nil
+	case *classfile.ConstantDoubleInfo:
+		return info.Value(), nil
+	case *classfile.ConstantStringInfo:
+		return info.Resolve(cp)
+	}
+	return nil, fmt.Errorf("unsupported constant %T", cp[index])
 }
-func NewOperandStack() *OperandStack {
-	return &OperandStack{}
+
+// entry returns constant pool entry index, or nil if it is not usable.
+func entry(cp classfile.ConstantPool, index int) classfile.ConstantInfo {
+	if index < 0 || index > 0xFFFF || !cp.IsUsable(uint16(index)) {
+		return nil
+	}
+	return cp[index]
 }
@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gjvm/builder"
+	"gjvm/bytecode"
+	"gjvm/classfile"
+)
+
+const publicStatic = classfile.ACC_PUBLIC | classfile.ACC_STATIC
+
+// run builds a class whose static method f has the given code and runs it.
+func run(t *testing.T, desc string, code func(c *builder.CodeBuilder), args ...Value) (Value, string, error) {
+	t.Helper()
+	c := builder.NewClass("Test")
+	code(c.Method(publicStatic, "f", desc).Code())
+	cf, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	result, err := NewThread(&out).Invoke(cf, cf.Methods[0], args...)
+	return result, out.String(), err
+}
+
+// printTop prints the value on top of the stack, which is of type desc.
+func printTop(c *builder.CodeBuilder, desc string) *builder.CodeBuilder {
+	c.Field(bytecode.GETSTATIC, "java/lang/System", "out", "Ljava/io/PrintStream;")
+	if desc == "J" || desc == "D" {
+		c.Op(bytecode.DUP_X2).Op(bytecode.POP)
+	} else {
+		c.Op(bytecode.SWAP)
+	}
+	return c.Invoke(bytecode.INVOKEVIRTUAL, "java/io/PrintStream", "println", "("+desc+")V")
+}
+
+func TestStackInstructions(t *testing.T) {
+	_, out, err := run(t, "(Ljava/lang/String;)V", func(c *builder.CodeBuilder) {
+		c.Ldc("a").Local(bytecode.ALOAD, 0).Op(bytecode.SWAP) // 0 a
+		c.Op(bytecode.DUP_X1)                                 // a 0 a
+		printTop(c, "Ljava/lang/String;")                     // a 0
+		printTop(c, "Ljava/lang/String;")                     // a
+		c.Local(bytecode.ASTORE, 3).Local(bytecode.ALOAD, 3)
+		printTop(c, "Ljava/lang/String;")
+		c.Ldc(int64(1) << 40).Op(bytecode.DUP2).Op(bytecode.POP2)
+		printTop(c, "J")
+		c.Op(bytecode.ACONST_NULL)
+		printTop(c, "Ljava/lang/Object;")
+		c.Op(bytecode.RETURN)
+	}, "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a\n0\na\n1099511627776\nnull\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestOperandStackBound(t *testing.T) {
+	c := builder.NewClass("Test")
+	c.Method(publicStatic, "f", "()V").Code().Ldc("a").Ldc("b").Op(bytecode.POP2).Op(bytecode.RETURN)
+	cf, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := cf.Methods[0].Attributes[0].(classfile.CodeAttribute)
+	code.MaxStack = 1
+	cf.Methods[0].Attributes[0] = code
+	_, err = NewThread(&bytes.Buffer{}).Invoke(cf, cf.Methods[0])
+	if err == nil || !strings.Contains(err.Error(), "Test.f()V pc 2: operand stack overflow") {
+		t.Errorf("got %v, want an operand stack overflow", err)
+	}
+}
+
+func TestUnsupportedOpcode(t *testing.T) {
+	_, _, err := run(t, "()V", func(c *builder.CodeBuilder) {
+		c.Op(bytecode.ACONST_NULL).Op(bytecode.MONITORENTER).Op(bytecode.RETURN)
+	})
+	if err == nil || !strings.Contains(err.Error(), "unsupported opcode monitorenter") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gjvm/bytecode"
+	"gjvm/classfile"
+)
+
+// DefaultMaxDepth is how many frames a new thread may hold before a call
+// throws StackOverflowError.
+const DefaultMaxDepth = 1024
+
+// ClassLoader finds a class by its binary name, e.g. "com.x.Foo".
+type ClassLoader func(name string) (*classfile.ClassFile, error)
+
+// DirLoader loads classes from the class files under root, laid out by
+// package like a class path directory: com.x.Foo is root/com/x/Foo.class.
+func DirLoader(root string) ClassLoader {
+	return func(name string) (*classfile.ClassFile, error) {
+		internal := strings.ReplaceAll(name, ".", "/")
+		file, err := os.Open(filepath.Join(root, filepath.FromSlash(internal)+".class"))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, throw("java.lang.NoClassDefFoundError", internal)
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		class, err := classfile.NewClassFileParser(file).Parse()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name(), err)
+		}
+		if class.Name() != internal {
+			return nil, throw("java.lang.NoClassDefFoundError", fmt.Sprintf("%s (wrong name: %s)", internal, class.Name()))
+		}
+		return class, nil
+	}
+}
+
+// loadClass returns the class with the given binary name, asking the
+// thread's Loader for classes it has not seen yet.
+func (t *Thread) loadClass(name string) (*classfile.ClassFile, error) {
+	if class, ok := t.classes[name]; ok {
+		return class, nil
+	}
+	if t.Loader == nil {
+		return nil, throw("java.lang.NoClassDefFoundError", strings.ReplaceAll(name, ".", "/"))
+	}
+	class, err := t.Loader(name)
+	if err != nil {
+		return nil, err
+	}
+	t.classes[name] = class
+	return class, nil
+}
+
+// resolveMethod finds the method ref names in its class or, failing that, the
+// nearest superclass declaring it.
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-5.html#jvms-5.4.3.3
+func (t *Thread) resolveMethod(ref classfile.MethodRef) (*classfile.ClassFile, classfile.MethodInfo, error) {
+	class, err := t.loadClass(ref.Class)
+	if err != nil {
+		return nil, classfile.MethodInfo{}, err
+	}
+	desc := ref.Descriptor.Descriptor()
+	for class != nil {
+		for _, method := range class.Methods {
+			if method.Name == ref.Name && method.Descriptor == desc {
+				return class, method, nil
+			}
+		}
+		super, err := t.superclass(class)
+		var thrown *Throwable
+		if errors.As(err, &thrown) && thrown.Class == "java.lang.NoClassDefFoundError" {
+			// a superclass the thread cannot find, such as java.lang.Object,
+			// declares nothing it could run
+			break
+		}
+		if err != nil {
+			return nil, classfile.MethodInfo{}, err
+		}
+		class = super
+	}
+	return nil, classfile.MethodInfo{}, throw("java.lang.NoSuchMethodError", ref.Class+"."+ref.Name+desc)
+}
+
+func (t *Thread) superclass(class *classfile.ClassFile) (*classfile.ClassFile, error) {
+	cp := class.ConstantPool
+	if class.SuperClass == 0 {
+		return nil, nil
+	}
+	info, ok := entry(cp, int(class.SuperClass)).(*classfile.ConstantClassInfo)
+	if !ok {
+		return nil, fmt.Errorf("super_class #%d is not a class", class.SuperClass)
+	}
+	name, ok := entry(cp, int(info.NameIndex)).(*classfile.ConstantUtf8Info)
+	if !ok {
+		return nil, fmt.Errorf("class #%d has no name", class.SuperClass)
+	}
+	return t.loadClass(strings.ReplaceAll(name.Value(), "/", "."))
+}
+
+// invoke runs invokestatic or invokespecial: it pops the arguments, and the
+// receiver for invokespecial, into the first local variables of a new frame
+// for the method, runs it, and pushes its result.
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-6.html#jvms-6.5.invokestatic
+func (t *Thread) invoke(f *Frame, insn bytecode.Instruction) error {
+	op := insn.Opcode
+	cp := f.Class.ConstantPool
+	var m classfile.MethodRef
+	var err error
+	// since version 52 both may name an interface method, e.g. a static
+	// or private one
+	switch ref := entry(cp, insn.Index).(type) {
+	case *classfile.ConstantMethodrefInfo:
+		m, err = ref.Resolve(cp)
+	case *classfile.ConstantInterfaceMethodrefInfo:
+		m, err = ref.Resolve(cp)
+	default:
+		return fmt.Errorf("%s #%d is not a method", op, insn.Index)
+	}
+	if err != nil {
+		return err
+	}
+	slots := m.Descriptor.ParameterSlots()
+	if op == bytecode.INVOKESPECIAL {
+		slots++
+		if m.Class == "java.lang.Object" && m.Name == "<init>" {
+			// Object has no state to initialize
+			f.Stack.Pop()
+			return nil
+		}
+	}
+
+	class, method, err := t.resolveMethod(m)
+	if err != nil {
+		return err
+	}
+	if method.IsStatic() != (op == bytecode.INVOKESTATIC) {
+		return throw("java.lang.IncompatibleClassChangeError", fmt.Sprintf("%s of %s.%s%s", op, m.Class, m.Name, method.Descriptor))
+	}
+	callee, err := NewFrame(class, method)
+	if err != nil {
+		return err
+	}
+	if slots > len(callee.Locals) {
+		return fmt.Errorf("%s: %d argument slots for %d local variables", callee, slots, len(callee.Locals))
+	}
+	for i := slots - 1; i >= 0; i-- {
+		callee.Locals[i] = f.Stack.Pop()
+	}
+	if op == bytecode.INVOKESPECIAL && callee.Locals[0] == nil {
+		return throw("java.lang.NullPointerException", "")
+	}
+
+	result, err := t.run(callee)
+	if err != nil {
+		return err
+	}
+	pushResult(f.Stack, result, m.Descriptor.ReturnType)
+	return nil
+}
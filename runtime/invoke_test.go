@@ -0,0 +1,225 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"gjvm/builder"
+	"gjvm/bytecode"
+	"gjvm/classfile"
+)
+
+// invoke runs the static method f of class on a new thread with a loader
+// that finds others.
+func invoke(t *testing.T, class *builder.ClassBuilder, others ...*builder.ClassBuilder) (string, error) {
+	t.Helper()
+	classes := map[string]*classfile.ClassFile{}
+	for _, b := range others {
+		cf, err := b.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		classes[className(cf)] = cf
+	}
+	cf, err := class.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	thread := NewThread(&out)
+	thread.Loader = func(name string) (*classfile.ClassFile, error) {
+		if cf, ok := classes[name]; ok {
+			return cf, nil
+		}
+		return nil, throw("java.lang.NoClassDefFoundError", name)
+	}
+	for _, m := range cf.Methods {
+		if m.Name == "f" {
+			_, err = thread.Invoke(cf, m)
+			return out.String(), err
+		}
+	}
+	t.Fatal("no method f")
+	return "", nil
+}
+
+func TestFibonacci(t *testing.T) {
+	c := builder.NewClass("Test")
+	fib := c.Method(publicStatic, "fib", "(I)I").Code()
+	small := fib.NewLabel()
+	fib.Local(bytecode.ILOAD, 0).Push(2).Jump(bytecode.IF_ICMPLT, small)
+	fib.Local(bytecode.ILOAD, 0).Push(1).Op(bytecode.ISUB).Invoke(bytecode.INVOKESTATIC, "Test", "fib", "(I)I")
+	fib.Local(bytecode.ILOAD, 0).Push(2).Op(bytecode.ISUB).Invoke(bytecode.INVOKESTATIC, "Test", "fib", "(I)I")
+	fib.Op(bytecode.IADD).Op(bytecode.IRETURN)
+	fib.Mark(small).Local(bytecode.ILOAD, 0).Op(bytecode.IRETURN)
+	f := c.Method(publicStatic, "f", "()V").Code()
+	f.Push(20).Invoke(bytecode.INVOKESTATIC, "Test", "fib", "(I)I")
+	printTop(f, "I").Op(bytecode.RETURN)
+
+	out, err := invoke(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "6765\n" {
+		t.Errorf("got %q, want 6765", out)
+	}
+}
+
+// TestArgumentsAndReturns passes every kind of argument, longs and doubles
+// taking two local variables, and returns every kind of result.
+func TestArgumentsAndReturns(t *testing.T) {
+	c := builder.NewClass("Test")
+	c.Method(publicStatic, "mix", "(JIDF)D").Code().
+		Local(bytecode.LLOAD, 0).Local(bytecode.ILOAD, 2).Op(bytecode.I2L).Op(bytecode.LADD).Op(bytecode.L2D).
+		Local(bytecode.DLOAD, 3).Op(bytecode.DADD).
+		Local(bytecode.FLOAD, 5).Op(bytecode.F2D).Op(bytecode.DADD).Op(bytecode.DRETURN)
+	c.Method(publicStatic, "twice", "(J)J").Code().
+		Local(bytecode.LLOAD, 0).Op(bytecode.DUP2).Op(bytecode.LADD).Op(bytecode.LRETURN)
+	c.Method(publicStatic, "half", "(F)F").Code().
+		Local(bytecode.FLOAD, 0).Op(bytecode.FCONST_2).Op(bytecode.FDIV).Op(bytecode.FRETURN)
+	c.Method(publicStatic, "id", "(Ljava/lang/String;)Ljava/lang/String;").Code().
+		Local(bytecode.ALOAD, 0).Op(bytecode.ARETURN)
+	c.Method(publicStatic, "nothing", "()V").Code().Op(bytecode.RETURN)
+	f := c.Method(publicStatic, "f", "()V").Code()
+	f.Ldc(int64(1)<<40).Push(2).Ldc(0.5).Ldc(float32(0.25)).Invoke(bytecode.INVOKESTATIC, "Test", "mix", "(JIDF)D")
+	printTop(f, "D")
+	f.Ldc(int64(1)<<40).Invoke(bytecode.INVOKESTATIC, "Test", "twice", "(J)J")
+	printTop(f, "J")
+	f.Op(bytecode.FCONST_1).Invoke(bytecode.INVOKESTATIC, "Test", "half", "(F)F")
+	printTop(f, "F")
+	f.Invoke(bytecode.INVOKESTATIC, "Test", "nothing", "()V")
+	f.Ldc("s").Invoke(bytecode.INVOKESTATIC, "Test", "id", "(Ljava/lang/String;)Ljava/lang/String;")
+	printTop(f, "Ljava/lang/String;").Op(bytecode.RETURN)
+
+	out, err := invoke(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1.09951162777875E12\n2199023255552\n0.5\ns\n"; out != want {
+		t.Errorf("got  %q\nwant %q", out, want)
+	}
+}
+
+func TestInvokeSpecial(t *testing.T) {
+	c := builder.NewClass("Test")
+	c.Method(classfile.ACC_PRIVATE, "<init>", "()V").Code().
+		Local(bytecode.ALOAD, 0).Invoke(bytecode.INVOKESPECIAL, "java/lang/Object", "<init>", "()V").Op(bytecode.RETURN)
+	c.Method(classfile.ACC_PRIVATE, "self", "(I)Ljava/lang/Object;").Code().
+		Local(bytecode.ALOAD, 0).Op(bytecode.ARETURN)
+	f := c.Method(publicStatic, "f", "()V").Code()
+	f.Ldc("this").Op(bytecode.DUP).Invoke(bytecode.INVOKESPECIAL, "Test", "<init>", "()V")
+	f.Push(1).Invoke(bytecode.INVOKESPECIAL, "Test", "self", "(I)Ljava/lang/Object;")
+	printTop(f, "Ljava/lang/Object;")
+	f.Op(bytecode.ACONST_NULL).Push(1).Invoke(bytecode.INVOKESPECIAL, "Test", "self", "(I)Ljava/lang/Object;")
+	f.Op(bytecode.RETURN)
+
+	out, err := invoke(t, c)
+	if out != "this\n" {
+		t.Errorf("got %q, want this", out)
+	}
+	var e *Throwable
+	if !errors.As(err, &e) || e.Class != "java.lang.NullPointerException" {
+		t.Errorf("got %v, want a NullPointerException", err)
+	}
+}
+
+func TestInvokeInterfaceMethodref(t *testing.T) {
+	shape := builder.NewClass("p/Shape").Flags(classfile.ACC_PUBLIC | classfile.ACC_INTERFACE | classfile.ACC_ABSTRACT)
+	shape.Method(publicStatic, "sides", "()I").Code().Push(4).Op(bytecode.IRETURN)
+
+	c := builder.NewClass("Test")
+	f := c.Method(publicStatic, "f", "()V").Code()
+	ref := c.ConstantPool().InterfaceMethodref("p/Shape", "sides", "()I")
+	f.Insn(bytecode.Instruction{Opcode: bytecode.INVOKESTATIC, Index: int(ref)})
+	printTop(f, "I").Op(bytecode.RETURN)
+	out, err := invoke(t, c, shape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "4\n" {
+		t.Errorf("got %q, want 4", out)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	c := builder.NewClass("Test")
+	c.Method(publicStatic, "f", "()V").Code().Invoke(bytecode.INVOKESTATIC, "Test", "f", "()V").Op(bytecode.RETURN)
+	cf, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	thread := NewThread(&bytes.Buffer{})
+	thread.MaxDepth = 10
+	_, err = thread.Invoke(cf, cf.Methods[0])
+	var e *Throwable
+	if !errors.As(err, &e) || e.Class != "java.lang.StackOverflowError" {
+		t.Fatalf("got %v, want a StackOverflowError", err)
+	}
+	if len(e.StackTrace) != 10 || e.StackTrace[0] != "Test.f()V pc 0" {
+		t.Errorf("got stack trace %q", e.StackTrace)
+	}
+	if len(thread.frames) != 0 {
+		t.Errorf("%d frames left after the error", len(thread.frames))
+	}
+}
+
+func TestInvokeOtherClasses(t *testing.T) {
+	base := builder.NewClass("p/Base")
+	base.Method(publicStatic, "name", "()Ljava/lang/String;").Code().Ldc("base").Op(bytecode.ARETURN)
+	other := builder.NewClass("p/Other").Extends("p/Base")
+	other.Method(publicStatic, "square", "(I)I").Code().
+		Local(bytecode.ILOAD, 0).Op(bytecode.DUP).Op(bytecode.IMUL).Op(bytecode.IRETURN)
+
+	c := builder.NewClass("Test")
+	f := c.Method(publicStatic, "f", "()V").Code()
+	f.Push(12).Invoke(bytecode.INVOKESTATIC, "p/Other", "square", "(I)I")
+	printTop(f, "I")
+	f.Invoke(bytecode.INVOKESTATIC, "p/Other", "name", "()Ljava/lang/String;")
+	printTop(f, "Ljava/lang/String;").Op(bytecode.RETURN)
+	out, err := invoke(t, c, base, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "144\nbase\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	for _, tc := range []struct{ class, name, want string }{
+		{"p/Missing", "square", "java.lang.NoClassDefFoundError: p.Missing"},
+		{"p/Other", "cube", "java.lang.NoSuchMethodError: p.Other.cube(I)I"},
+	} {
+		c := builder.NewClass("Test")
+		c.Method(publicStatic, "f", "()V").Code().
+			Push(2).Invoke(bytecode.INVOKESTATIC, tc.class, tc.name, "(I)I").Op(bytecode.POP).Op(bytecode.RETURN)
+		_, err := invoke(t, c, base, other)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("%s.%s: got %v, want %s", tc.class, tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestInvokeSuperclassLoadError(t *testing.T) {
+	sub, err := builder.NewClass("p/Sub").Extends("p/Broken").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := builder.NewClass("Test")
+	c.Method(publicStatic, "f", "()V").Code().
+		Invoke(bytecode.INVOKESTATIC, "p/Sub", "g", "()V").Op(bytecode.RETURN)
+	cf, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := errors.New("p/Broken.class: truncated class file")
+	thread := NewThread(&bytes.Buffer{})
+	thread.Loader = func(name string) (*classfile.ClassFile, error) {
+		if name == "p.Sub" {
+			return sub, nil
+		}
+		return nil, broken
+	}
+	if _, err := thread.Invoke(cf, cf.Methods[0]); !errors.Is(err, broken) {
+		t.Errorf("got %v, want %v", err, broken)
+	}
+}
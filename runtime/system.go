@@ -2,30 +2,65 @@ package runtime
 
 import (
 	"fmt"
+	"io"
+	"strconv"
+
+	"gjvm/classfile"
+	"gjvm/descriptor"
 )
 
-func NewSystem() *System {
-	return &System{Out: &PrintStream{}}
+func NewSystem(out io.Writer) *System {
+	return &System{Out: &PrintStream{out}}
 }
 
-func (s *System) Call(Method string, args ...any) (any, error) {
-	switch Method {
-	case"java.io.PrintStream.println":
-		s.Out.println(args[0])
-		return nil, nil
+// Call runs one of the library methods the interpreter provides natively,
+// named like "java.io.PrintStream.println".
+func (s *System) Call(method string, md descriptor.MethodDescriptor, args ...Value) (Value, error) {
+	switch method {
+	case "java.io.PrintStream.print", "java.io.PrintStream.println":
+		text := ""
+		if len(args) == 1 {
+			text = JavaString(args[0], md.Parameters[0])
+		}
+		if method == "java.io.PrintStream.println" {
+			text += "\n"
+		}
+		_, err := io.WriteString(s.Out.w, text)
+		return nil, err
 	}
-	return nil, fmt.Errorf("Method not found: %s", Method)
+	return nil, fmt.Errorf("Method not found: %s", method)
 }
 
 type System struct {
 	Out *PrintStream
 }
 
-type PrintStream struct {}
+type PrintStream struct {
+	w io.Writer
+}
 
-func (p *PrintStream) println(args ...any) {
-	for _, arg := range args {
-		fmt.Printf("%v ", arg)
+// JavaString renders a value of type t the way String.valueOf does.
+func JavaString(v Value, t descriptor.FieldType) string {
+	if v == nil {
+		return "null"
+	}
+	switch t {
+	case descriptor.Boolean:
+		return strconv.FormatBool(v.(int32) != 0)
+	case descriptor.Char:
+		return string(rune(uint16(v.(int32))))
+	case descriptor.Float:
+		return classfile.FormatJavaFloat(float64(v.(float32)), 32)
+	case descriptor.Double:
+		return classfile.FormatJavaFloat(v.(float64), 64)
+	}
+	switch v := v.(type) {
+	case int32, int64, string:
+		return fmt.Sprint(v)
+	case float32:
+		return classfile.FormatJavaFloat(float64(v), 32)
+	case float64:
+		return classfile.FormatJavaFloat(v, 64)
 	}
-	fmt.Print("\n")
+	return fmt.Sprintf("%v", v)
 }
@@ -0,0 +1,38 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Throwable is a Java exception raised by the interpreter, such as the
+// ArithmeticException of an integer division by zero. Exception handlers are
+// not run yet, so it unwinds every frame and ends the thread.
+type Throwable struct {
+	// Class is the binary name, e.g. "java.lang.ArithmeticException".
+	Class   string
+	Message string
+	// StackTrace lists the frames it unwound, innermost first.
+	StackTrace []string
+}
+
+func throw(class, message string) *Throwable {
+	return &Throwable{Class: class, Message: message}
+}
+
+func (e *Throwable) Error() string {
+	if e.Message == "" {
+		return e.Class
+	}
+	return fmt.Sprintf("%s: %s", e.Class, e.Message)
+}
+
+// Trace renders the exception with its stack trace, like Throwable.printStackTrace.
+func (e *Throwable) Trace() string {
+	var b strings.Builder
+	b.WriteString(e.Error())
+	for _, frame := range e.StackTrace {
+		fmt.Fprintf(&b, "\n\tat %s", frame)
+	}
+	return b.String()
+}
@@ -0,0 +1,269 @@
+package signature
+
+import (
+	"fmt"
+	"strings"
+
+	"gjvm/descriptor"
+)
+
+// ParseClassSignature parses the Signature attribute of a ClassFile.
+func ParseClassSignature(s string) (*ClassSignature, error) {
+	p := &parser{s: s}
+	sig := &ClassSignature{}
+	var err error
+	if sig.TypeParameters, err = p.typeParameters(); err != nil {
+		return nil, err
+	}
+	if sig.SuperClass, err = p.classTypeSignature(); err != nil {
+		return nil, err
+	}
+	for !p.eof() {
+		itf, err := p.classTypeSignature()
+		if err != nil {
+			return nil, err
+		}
+		sig.SuperInterfaces = append(sig.SuperInterfaces, itf)
+	}
+	return sig, nil
+}
+
+// ParseMethodSignature parses the Signature attribute of a method_info.
+func ParseMethodSignature(s string) (*MethodSignature, error) {
+	p := &parser{s: s}
+	sig := &MethodSignature{}
+	var err error
+	if sig.TypeParameters, err = p.typeParameters(); err != nil {
+		return nil, err
+	}
+	if !p.consume('(') {
+		return nil, p.errorf("expected '('")
+	}
+	sig.Parameters = []JavaTypeSignature{}
+	for !p.consume(')') {
+		t, err := p.javaTypeSignature()
+		if err != nil {
+			return nil, err
+		}
+		sig.Parameters = append(sig.Parameters, t)
+	}
+	if p.consume('V') {
+		sig.Result = descriptor.Void
+	} else if sig.Result, err = p.javaTypeSignature(); err != nil {
+		return nil, err
+	}
+	for p.consume('^') {
+		var t JavaTypeSignature
+		if p.peek() == 'T' {
+			t, err = p.typeVariableSignature()
+		} else {
+			t, err = p.classTypeSignature()
+		}
+		if err != nil {
+			return nil, err
+		}
+		sig.Throws = append(sig.Throws, t)
+	}
+	if !p.eof() {
+		return nil, p.errorf("trailing characters")
+	}
+	return sig, nil
+}
+
+// ParseFieldSignature parses the Signature attribute of a field_info or a
+// record component.
+func ParseFieldSignature(s string) (FieldSignature, error) {
+	p := &parser{s: s}
+	t, err := p.referenceTypeSignature()
+	if err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		return nil, p.errorf("trailing characters")
+	}
+	return t, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (self *parser) errorf(format string, a ...any) error {
+	return fmt.Errorf("invalid signature %q at %d: %s", self.s, self.pos, fmt.Sprintf(format, a...))
+}
+
+func (self *parser) eof() bool {
+	return self.pos >= len(self.s)
+}
+
+func (self *parser) peek() byte {
+	if self.eof() {
+		return 0
+	}
+	return self.s[self.pos]
+}
+
+func (self *parser) consume(c byte) bool {
+	if self.peek() == c && !self.eof() {
+		self.pos++
+		return true
+	}
+	return false
+}
+
+// identifier reads up to the next character that cannot appear in an Identifier.
+func (self *parser) identifier() (string, error) {
+	start := self.pos
+	for !self.eof() && !strings.ContainsRune(".;[/<>:", rune(self.s[self.pos])) {
+		self.pos++
+	}
+	if self.pos == start {
+		return "", self.errorf("expected an identifier")
+	}
+	return self.s[start:self.pos], nil
+}
+
+// TypeParameters: < TypeParameter {TypeParameter} >
+func (self *parser) typeParameters() ([]TypeParameter, error) {
+	if !self.consume('<') {
+		return nil, nil
+	}
+	var params []TypeParameter
+	for !self.consume('>') {
+		name, err := self.identifier()
+		if err != nil {
+			return nil, err
+		}
+		param := TypeParameter{Name: name}
+		if !self.consume(':') {
+			return nil, self.errorf("expected ':'")
+		}
+		if c := self.peek(); c == 'L' || c == 'T' || c == '[' {
+			if param.ClassBound, err = self.referenceTypeSignature(); err != nil {
+				return nil, err
+			}
+		}
+		for self.consume(':') {
+			bound, err := self.referenceTypeSignature()
+			if err != nil {
+				return nil, err
+			}
+			param.InterfaceBounds = append(param.InterfaceBounds, bound)
+		}
+		params = append(params, param)
+	}
+	if len(params) == 0 {
+		return nil, self.errorf("empty type parameter list")
+	}
+	return params, nil
+}
+
+func (self *parser) javaTypeSignature() (JavaTypeSignature, error) {
+	switch c := descriptor.BaseType(self.peek()); c {
+	case descriptor.Byte, descriptor.Char, descriptor.Double, descriptor.Float,
+		descriptor.Int, descriptor.Long, descriptor.Short, descriptor.Boolean:
+		self.pos++
+		return c, nil
+	}
+	return self.referenceTypeSignature()
+}
+
+func (self *parser) referenceTypeSignature() (JavaTypeSignature, error) {
+	switch self.peek() {
+	case 'L':
+		return self.classTypeSignature()
+	case 'T':
+		return self.typeVariableSignature()
+	case '[':
+		self.pos++
+		component, err := self.javaTypeSignature()
+		if err != nil {
+			return nil, err
+		}
+		return ArrayTypeSignature{component}, nil
+	}
+	if self.eof() {
+		return nil, self.errorf("unexpected end")
+	}
+	return nil, self.errorf("unexpected %q", self.peek())
+}
+
+func (self *parser) typeVariableSignature() (TypeVariableSignature, error) {
+	if !self.consume('T') {
+		return TypeVariableSignature{}, self.errorf("expected 'T'")
+	}
+	name, err := self.identifier()
+	if err != nil {
+		return TypeVariableSignature{}, err
+	}
+	if !self.consume(';') {
+		return TypeVariableSignature{}, self.errorf("expected ';'")
+	}
+	return TypeVariableSignature{name}, nil
+}
+
+func (self *parser) classTypeSignature() (ClassTypeSignature, error) {
+	var sig ClassTypeSignature
+	if !self.consume('L') {
+		return sig, self.errorf("expected 'L'")
+	}
+	// The package specifier and the outermost class name are one run of
+	// identifiers separated by '/'; the last one is the class.
+	var parts []string
+	for {
+		id, err := self.identifier()
+		if err != nil {
+			return sig, err
+		}
+		parts = append(parts, id)
+		if !self.consume('/') {
+			break
+		}
+	}
+	sig.Package = strings.Join(parts[:len(parts)-1], "/")
+	name := parts[len(parts)-1]
+	for {
+		simple := SimpleClassTypeSignature{Name: name}
+		if self.consume('<') {
+			for !self.consume('>') {
+				arg, err := self.typeArgument()
+				if err != nil {
+					return sig, err
+				}
+				simple.TypeArguments = append(simple.TypeArguments, arg)
+			}
+			if len(simple.TypeArguments) == 0 {
+				return sig, self.errorf("empty type argument list")
+			}
+		}
+		sig.Classes = append(sig.Classes, simple)
+		if self.consume(';') {
+			return sig, nil
+		}
+		if !self.consume('.') {
+			return sig, self.errorf("expected ';' or '.'")
+		}
+		var err error
+		if name, err = self.identifier(); err != nil {
+			return sig, err
+		}
+	}
+}
+
+func (self *parser) typeArgument() (TypeArgument, error) {
+	if self.consume('*') {
+		return TypeArgument{Wildcard: Unbounded}, nil
+	}
+	arg := TypeArgument{Wildcard: NoWildcard}
+	if c := self.peek(); c == '+' || c == '-' {
+		arg.Wildcard = c
+		self.pos++
+	}
+	t, err := self.referenceTypeSignature()
+	if err != nil {
+		return arg, err
+	}
+	arg.Type = t
+	return arg, nil
+}
@@ -0,0 +1,126 @@
+package signature
+
+import (
+	"strings"
+)
+
+// Printer renders signatures as Java source would spell them, e.g.
+// "java.util.Map<java.lang.String, java.util.List<? extends T>>".
+type Printer struct {
+	// SimpleNames drops package names: "Map<String, List<? extends T>>".
+	SimpleNames bool
+}
+
+// Type renders a single type.
+func (self Printer) Type(t JavaTypeSignature) string {
+	switch t := t.(type) {
+	case ClassTypeSignature:
+		var b strings.Builder
+		if t.Package != "" && !self.SimpleNames {
+			b.WriteString(strings.ReplaceAll(t.Package, "/", "."))
+			b.WriteByte('.')
+		}
+		for i, c := range t.Classes {
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(c.Name)
+			if len(c.TypeArguments) > 0 {
+				args := make([]string, len(c.TypeArguments))
+				for j, arg := range c.TypeArguments {
+					args[j] = self.typeArgument(arg)
+				}
+				b.WriteString("<" + strings.Join(args, ", ") + ">")
+			}
+		}
+		return b.String()
+	case ArrayTypeSignature:
+		return self.Type(t.ComponentType) + "[]"
+	case nil:
+		return ""
+	}
+	return t.String()
+}
+
+func (self Printer) typeArgument(arg TypeArgument) string {
+	switch arg.Wildcard {
+	case Unbounded:
+		return "?"
+	case ExtendsBound:
+		return "? extends " + self.Type(arg.Type)
+	case SuperBound:
+		return "? super " + self.Type(arg.Type)
+	}
+	return self.Type(arg.Type)
+}
+
+func (self Printer) typeParameter(param TypeParameter) string {
+	bounds := []string{}
+	if param.ClassBound != nil {
+		bounds = append(bounds, self.Type(param.ClassBound))
+	}
+	for _, bound := range param.InterfaceBounds {
+		bounds = append(bounds, self.Type(bound))
+	}
+	if len(bounds) == 0 {
+		return param.Name
+	}
+	return param.Name + " extends " + strings.Join(bounds, " & ")
+}
+
+// TypeParameters renders "<K, V extends java.lang.Comparable<V>>", or "" when
+// there are none.
+func (self Printer) TypeParameters(params []TypeParameter) string {
+	if len(params) == 0 {
+		return ""
+	}
+	ps := make([]string, len(params))
+	for i, p := range params {
+		ps[i] = self.typeParameter(p)
+	}
+	return "<" + strings.Join(ps, ", ") + ">"
+}
+
+// Class renders a class header after the modifiers, e.g.
+// "Foo<T> extends Bar<T> implements java.lang.Iterable<T>". name is in internal form.
+func (self Printer) Class(name string, sig *ClassSignature) string {
+	s := self.className(name) + self.TypeParameters(sig.TypeParameters)
+	s += " extends " + self.Type(sig.SuperClass)
+	if len(sig.SuperInterfaces) > 0 {
+		itfs := make([]string, len(sig.SuperInterfaces))
+		for i, itf := range sig.SuperInterfaces {
+			itfs[i] = self.Type(itf)
+		}
+		s += " implements " + strings.Join(itfs, ", ")
+	}
+	return s
+}
+
+// Method renders a method header after the modifiers, e.g.
+// "<T> T max(java.util.List<? extends T>) throws E".
+func (self Printer) Method(name string, sig *MethodSignature) string {
+	s := ""
+	if len(sig.TypeParameters) > 0 {
+		s = self.TypeParameters(sig.TypeParameters) + " "
+	}
+	params := make([]string, len(sig.Parameters))
+	for i, p := range sig.Parameters {
+		params[i] = self.Type(p)
+	}
+	s += self.Type(sig.Result) + " " + name + "(" + strings.Join(params, ", ") + ")"
+	if len(sig.Throws) > 0 {
+		throws := make([]string, len(sig.Throws))
+		for i, t := range sig.Throws {
+			throws[i] = self.Type(t)
+		}
+		s += " throws " + strings.Join(throws, ", ")
+	}
+	return s
+}
+
+func (self Printer) className(name string) string {
+	if self.SimpleNames {
+		return name[strings.LastIndexByte(name, '/')+1:]
+	}
+	return strings.ReplaceAll(name, "/", ".")
+}
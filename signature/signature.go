@@ -0,0 +1,123 @@
+// Package signature parses the generic signatures stored in Signature attributes.
+//
+// https://docs.oracle.com/javase/specs/jvms/se21/html/jvms-4.html#jvms-4.7.9.1
+package signature
+
+// JavaTypeSignature is a descriptor.BaseType, a ClassTypeSignature, a
+// TypeVariableSignature or an ArrayTypeSignature.
+//
+//	JavaTypeSignature:      ReferenceTypeSignature | BaseType
+//	ReferenceTypeSignature: ClassTypeSignature | TypeVariableSignature | ArrayTypeSignature
+type JavaTypeSignature interface {
+	String() string
+}
+
+// ClassTypeSignature:       L [PackageSpecifier] SimpleClassTypeSignature {ClassTypeSignatureSuffix} ;
+// PackageSpecifier:         Identifier / {PackageSpecifier}
+// ClassTypeSignatureSuffix: . SimpleClassTypeSignature
+type ClassTypeSignature struct {
+	// Package in internal form without the trailing slash, e.g. "java/util".
+	// Empty for the unnamed package.
+	Package string
+	// Classes holds the outermost class first, followed by one entry per
+	// inner class, e.g. Outer<T> and Inner for "LOuter<TT;>.Inner;".
+	Classes []SimpleClassTypeSignature
+}
+
+// ClassName returns the binary name in internal form, e.g. "java/util/Map$Entry".
+func (self ClassTypeSignature) ClassName() string {
+	name := ""
+	if self.Package != "" {
+		name = self.Package + "/"
+	}
+	for i, c := range self.Classes {
+		if i > 0 {
+			name += "$"
+		}
+		name += c.Name
+	}
+	return name
+}
+
+func (self ClassTypeSignature) String() string {
+	return Printer{}.Type(self)
+}
+
+// SimpleClassTypeSignature: Identifier [TypeArguments]
+type SimpleClassTypeSignature struct {
+	Name          string
+	TypeArguments []TypeArgument
+}
+
+// Wildcard indicators of a TypeArgument.
+const (
+	NoWildcard   byte = 0
+	Unbounded    byte = '*'
+	ExtendsBound byte = '+'
+	SuperBound   byte = '-'
+)
+
+// TypeArgument:      [WildcardIndicator] ReferenceTypeSignature | *
+// WildcardIndicator: + | -
+type TypeArgument struct {
+	Wildcard byte
+	// Type is nil for the unbounded wildcard.
+	Type JavaTypeSignature
+}
+
+func (self TypeArgument) String() string {
+	return Printer{}.typeArgument(self)
+}
+
+// TypeVariableSignature: T Identifier ;
+type TypeVariableSignature struct {
+	Name string
+}
+
+func (self TypeVariableSignature) String() string {
+	return self.Name
+}
+
+// ArrayTypeSignature: [ JavaTypeSignature
+type ArrayTypeSignature struct {
+	ComponentType JavaTypeSignature
+}
+
+func (self ArrayTypeSignature) String() string {
+	return Printer{}.Type(self)
+}
+
+// TypeParameter:  Identifier ClassBound {InterfaceBound}
+// ClassBound:     : [ReferenceTypeSignature]
+// InterfaceBound: : ReferenceTypeSignature
+type TypeParameter struct {
+	Name string
+	// ClassBound is nil when only interface bounds are given, as in <T::Ljava/lang/Comparable<TT;>;>.
+	ClassBound      JavaTypeSignature
+	InterfaceBounds []JavaTypeSignature
+}
+
+func (self TypeParameter) String() string {
+	return Printer{}.typeParameter(self)
+}
+
+// ClassSignature: [TypeParameters] SuperclassSignature {SuperinterfaceSignature}
+type ClassSignature struct {
+	TypeParameters  []TypeParameter
+	SuperClass      ClassTypeSignature
+	SuperInterfaces []ClassTypeSignature
+}
+
+// MethodSignature: [TypeParameters] ( {JavaTypeSignature} ) Result {ThrowsSignature}
+// Result:          JavaTypeSignature | VoidDescriptor
+// ThrowsSignature: ^ ClassTypeSignature | ^ TypeVariableSignature
+type MethodSignature struct {
+	TypeParameters []TypeParameter
+	Parameters     []JavaTypeSignature
+	// Result is descriptor.Void for void methods.
+	Result JavaTypeSignature
+	Throws []JavaTypeSignature
+}
+
+// FieldSignature is a ReferenceTypeSignature.
+type FieldSignature = JavaTypeSignature
@@ -0,0 +1,84 @@
+package signature
+
+import (
+	"testing"
+)
+
+func TestParseFieldSignature(t *testing.T) {
+	cases := []struct {
+		sig, qualified, simple string
+	}{
+		{"Ljava/util/List<Ljava/lang/String;>;", "java.util.List<java.lang.String>", "List<String>"},
+		{"Ljava/util/Map<Ljava/lang/String;Ljava/util/List<+TT;>;>;", "java.util.Map<java.lang.String, java.util.List<? extends T>>", "Map<String, List<? extends T>>"},
+		{"Ljava/util/Comparator<-TT;>;", "java.util.Comparator<? super T>", "Comparator<? super T>"},
+		{"Ljava/lang/Class<*>;", "java.lang.Class<?>", "Class<?>"},
+		{"[[TT;", "T[][]", "T[][]"},
+		{"Lcom/x/Outer<TK;>.Inner<[I>.Deep;", "com.x.Outer<K>.Inner<int[]>.Deep", "Outer<K>.Inner<int[]>.Deep"},
+	}
+	for _, c := range cases {
+		sig, err := ParseFieldSignature(c.sig)
+		if err != nil {
+			t.Errorf("%s: %v", c.sig, err)
+			continue
+		}
+		if got := sig.String(); got != c.qualified {
+			t.Errorf("%s: got %q, want %q", c.sig, got, c.qualified)
+		}
+		if got := (Printer{SimpleNames: true}).Type(sig); got != c.simple {
+			t.Errorf("%s: got %q, want %q", c.sig, got, c.simple)
+		}
+	}
+
+	sig, _ := ParseFieldSignature("Lcom/x/Outer<TK;>.Inner;")
+	if name := sig.(ClassTypeSignature).ClassName(); name != "com/x/Outer$Inner" {
+		t.Errorf("ClassName: got %q", name)
+	}
+}
+
+func TestParseClassSignature(t *testing.T) {
+	s := "<K:Ljava/lang/Object;V::Ljava/lang/Comparable<TV;>;:Ljava/io/Serializable;>Ljava/util/AbstractMap<TK;TV;>;Ljava/lang/Cloneable;"
+	sig, err := ParseClassSignature(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Foo<K extends Object, V extends Comparable<V> & Serializable> extends AbstractMap<K, V> implements Cloneable"
+	if got := (Printer{SimpleNames: true}).Class("com/x/Foo", sig); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	if sig.TypeParameters[1].ClassBound != nil || len(sig.TypeParameters[1].InterfaceBounds) != 2 {
+		t.Errorf("V bounds: got %+v", sig.TypeParameters[1])
+	}
+}
+
+func TestParseMethodSignature(t *testing.T) {
+	s := "<T:Ljava/lang/Object;E:Ljava/lang/Exception;>(Ljava/util/List<+TT;>;I)TT;^TE;^Ljava/io/IOException;"
+	sig, err := ParseMethodSignature(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<T extends java.lang.Object, E extends java.lang.Exception> T max(java.util.List<? extends T>, int) throws E, java.io.IOException"
+	if got := (Printer{}).Method("max", sig); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+
+	sig, err = ParseMethodSignature("(Ljava/util/List<*>;)V")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := (Printer{}).Method("m", sig); got != "void m(java.util.List<?>)" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestParseInvalidSignatures(t *testing.T) {
+	for _, s := range []string{"", "I", "Ljava/util/List<>;", "Ljava/util/List", "TT", "Ljava/util/List<TT;>", "L;"} {
+		if _, err := ParseFieldSignature(s); err == nil {
+			t.Errorf("field %q: expected an error", s)
+		}
+	}
+	for _, s := range []string{"", "<>()V", "<T>()V", "(I", "()", "()VV", "()V^I"} {
+		if _, err := ParseMethodSignature(s); err == nil {
+			t.Errorf("method %q: expected an error", s)
+		}
+	}
+}